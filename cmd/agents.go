@@ -3,12 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"go-code/internal/agents"
-	"go-code/internal/api"
-	"go-code/internal/config"
+	"go-code/internal/ui"
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
 )
 
 // agentsCmd lists all available agents
@@ -16,47 +17,90 @@ var agentsCmd = &cobra.Command{
 	Use:   "agents",
 	Short: "List all available agents",
 	Long: `Display information about all available AI agents and their specializations.
-Use @agent syntax to interact with specific agents.`,
+Use @agent syntax to interact with specific agents.
+
+With --output json or ndjson the agents are listed with their model settings.`,
+	Annotations: map[string]string{jsonAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
 
 		cfg := manager.GetConfig()
-		if cfg.GroqAPIKey == "" {
+		if cfg.Provider.IsGroq() && cfg.GroqAPIKey == "" {
 			fmt.Fprintf(os.Stderr, "❌ Groq API key not set. Use 'go-code config set-key YOUR_API_KEY' to set it.\n")
 			os.Exit(1)
 		}
 
-		// Create client and registry
-		client := api.NewGroqClient(cfg.GroqAPIKey)
-		registry := agents.NewRegistry(client, cfg)
+		team := newTeam(manager)
+		
+		if ui.IsMachineReadable() {
+			printAgentsJSON(team)
+			return
+		}
 		
-		fmt.Println("🤖 Available AI Development Agents")
-		fmt.Println("=" + strings.Repeat("=", 35))
-		fmt.Println()
+		fmt.Fprintln(ui.Stdout, "🤖 Available AI Development Agents")
+		fmt.Fprintln(ui.Stdout, "=" + strings.Repeat("=", 35))
+		fmt.Fprintln(ui.Stdout)
 		
-		agentList := registry.ListAgents()
+		agentList := team.Agents()
 		for _, agent := range agentList {
 			// Use agent's color for the output
 			color := agent.Color()
 			color.Printf("%s @%s\n", agent.Icon(), strings.ToLower(agent.Name()))
-			fmt.Printf("   %s\n", agent.Role())
-			fmt.Println()
+			fmt.Fprintf(ui.Stdout, "   %s\n", agent.Role())
+			fmt.Fprintln(ui.Stdout)
 		}
 		
-		fmt.Println("Usage Examples:")
-		fmt.Println("  go-code chat @planner \"Plan a web application with authentication\"")
-		fmt.Println("  go-code chat @frontend \"Create a React component for user login\"")
-		fmt.Println("  go-code chat @backend \"Design a REST API for user management\"")
-		fmt.Println("  go-code chat @security \"Review this authentication code\"")
-		fmt.Println()
-		fmt.Println("💡 Tip: Use tab completion for agent names after @")
+		fmt.Fprintln(ui.Stdout, "Usage Examples:")
+		fmt.Fprintln(ui.Stdout, "  go-code chat @planner \"Plan a web application with authentication\"")
+		fmt.Fprintln(ui.Stdout, "  go-code chat @frontend \"Create a React component for user login\"")
+		fmt.Fprintln(ui.Stdout, "  go-code chat @backend \"Design a REST API for user management\"")
+		fmt.Fprintln(ui.Stdout, "  go-code chat @security \"Review this authentication code\"")
+		fmt.Fprintln(ui.Stdout)
+		fmt.Fprintln(ui.Stdout, "💡 Tip: Use tab completion for agent names after @")
 	},
 }
 
+// agentInfo describes an agent in JSON output
+type agentInfo struct {
+	Name        string           `json:"name"`
+	Type        models.AgentType `json:"type"`
+	Role        string           `json:"role"`
+	Icon        string           `json:"icon"`
+	Model       string           `json:"model"`
+	Temperature float32          `json:"temperature"`
+	MaxTokens   int              `json:"max_tokens"`
+}
+
+// printAgentsJSON writes the team's agents, sorted by name
+func printAgentsJSON(team *gocode.Team) {
+	var infos []agentInfo
+	for _, agent := range team.Agents() {
+		agentConfig := team.AgentConfig(agent.Type())
+		infos = append(infos, agentInfo{
+			Name:        strings.ToLower(agent.Name()),
+			Type:        agent.Type(),
+			Role:        agent.Role(),
+			Icon:        agent.Icon(),
+			Model:       agentConfig.Model,
+			Temperature: agentConfig.Temperature,
+			MaxTokens:   agentConfig.MaxTokens,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	if ui.Output() == ui.OutputNDJSON {
+		for _, info := range infos {
+			ui.PrintJSON(info)
+		}
+		return
+	}
+	ui.PrintJSON(map[string]interface{}{"agents": infos})
+}
+
 func init() {
 	rootCmd.AddCommand(agentsCmd)
 }
\ No newline at end of file
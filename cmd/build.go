@@ -1,19 +1,31 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"go-code/internal/agents"
-	"go-code/internal/api"
-	"go-code/internal/config"
-	"go-code/internal/orchestrator"
+	"go-code/internal/dashboard"
+	"go-code/internal/events"
+	"go-code/internal/git"
 	"go-code/internal/ui"
-	"go-code/pkg/models"
+	"go-code/pkg/gocode"
 )
 
+var commitBuild bool
+var forceCommit bool
+var reviewBuild bool
+var reviewRounds int
+var noScan bool
+var noTests bool
+var buildSARIFPath string
+var budgetTokens int
+var budgetCost float64
+var useDashboard bool
+
 // buildCmd auto-coordinates agents to build a feature
 var buildCmd = &cobra.Command{
 	Use:   "build [description]",
@@ -25,11 +37,48 @@ specialized agents who will execute their parts automatically.
 Examples:
   go-code build "a todo app with React frontend and Node.js backend"
   go-code build "user authentication system with JWT"
-  go-code build "REST API for blog management"`,
-	Args: cobra.MinimumNArgs(1),
+  go-code build "REST API for blog management"
+  go-code build --commit "REST API for blog management"
+
+With --commit, the build runs on a new go-code/<run-id> branch and every
+completed task becomes its own commit. The worktree must be clean unless
+--force is given.
+
+With --review, a reviewer checks each coding task's files and the original
+agent revises them, up to --review-rounds times (config: review.rounds).
+
+After the build, every generated file is checked by the offline security
+scanner. Findings are written as SARIF (--sarif, default in the run journal)
+and handed to the Security agent for triage. Use --no-scan to skip it.
+
+Tasks the planner gives acceptance criteria are only done when tests for the
+criteria pass: the acceptance agent (config: acceptance.agent) writes tests
+for the detected stack, they run with the stack's test command, and the
+task's agent may fix its code acceptance.rounds times. The test command must
+be in allowed_commands and is confirmed before it runs. Use --no-tests to
+skip acceptance tests.
+
+Token usage and cost are recorded in ~/.go-code/usage.jsonl. With
+--budget-tokens or --budget-cost (config: budget.max_tokens, budget.max_cost)
+the build stops as soon as the budget is exceeded.
+
+With --output ndjson, progress is written as one JSON event per line:
+build_started, stage_started, stage_completed, plan, task_started,
+task_completed, task_failed, task_skipped, file_written, tokens_used,
+budget_exceeded, agent_response, warning, error and summary. --output json
+writes the same events as a single array when the build ends. The run's state
+is kept up to date in ~/.go-code/runs/<run-id>/state.json.
+
+With --tui, the build runs in a full-screen dashboard: the task list with each
+task's status, agent and elapsed time, the selected task's output as it
+streams in, the files written and a token and cost meter. Keys: ↑/↓ select a
+task, p pause before the next task, s skip, r retry, q quit. Without an
+interactive terminal it falls back to the line output.`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: map[string]string{jsonAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load configuration
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
@@ -37,49 +86,143 @@ Examples:
 
 		cfg := manager.GetConfig()
 		
-		// Override model if --gpt-oss-120b flag is set
-		if IsGptOss120bEnabled() {
-			// Create a copy of the config and override all agent models
-			configCopy := *cfg
-			agentPrefs := make(map[models.AgentType]models.AgentConfig)
-			for agentType, agentConfig := range cfg.AgentPreferences {
-				newConfig := agentConfig
-				newConfig.Model = "openai/gpt-oss-120b"
-				agentPrefs[agentType] = newConfig
-			}
-			configCopy.AgentPreferences = agentPrefs
-			configCopy.DefaultModel = "openai/gpt-oss-120b"
-			cfg = &configCopy
-		}
-		
 		if err := manager.ValidateConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 			os.Exit(1)
 		}
 
+		// Review loop flags override the configured review settings
+		if cmd.Flags().Changed("review") {
+			cfg.Review.Enabled = reviewBuild
+		}
+		if cmd.Flags().Changed("review-rounds") {
+			cfg.Review.Enabled = true
+			cfg.Review.Rounds = reviewRounds
+		}
+		if noTests {
+			cfg.Acceptance.Enabled = false
+		}
+		if cmd.Flags().Changed("budget-tokens") {
+			cfg.Budget.MaxTokens = budgetTokens
+		}
+		if cmd.Flags().Changed("budget-cost") {
+			cfg.Budget.MaxCost = budgetCost
+		}
+
 		description := strings.Join(args, " ")
 
-		// Create client and registry
-		client := api.NewGroqClient(cfg.GroqAPIKey)
-		registry := agents.NewRegistry(client, cfg)
+		var opts []gocode.Option
+		if noScan {
+			opts = append(opts, gocode.WithSecurityScan(false))
+		}
+		if buildSARIFPath != "" {
+			opts = append(opts, gocode.WithSARIFPath(buildSARIFPath))
+		}
+		if commitBuild {
+			repo, err := openBuildRepo()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Git error: %v\n", err)
+				os.Exit(1)
+			}
+			opts = append(opts, gocode.WithGitRepo(repo))
+		}
+
+		// The dashboard steers the build and shows the agents' output as it streams
+		board := useDashboard && ui.Output() == ui.OutputText && dashboard.Interactive()
+		if useDashboard && !board {
+			ui.DisplayWarning("--tui needs an interactive terminal, using line output")
+		}
+		var controls *gocode.Controls
+		if board {
+			controls = gocode.NewControls()
+			opts = append(opts, gocode.WithControls(controls), gocode.WithStreaming())
+			promptForPermission = false
+		}
+
+		team := newTeam(manager, opts...)
 
-		// Create orchestrator
-		orch := orchestrator.New(registry, cfg)
+		// In the JSON formats the build's events are the output
+		var logger *events.JSONLogger
+		switch {
+		case ui.IsMachineReadable():
+			logger = events.NewJSONLogger(os.Stdout, ui.Output() == ui.OutputJSON)
+			team.Subscribe(logger)
+		case !board:
+			team.Subscribe(ui.NewTerminal(team.Agent))
+		}
 
-		// Display starting message
-		ui.DisplayInfo(fmt.Sprintf("🚀 Building: %s", description))
-		fmt.Println()
+		// Ctrl-C stops the build, leaving its journal and state behind
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
 
 		// Execute the build workflow
-		if err := orch.ExecuteBuild(description); err != nil {
+		var result *gocode.BuildResult
+		var err error
+		if board {
+			result, err = buildInDashboard(ctx, team, controls, description)
+		} else {
+			result, err = team.Build(ctx, description)
+		}
+		if logger != nil {
+			logger.Flush()
+		}
+		if err != nil {
 			ui.DisplayError(fmt.Errorf("build failed: %w", err))
 			os.Exit(1)
 		}
 
 		ui.DisplaySuccess("Build completed successfully!")
+		ui.DisplayInfo(fmt.Sprintf("Run ID: %s (revert with 'go-code undo %s')", result.RunID, result.RunID))
+		if result.Branch != "" {
+			ui.DisplayInfo(fmt.Sprintf("Changes committed on branch %s", result.Branch))
+		}
 	},
 }
 
+// buildInDashboard runs the build in the full-screen dashboard
+func buildInDashboard(ctx context.Context, team *gocode.Team, controls *gocode.Controls, description string) (*gocode.BuildResult, error) {
+	board := dashboard.New(description, controls)
+	team.Subscribe(board)
+
+	var result *gocode.BuildResult
+	err := board.Run(func() error {
+		var err error
+		result, err = team.Build(ctx, description)
+		return err
+	})
+
+	// The dashboard is gone once the build ends, so repeat the totals
+	if result != nil {
+		ui.DisplayUsage(result.Usage)
+	}
+	return result, err
+}
+
+// openBuildRepo finds the repository the build should commit to. Outside of a
+// repository a new one is initialized in the generated project directory.
+func openBuildRepo() (*git.Repo, error) {
+	if !git.Available() {
+		return nil, fmt.Errorf("--commit requires the git binary on your PATH")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	return git.OpenForBuild(cwd, forceCommit)
+}
+
 func init() {
 	rootCmd.AddCommand(buildCmd)
-}
\ No newline at end of file
+	buildCmd.Flags().BoolVar(&commitBuild, "commit", false, "Run on a new git branch and commit each completed task")
+	buildCmd.Flags().BoolVar(&forceCommit, "force", false, "Allow --commit on a dirty worktree")
+	buildCmd.Flags().BoolVar(&reviewBuild, "review", false, "Review each coding task and let the agent revise its output")
+	buildCmd.Flags().IntVar(&reviewRounds, "review-rounds", 2, "Maximum revision rounds per task (implies --review)")
+	buildCmd.Flags().BoolVar(&noScan, "no-scan", false, "Skip the static security scan of generated files")
+	buildCmd.Flags().BoolVar(&noTests, "no-tests", false, "Don't generate or run acceptance tests for planned tasks")
+	buildCmd.Flags().StringVar(&buildSARIFPath, "sarif", "", "Write the security scan SARIF report to this path")
+	buildCmd.Flags().IntVar(&budgetTokens, "budget-tokens", 0, "Abort the build after this many tokens (0 = no limit)")
+	buildCmd.Flags().Float64Var(&budgetCost, "budget-cost", 0, "Abort the build after this much spend in US dollars (0 = no limit)")
+	buildCmd.Flags().BoolVar(&useDashboard, "tui", false, "Show the build in a full-screen dashboard with pause, skip and retry")
+}
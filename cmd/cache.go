@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/internal/cache"
+	"go-code/pkg/models"
+)
+
+// cacheCmd manages the response cache
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the API response cache",
+	Long: `Inspect or clear the on-disk cache of API responses in ~/.go-code/cache.
+
+The cache is opt-in: enable it with 'go-code config set cache.enabled true'.
+Responses are keyed by model, messages, temperature and max_tokens, expire
+after cache.ttl and the oldest are evicted beyond cache.max_size_mb.
+Use --no-cache to bypass it for one run, or --cache-only to answer only from
+it (for offline demos and tests).`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and entries",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		cfg := manager.GetConfig()
+
+		stats, err := newResponseCache(cfg).Stats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading cache: %v\n", err)
+			os.Exit(1)
+		}
+
+		status := color.YellowString("disabled")
+		if cfg.Cache.Enabled {
+			status = color.GreenString("enabled")
+		}
+		fmt.Printf("📦 Response cache: %s%s\n", status, color.HiBlackString(" (%s)", manager.Source("cache.enabled")))
+		fmt.Printf("   Location: %s\n", stats.Dir)
+		fmt.Printf("   Entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("   Size:     %s of %s\n", formatBytes(stats.Bytes), formatBytes(stats.MaxBytes))
+		fmt.Printf("   TTL:      %s\n", stats.TTL)
+		if stats.Entries > 0 {
+			fmt.Printf("   Oldest:   %s\n", stats.Oldest.Format("2006-01-02 15:04"))
+			fmt.Printf("   Newest:   %s\n", stats.Newest.Format("2006-01-02 15:04"))
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		removed, err := newResponseCache(manager.GetConfig()).Clear()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing cache: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Removed %d cached responses\n", removed)
+	},
+}
+
+// newResponseCache opens the response cache with the configured limits
+func newResponseCache(cfg *models.Config) *cache.Cache {
+	ttl, _ := time.ParseDuration(cfg.Cache.TTL)
+	return cache.New(cache.DefaultDir(), ttl, int64(cfg.Cache.MaxSizeMB)<<20)
+}
+
+// formatBytes renders a byte count in KB or MB
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
@@ -1,15 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"go-code/internal/agents"
-	"go-code/internal/api"
-	"go-code/internal/config"
 	"go-code/internal/ui"
+	"go-code/pkg/gocode"
 	"go-code/pkg/models"
 )
 
@@ -33,33 +32,20 @@ Available agents:
 Examples:
   go-code chat @planner "Plan a microservices architecture"
   go-code chat @frontend "Create a responsive navbar component"
-  go-code chat @security "Review this authentication function"`,
-	Args: cobra.MinimumNArgs(2),
+  go-code chat @security "Review this authentication function"
+
+With --output json or ndjson the response is written as a JSON object with
+the content, model, agent and token usage.`,
+	Args:        cobra.MinimumNArgs(2),
+	Annotations: map[string]string{jsonAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load configuration
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
 
-		cfg := manager.GetConfig()
-		
-		// Override model if --gpt-oss-120b flag is set
-		if IsGptOss120bEnabled() {
-			// Create a copy of the config and override all agent models
-			configCopy := *cfg
-			agentPrefs := make(map[models.AgentType]models.AgentConfig)
-			for agentType, agentConfig := range cfg.AgentPreferences {
-				newConfig := agentConfig
-				newConfig.Model = "openai/gpt-oss-120b"
-				agentPrefs[agentType] = newConfig
-			}
-			configCopy.AgentPreferences = agentPrefs
-			configCopy.DefaultModel = "openai/gpt-oss-120b"
-			cfg = &configCopy
-		}
-		
 		if err := manager.ValidateConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 			os.Exit(1)
@@ -79,15 +65,13 @@ Examples:
 			os.Exit(1)
 		}
 
-		// Create client and registry
-		client := api.NewGroqClient(cfg.GroqAPIKey)
-		registry := agents.NewRegistry(client, cfg)
+		team := newTeam(manager)
 
 		// Get agent
-		agent, err := registry.GetAgentByName(agentName)
+		agent, err := team.FindAgent(agentName)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Fprintf(os.Stderr, "\nAvailable agents: %v\n", strings.Join(registry.GetAgentNames(), ", "))
+			fmt.Fprintf(os.Stderr, "\nAvailable agents: %v\n", strings.Join(team.AgentNames(), ", "))
 			os.Exit(1)
 		}
 
@@ -95,13 +79,18 @@ Examples:
 		ui.DisplayAgentHeader(agent)
 
 		// Process message
-		fmt.Println("💭 Thinking...")
-		response, err := agent.Process("", message)
+		fmt.Fprintln(ui.Stdout, "💭 Thinking...")
+		response, err := team.Chat(context.Background(), agent.Type(), []gocode.Message{{Role: models.RoleUser, Content: message}})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing request: %v\n", err)
 			os.Exit(1)
 		}
 
+		if ui.IsMachineReadable() {
+			ui.PrintJSON(response)
+			return
+		}
+
 		// Display response
 		ui.DisplayAgentResponse(agent, response)
 	},
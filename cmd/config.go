@@ -3,11 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"go-code/internal/config"
+	"go-code/internal/ui"
 )
 
 // configCmd represents the config command
@@ -24,7 +26,7 @@ var setKeyCmd = &cobra.Command{
 	Long:  `Set your Groq API key for accessing the Groq API.`,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
@@ -42,6 +44,9 @@ var setKeyCmd = &cobra.Command{
 		}
 
 		fmt.Println("✅ Groq API key set successfully!")
+		if source := manager.Source("groq_api_key"); source != config.SourceFile {
+			color.Yellow("⚠️  The stored key is currently overridden by %s", source)
+		}
 	},
 }
 
@@ -49,21 +54,22 @@ var setKeyCmd = &cobra.Command{
 var setModelCmd = &cobra.Command{
 	Use:   "set-model [model]",
 	Short: "Set default model",
-	Long: `Set the default model for agents. Available models:
-- llama-3.1-70b-versatile
-- llama-3.1-8b-instant
-- mixtral-8x7b-32768
-- gemma2-9b-it
-- qwen2.5-coder-32b-instruct`,
+	Long: `Set the default model for agents.
+Run 'go-code models list' to see the available models.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
 
 		model := strings.TrimSpace(args[0])
+		if err := manager.ValidateModel(model); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		if err := manager.SetDefaultModel(model); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting model: %v\n", err)
 			os.Exit(1)
@@ -79,7 +85,7 @@ var allowCommandsCmd = &cobra.Command{
 	Short: "Toggle command execution permissions",
 	Long:  `Toggle whether go-code agents can execute system commands.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
@@ -101,63 +107,142 @@ var allowCommandsCmd = &cobra.Command{
 	},
 }
 
+var showEffective bool
+
 // showCmd shows current configuration
 var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Long:  `Display the current go-code configuration settings.`,
+	Long: `Display the current go-code configuration settings.
+
+With --effective, every setting is listed as a dotted key together with the
+layer it came from: default, file, project:<path>, env:<NAME>, flag or key_command.
+
+With --output json the configuration is written as a JSON document with the
+API key masked; with --effective, as a list of key, value and source.`,
+	Annotations: map[string]string{jsonAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
 
+		if showEffective {
+			displayEffectiveConfig(manager)
+			return
+		}
+
+		if ui.IsMachineReadable() {
+			config := *manager.GetConfig()
+			config.GroqAPIKey = maskAPIKey(config.GroqAPIKey)
+			ui.PrintJSON(map[string]interface{}{
+				"config":         config,
+				"config_file":    manager.ConfigPath(),
+				"project_config": manager.ProjectPath(),
+			})
+			return
+		}
+
 		config := manager.GetConfig()
 		
-		fmt.Println("🔧 go-code Configuration")
-		fmt.Println("=" + strings.Repeat("=", 25))
-		fmt.Println()
+		fmt.Fprintln(ui.Stdout, "🔧 go-code Configuration")
+		fmt.Fprintln(ui.Stdout, "=" + strings.Repeat("=", 25))
+		fmt.Fprintln(ui.Stdout)
+		
+		source := func(key string) string {
+			return color.HiBlackString(" (%s)", manager.Source(key))
+		}
 		
 		// API Key
 		if config.GroqAPIKey != "" {
 			maskedKey := maskAPIKey(config.GroqAPIKey)
-			color.Green("✅ Groq API Key: %s", maskedKey)
+			fmt.Fprintln(ui.Stdout, color.GreenString("✅ Groq API Key: %s", maskedKey) + source("groq_api_key"))
 		} else {
 			color.Red("❌ Groq API Key: Not set")
 		}
 		
+		if config.KeyCommand != "" {
+			fmt.Fprintf(ui.Stdout, "🔑 Key Command: %s%s\n", config.KeyCommand, source("key_command"))
+		}
+		
 		// Default Model
-		fmt.Printf("🤖 Default Model: %s\n", config.DefaultModel)
+		fmt.Fprintf(ui.Stdout, "🤖 Default Model: %s%s\n", config.DefaultModel, source("default_model"))
 		
 		// Command Permissions
 		if config.RequireCommandPermission {
-			color.Yellow("🔒 Command Execution: Requires permission")
+			fmt.Fprintln(ui.Stdout, color.YellowString("🔒 Command Execution: Requires permission") + source("require_command_permission"))
 		} else {
-			color.Green("🔓 Command Execution: Allowed")
+			fmt.Fprintln(ui.Stdout, color.GreenString("🔓 Command Execution: Allowed") + source("require_command_permission"))
 		}
 		
 		// Working Directory
 		if config.WorkingDirectory != "" {
-			fmt.Printf("📁 Working Directory: %s\n", config.WorkingDirectory)
+			fmt.Fprintf(ui.Stdout, "📁 Working Directory: %s%s\n", config.WorkingDirectory, source("working_directory"))
 		}
 		
 		// Allowed Commands
 		if len(config.AllowedCommands) > 0 {
-			fmt.Printf("✅ Allowed Commands: %s\n", strings.Join(config.AllowedCommands, ", "))
+			fmt.Fprintf(ui.Stdout, "✅ Allowed Commands: %s%s\n", strings.Join(config.AllowedCommands, ", "), source("allowed_commands"))
+		}
+
+		// MCP Servers
+		if len(config.MCPServers) > 0 {
+			names := make([]string, 0, len(config.MCPServers))
+			for name := range config.MCPServers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(ui.Stdout, "🔌 MCP Servers: %s\n", strings.Join(names, ", "))
 		}
 		
-		fmt.Println()
-		fmt.Printf("📄 Config file: %s/.go-code/config.json\n", os.Getenv("HOME"))
+		fmt.Fprintln(ui.Stdout)
+		fmt.Fprintf(ui.Stdout, "📄 Config file: %s\n", manager.ConfigPath())
+		if manager.ProjectPath() != "" {
+			fmt.Fprintf(ui.Stdout, "📄 Project config: %s\n", manager.ProjectPath())
+		}
 	},
 }
 
+// displayEffectiveConfig lists every effective setting and its source
+func displayEffectiveConfig(manager *config.Manager) {
+	settings, err := manager.Settings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	for i, setting := range settings {
+		if value, ok := setting.Value.(string); ok && setting.Key == "groq_api_key" && value != "" {
+			settings[i].Value = maskAPIKey(value)
+		}
+	}
+
+	switch ui.Output() {
+	case ui.OutputJSON:
+		ui.PrintJSON(map[string]interface{}{"settings": settings})
+		return
+	case ui.OutputNDJSON:
+		for _, setting := range settings {
+			ui.PrintJSON(setting)
+		}
+		return
+	}
+
+	for _, setting := range settings {
+		value := fmt.Sprintf("%v", setting.Value)
+		fmt.Fprintf(ui.Stdout, "%s = %s", setting.Key, value)
+		color.HiBlack("  (%s)", setting.Source)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(setKeyCmd)
 	configCmd.AddCommand(setModelCmd)
 	configCmd.AddCommand(allowCommandsCmd)
 	configCmd.AddCommand(showCmd)
+	showCmd.Flags().BoolVar(&showEffective, "effective", false, "List every effective setting with its source")
 }
 
 // maskAPIKey masks the API key for display
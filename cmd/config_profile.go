@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/pkg/models"
+)
+
+var (
+	profileDescription  string
+	profileModel        string
+	profileDefaultModel string
+	profileProvider     string
+	profileBaseURL      string
+	profileAPIKeyEnv    string
+	profileFrom         string
+)
+
+// profileCmd manages named profiles
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named model/provider profiles",
+	Long: `Profiles are named sets of overrides for the default model, the agents'
+preferences and the provider. Select one for a single run with --profile <name>
+or GOCODE_PROFILE, or make it the default with 'go-code config profile use'.
+
+Built-in profiles:
+  fast     llama-3.1-8b-instant for every agent
+  quality  openai/gpt-oss-120b for every agent
+  local    an OpenAI-compatible server on localhost:11434 (e.g. Ollama)`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		active := manager.ActiveProfile()
+
+		for _, info := range manager.Profiles() {
+			marker := "  "
+			if info.Name == active {
+				marker = color.GreenString("* ")
+			}
+			kind := ""
+			if info.Builtin {
+				kind = color.HiBlackString(" (built-in)")
+			}
+			fmt.Printf("%s%s%s\n", marker, info.Name, kind)
+			if info.Profile.Description != "" {
+				color.HiBlack("    %s", info.Profile.Description)
+			}
+		}
+
+		if active != "" {
+			fmt.Printf("\nActive profile: %s (%s)\n", active, manager.Source("profile"))
+		}
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create or replace a profile",
+	Long: `Create a profile, optionally starting from an existing one with --from.
+
+Examples:
+  go-code config profile create cheap --model llama-3.1-8b-instant
+  go-code config profile create review --from quality --default-model llama-3.3-70b-versatile
+  go-code config profile create lmstudio --provider lmstudio --base-url http://localhost:1234/v1 --model qwen2.5-coder`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		name := args[0]
+
+		var profile models.Profile
+		if profileFrom != "" {
+			found := false
+			for _, info := range manager.Profiles() {
+				if info.Name == profileFrom {
+					profile, found = info.Profile, true
+				}
+			}
+			if !found {
+				fmt.Fprintf(os.Stderr, "Unknown profile '%s'\n", profileFrom)
+				os.Exit(1)
+			}
+		}
+
+		flags := cmd.Flags()
+		if flags.Changed("description") {
+			profile.Description = profileDescription
+		}
+		if flags.Changed("model") {
+			profile.Model = strings.TrimSpace(profileModel)
+		}
+		if flags.Changed("default-model") {
+			profile.DefaultModel = strings.TrimSpace(profileDefaultModel)
+		}
+		if flags.Changed("provider") {
+			profile.Provider.Name = profileProvider
+		}
+		if flags.Changed("base-url") {
+			profile.Provider.BaseURL = profileBaseURL
+		}
+		if flags.Changed("api-key-env") {
+			profile.Provider.APIKeyEnv = profileAPIKeyEnv
+		}
+
+		if profile.Provider.Name != "" && !profile.Provider.IsGroq() && profile.Provider.BaseURL == "" {
+			fmt.Fprintf(os.Stderr, "Error: provider '%s' needs --base-url\n", profile.Provider.Name)
+			os.Exit(1)
+		}
+
+		// Groq models are checked against the catalog; other providers serve their own
+		if profile.Provider.IsGroq() {
+			for _, model := range []string{profile.Model, profile.DefaultModel} {
+				if model == "" {
+					continue
+				}
+				if err := manager.ValidateModel(model); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		if err := manager.CreateProfile(name, profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Profile '%s' saved. Use it with --profile %s or 'go-code config profile use %s'\n", name, name, name)
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Make a profile the default (\"none\" to clear)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		name := args[0]
+		if name == "none" {
+			name = ""
+		}
+
+		if err := manager.UseProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if name == "" {
+			fmt.Println("✅ No default profile")
+		} else {
+			fmt.Printf("✅ Default profile: %s\n", name)
+		}
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a user profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		if err := manager.DeleteProfile(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Deleted profile '%s'\n", args[0])
+	},
+}
+
+func init() {
+	configCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+
+	profileCreateCmd.Flags().StringVar(&profileFrom, "from", "", "Start from an existing profile")
+	profileCreateCmd.Flags().StringVar(&profileDescription, "description", "", "Short description")
+	profileCreateCmd.Flags().StringVar(&profileModel, "model", "", "Model for every agent and the default model")
+	profileCreateCmd.Flags().StringVar(&profileDefaultModel, "default-model", "", "Default model only")
+	profileCreateCmd.Flags().StringVar(&profileProvider, "provider", "", "Provider name (groq, or any name for another OpenAI-compatible API)")
+	profileCreateCmd.Flags().StringVar(&profileBaseURL, "base-url", "", "Provider API root, e.g. http://localhost:11434/v1")
+	profileCreateCmd.Flags().StringVar(&profileAPIKeyEnv, "api-key-env", "", "Environment variable holding the provider's API key")
+}
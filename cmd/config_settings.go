@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/internal/config"
+	"go-code/pkg/models"
+)
+
+var (
+	agentModel       string
+	agentTemperature float32
+	agentMaxTokens   int
+	resetYes         bool
+	resetAll         bool
+	doctorFix        bool
+)
+
+// agentConfigCmd shows or changes one agent's preferences
+var agentConfigCmd = &cobra.Command{
+	Use:   "agent [name]",
+	Short: "Show or change an agent's model, temperature and max tokens",
+	Long: `Show or change the preferences used by a single agent.
+Without flags the agent's current settings are printed.
+
+Examples:
+  go-code config agent backend
+  go-code config agent backend --model llama-3.3-70b-versatile --temperature 0.2
+  go-code config agent planner --max-tokens 4096`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		agentType := models.AgentType(strings.ToLower(strings.TrimPrefix(args[0], "@")))
+		if !models.IsValidAgentType(agentType) {
+			fmt.Fprintf(os.Stderr, "Unknown agent '%s' (available: %s)\n", args[0], agentTypeList())
+			os.Exit(1)
+		}
+
+		agentConfig := manager.GetConfig().AgentPreferences[agentType]
+		flags := cmd.Flags()
+		if !flags.Changed("model") && !flags.Changed("temperature") && !flags.Changed("max-tokens") {
+			fmt.Printf("🤖 %s\n", agentType)
+			fmt.Printf("   Model:       %s\n", agentConfig.Model)
+			fmt.Printf("   Temperature: %g\n", agentConfig.Temperature)
+			fmt.Printf("   Max tokens:  %d\n", agentConfig.MaxTokens)
+			return
+		}
+
+		// Only the changed keys are written, so values from a profile, the
+		// environment, a project file or --set stay out of the user file
+		var settings [][2]string
+		prefix := "agent_preferences." + string(agentType) + "."
+		if flags.Changed("model") {
+			agentConfig.Model = strings.TrimSpace(agentModel)
+			settings = append(settings, [2]string{prefix + "model", agentConfig.Model})
+		}
+		if flags.Changed("temperature") {
+			agentConfig.Temperature = agentTemperature
+			settings = append(settings, [2]string{prefix + "temperature", strconv.FormatFloat(float64(agentTemperature), 'g', -1, 32)})
+		}
+		if flags.Changed("max-tokens") {
+			agentConfig.MaxTokens = agentMaxTokens
+			settings = append(settings, [2]string{prefix + "max_tokens", strconv.Itoa(agentMaxTokens)})
+		}
+
+		if err := manager.ValidateAgentConfig(agentType, agentConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, setting := range settings {
+			if err := manager.Set(setting[0], setting[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating agent: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		agentConfig = manager.GetConfig().AgentPreferences[agentType]
+		fmt.Printf("✅ %s: model %s, temperature %g, max tokens %d\n",
+			agentType, agentConfig.Model, agentConfig.Temperature, agentConfig.MaxTokens)
+		for _, setting := range settings {
+			if source := manager.Source(setting[0]); source != config.SourceFile {
+				color.Yellow("⚠️  The saved %s is currently overridden by %s", setting[0], source)
+			}
+		}
+	},
+}
+
+// commandsCmd manages the allowed command list
+var commandsCmd = &cobra.Command{
+	Use:   "commands",
+	Short: "Manage the commands agents may run without asking",
+}
+
+var commandsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List allowed commands",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		commands := manager.GetConfig().AllowedCommands
+		if len(commands) == 0 {
+			fmt.Println("No allowed commands")
+			return
+		}
+		for _, command := range commands {
+			fmt.Printf("  %s\n", command)
+		}
+	},
+}
+
+var commandsAddCmd = &cobra.Command{
+	Use:   "add [command]...",
+	Short: "Allow commands",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		for _, command := range args {
+			command = strings.TrimSpace(command)
+			if command == "" {
+				continue
+			}
+			if err := manager.AddAllowedCommand(command); err != nil {
+				fmt.Fprintf(os.Stderr, "Error adding command: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Allowed: %s\n", command)
+		}
+	},
+}
+
+var commandsRemoveCmd = &cobra.Command{
+	Use:   "remove [command]...",
+	Short: "Stop allowing commands",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+		for _, command := range args {
+			if !containsString(manager.GetConfig().AllowedCommands, command) {
+				fmt.Fprintf(os.Stderr, "Command '%s' is not in the allowed list\n", command)
+				os.Exit(1)
+			}
+			if err := manager.RemoveAllowedCommand(command); err != nil {
+				fmt.Fprintf(os.Stderr, "Error removing command: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Removed: %s\n", command)
+		}
+	},
+}
+
+// setCmd sets any setting by its dotted key
+var setCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set any setting by its dotted key",
+	Long: `Set a setting in your config file by its dotted key. The value is checked
+against the setting's type and the resulting configuration is validated
+before it is saved. Run 'go-code config show --effective' to see every key.
+
+Examples:
+  go-code config set default_model llama-3.3-70b-versatile
+  go-code config set agent_preferences.backend.temperature 0.2
+  go-code config set review.enabled true
+  go-code config set allowed_commands "npm,go,git"`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		key := config.NormalizeKey(args[0])
+		if err := manager.Set(key, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ %s = %s\n", key, args[1])
+		if source := manager.Source(key); source != config.SourceFile {
+			color.Yellow("⚠️  The saved value is currently overridden by %s", source)
+		}
+	},
+}
+
+// resetCmd restores the default configuration
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset the config file to defaults",
+	Long: `Replace your config file with the default settings. Your API key and key
+command are kept unless --all is given. The previous file is saved with a .bak suffix.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		if !resetYes && !confirm(fmt.Sprintf("Reset %s to defaults?", manager.ConfigPath())) {
+			fmt.Println("Cancelled")
+			return
+		}
+
+		if err := manager.Reset(!resetAll); err != nil {
+			fmt.Fprintf(os.Stderr, "Error resetting config: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Configuration reset (previous file saved to %s.bak)\n", manager.ConfigPath())
+	},
+}
+
+// editCmd opens the config file in an editor
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the config file in $EDITOR",
+	Long: `Open your config file in $VISUAL or $EDITOR. The edited file is validated
+before it replaces the current one; if it is invalid you can edit it again or
+discard your changes.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		original, err := os.ReadFile(manager.ConfigPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		tmp, err := os.CreateTemp("", "go-code-config-*"+filepath.Ext(manager.ConfigPath()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating temp file: %v\n", err)
+			os.Exit(1)
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+		defer os.Remove(tmpPath)
+
+		if err := os.WriteFile(tmpPath, original, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating temp file: %v\n", err)
+			os.Exit(1)
+		}
+
+		for {
+			if err := runEditor(tmpPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error running editor: %v\n", err)
+				os.Exit(1)
+			}
+
+			edited, err := os.ReadFile(tmpPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading edited config: %v\n", err)
+				os.Exit(1)
+			}
+			if string(edited) == string(original) {
+				fmt.Println("No changes")
+				return
+			}
+
+			err = manager.ReplaceFile(edited)
+			if err == nil {
+				fmt.Println("✅ Configuration saved")
+				return
+			}
+
+			color.Red("❌ Invalid configuration: %v", err)
+			if !confirm("Edit again?") {
+				fmt.Println("Changes discarded")
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// doctorCmd reports and fixes configuration problems
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration for problems",
+	Long: `Check the effective configuration for unknown or retired models, out-of-range
+values, unknown settings and other problems. With --fix, problems in your
+config file are repaired; problems set by a project file, environment variable
+or flag are only reported.
+
+Outdated config files are upgraded automatically when they are loaded; the
+previous file is kept as config.json.v<version>.bak.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := loadConfigManager()
+
+		problems := manager.Doctor()
+		if len(problems) == 0 {
+			fmt.Println("✅ No problems found")
+			return
+		}
+
+		fixable := 0
+		for _, problem := range problems {
+			label := problem.Key
+			if label == "" {
+				label = "config"
+			}
+			if problem.Fixable() {
+				fixable++
+				fmt.Printf("%s %s: %s\n", color.YellowString("⚠️ "), label, problem.Message)
+			} else {
+				fmt.Printf("%s %s: %s", color.RedString("❌"), label, problem.Message)
+				color.HiBlack("  (%s)", problem.Source)
+			}
+		}
+
+		if fixable == 0 {
+			os.Exit(1)
+		}
+		if !doctorFix {
+			fmt.Printf("\n%d problem(s) can be fixed with 'go-code config doctor --fix'\n", fixable)
+			os.Exit(1)
+		}
+
+		if err := manager.Fix(problems); err != nil {
+			fmt.Fprintf(os.Stderr, "Error fixing config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n✅ Fixed %d problem(s) in %s\n", fixable, manager.ConfigPath())
+		if fixable < len(problems) {
+			os.Exit(1)
+		}
+	},
+}
+
+// loadConfigManager creates and loads the config manager, exiting on error
+func loadConfigManager() *config.Manager {
+	manager := newConfigManager()
+	if err := manager.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return manager
+}
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor variable may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	command := exec.Command(parts[0], append(parts[1:], path)...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	return command.Run()
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// agentTypeList returns the known agent names separated by commas
+func agentTypeList() string {
+	names := make([]string, len(models.AllAgentTypes))
+	for i, agentType := range models.AllAgentTypes {
+		names[i] = string(agentType)
+	}
+	return strings.Join(names, ", ")
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	configCmd.AddCommand(agentConfigCmd)
+	agentConfigCmd.Flags().StringVar(&agentModel, "model", "", "Model the agent uses")
+	agentConfigCmd.Flags().Float32Var(&agentTemperature, "temperature", 0, "Sampling temperature (0-2)")
+	agentConfigCmd.Flags().IntVar(&agentMaxTokens, "max-tokens", 0, "Maximum tokens per response")
+
+	configCmd.AddCommand(commandsCmd)
+	commandsCmd.AddCommand(commandsListCmd)
+	commandsCmd.AddCommand(commandsAddCmd)
+	commandsCmd.AddCommand(commandsRemoveCmd)
+
+	configCmd.AddCommand(setCmd)
+
+	configCmd.AddCommand(resetCmd)
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Don't ask for confirmation")
+	resetCmd.Flags().BoolVar(&resetAll, "all", false, "Also clear the API key and key command")
+
+	configCmd.AddCommand(editCmd)
+
+	configCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair problems in the config file")
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go-code/internal/stack"
+	"go-code/internal/ui"
+)
+
+// detectCmd prints the stack detected in a project
+var detectCmd = &cobra.Command{
+	Use:   "detect [path]",
+	Short: "Detect a project's languages, frameworks and tooling (offline)",
+	Long: `Inspect a project's manifests, lockfiles and source imports and print its
+stack: languages, frameworks, test runner, package manager, entrypoints and
+the commands that install, build and test it. Agents' prompts and build plans
+use the same profile.
+
+It reads go.mod, package.json, pyproject.toml, requirements.txt, Cargo.toml,
+lockfiles and Dockerfiles, and needs no API key or network access.
+
+The path defaults to the current directory.
+
+Examples:
+  go-code detect
+  go-code detect ./my-service --output json`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{jsonAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+
+		profile, err := stack.Detect(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error detecting stack: %v\n", err)
+			os.Exit(1)
+		}
+
+		if ui.IsMachineReadable() {
+			ui.PrintJSON(profile)
+			return
+		}
+		printProfile(profile)
+	},
+}
+
+// printProfile writes a profile for people
+func printProfile(profile *stack.Profile) {
+	fmt.Fprintf(ui.Stdout, "🔍 Stack of %s\n", profile.Root)
+	if profile.Empty() {
+		fmt.Fprintln(ui.Stdout, "   Nothing detected: no known manifests or source files.")
+		return
+	}
+
+	rows := []struct{ label, value string }{
+		{"Languages", strings.Join(profile.Languages, ", ")},
+		{"Frameworks", strings.Join(profile.Frameworks, ", ")},
+		{"Test runner", profile.TestRunner},
+		{"Package manager", profile.PackageManager},
+		{"Entrypoints", strings.Join(profile.Entrypoints, ", ")},
+		{"Tools", strings.Join(profile.Tools, ", ")},
+		{"Manifests", strings.Join(profile.Manifests, ", ")},
+		{"Install", profile.Commands.Install},
+		{"Build", profile.Commands.Build},
+		{"Test", profile.Commands.Test},
+	}
+	for _, row := range rows {
+		if row.value != "" {
+			fmt.Fprintf(ui.Stdout, "   %-16s %s\n", row.label+":", row.value)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+}
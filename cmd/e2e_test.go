@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+	"go-code/internal/catalog"
+	"go-code/internal/events"
+	"go-code/internal/llmtest"
+	"go-code/internal/stack"
+	"go-code/internal/usage"
+	"go-code/pkg/models"
+)
+
+// setupCLI gives the test an empty home and working directory and returns
+// the --set flags that point go-code at server
+func setupCLI(t *testing.T, server *llmtest.Server) (work string, flags []string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	work = t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	return work, []string{
+		"--set", "provider.base_url=" + server.BaseURL(),
+		"--set", "groq_api_key=test-key",
+	}
+}
+
+// runCLI executes go-code with args, resetting the global flag state left
+// behind by earlier runs
+func runCLI(t *testing.T, args ...string) {
+	t.Helper()
+	cfgFile, configOverrides, profileName = "", nil, ""
+	noCache, cacheOnly, recordPath, replayPath = false, false, "", ""
+	outputFormat, refreshModels = "", false
+
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("go-code %s: %v", strings.Join(args, " "), err)
+	}
+}
+
+func TestChatCommand(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "Use a REST API.", PromptTokens: 30, CompletionTokens: 5})
+	_, flags := setupCLI(t, server)
+
+	runCLI(t, append([]string{"chat", "@backend", "How", "should", "I", "design", "it?"}, flags...)...)
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(requests))
+	}
+	if got := llmtest.UserMessage(requests[0]); got != "How should I design it?" {
+		t.Errorf("user message = %q", got)
+	}
+	if !strings.HasPrefix(llmtest.SystemPrompt(requests[0]), "You are the Backend Agent") {
+		t.Errorf("system prompt = %q, want the backend agent's", llmtest.SystemPrompt(requests[0]))
+	}
+
+	entries, err := usage.Read(usage.DefaultLedgerPath(), time.Time{})
+	if err != nil {
+		t.Fatalf("reading ledger: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Command != "chat" || entries[0].PromptTokens != 30 {
+		t.Errorf("ledger = %+v, want one chat entry with 30 prompt tokens", entries)
+	}
+}
+
+func TestChatRecordReplay(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "Recorded advice."})
+	work, flags := setupCLI(t, server)
+	cassette := filepath.Join(work, "testdata", "chat.cassette.json")
+
+	runCLI(t, append([]string{"chat", "@planner", "Plan a blog", "--record", cassette}, flags...)...)
+	if _, err := os.Stat(cassette); err != nil {
+		t.Fatalf("cassette was not written: %v", err)
+	}
+
+	// Replay from another project, whose facts give a different system
+	// prompt. The server has no replies left, so only the cassette can answer.
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "go.mod"), []byte("module other\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(other); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, append([]string{"chat", "@planner", "Plan a blog", "--replay", cassette}, flags...)...)
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server saw %d requests, want the replay to stay off the network", n)
+	}
+}
+
+func TestBuildCommand(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		switch system := llmtest.SystemPrompt(req); {
+		case strings.HasPrefix(system, "You are the Planner Agent"):
+			return llmtest.Reply{Content: "1. **[BACKEND]** Create the Express server\n2. [SECURITY] Review the server"}
+		case strings.HasPrefix(system, "You are the Backend Agent"):
+			return llmtest.Reply{Content: "Here you go:\n\n```javascript\n// filename: server.js\nconst express = require('express');\nconst app = express();\napp.listen(3000);\n```"}
+		case strings.HasPrefix(system, "You are the Security Agent"):
+			return llmtest.Reply{Content: "The server looks fine. No changes needed."}
+		}
+		return llmtest.Reply{Status: 500, Error: "unexpected agent"}
+	})
+	work, flags := setupCLI(t, server)
+
+	runCLI(t, append([]string{"build", "an express server", "--no-scan"}, flags...)...)
+
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("server saw %d requests, want plan + 2 tasks", n)
+	}
+	data, err := os.ReadFile(filepath.Join(work, "generated-project", "server.js"))
+	if err != nil {
+		t.Fatalf("server.js was not written: %v", err)
+	}
+	if !strings.Contains(string(data), "app.listen(3000);") {
+		t.Errorf("server.js = %q", data)
+	}
+}
+
+// captureStdout runs fn and returns what it wrote to stdout
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestChatJSONOutput(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "Use JWT.", PromptTokens: 20, CompletionTokens: 3})
+	_, flags := setupCLI(t, server)
+
+	out := captureStdout(t, func() {
+		runCLI(t, append([]string{"chat", "@security", "How do I do auth?", "--output", "json"}, flags...)...)
+	})
+
+	var response models.Response
+	if err := json.Unmarshal(out, &response); err != nil {
+		t.Fatalf("stdout is not a JSON response: %v\n%s", err, out)
+	}
+	if response.Content != "Use JWT." || response.Agent != models.SecurityAgent || response.TokensUsed != 23 {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestDetectJSONOutput(t *testing.T) {
+	work, _ := setupCLI(t, llmtest.NewServer(t))
+	os.WriteFile(filepath.Join(work, "package.json"), []byte(`{"dependencies": {"express": "^4"}, "devDependencies": {"jest": "^29"}}`), 0644)
+	os.WriteFile(filepath.Join(work, "yarn.lock"), nil, 0644)
+
+	out := captureStdout(t, func() {
+		runCLI(t, "detect", "--output", "json")
+	})
+
+	var profile stack.Profile
+	if err := json.Unmarshal(out, &profile); err != nil {
+		t.Fatalf("stdout is not a JSON profile: %v\n%s", err, out)
+	}
+	if profile.Primary() != stack.JavaScript || profile.PackageManager != "yarn" || profile.TestRunner != "jest" || len(profile.Frameworks) != 1 {
+		t.Errorf("profile = %+v", profile)
+	}
+}
+
+func TestBuildNDJSONOutput(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		if strings.HasPrefix(llmtest.SystemPrompt(req), "You are the Planner Agent") {
+			return llmtest.Reply{Content: "1. [BACKEND] Create the server"}
+		}
+		return llmtest.Reply{Content: "```js\n// filename: server.js\nrequire('http').createServer().listen(8080);\n```"}
+	})
+	_, flags := setupCLI(t, server)
+
+	out := captureStdout(t, func() {
+		runCLI(t, append([]string{"build", "a server", "--output", "ndjson"}, flags...)...)
+	})
+
+	var types []string
+	var summary events.Event
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line is not a JSON event: %v\n%s", err, scanner.Text())
+		}
+		switch event.Type {
+		case events.StageStarted, events.StageCompleted, events.TokensUsed:
+			// Progress details, checked in the orchestrator tests
+		default:
+			types = append(types, string(event.Type))
+		}
+		if event.Type == events.Summary {
+			summary = event
+		}
+	}
+
+	want := []string{"build_started", "plan", "task_started", "file_written", "task_completed", "summary"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+	if len(summary.Files) != 1 || summary.Files[0] != "server.js" || summary.Summary == nil || summary.Summary.Total.Requests != 2 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestModelsRefresh(t *testing.T) {
+	server := llmtest.NewServer(t)
+	_, flags := setupCLI(t, server)
+
+	// The catalog comes from the configured endpoint, not Groq's
+	runCLI(t, append([]string{"models", "list", "--refresh"}, flags...)...)
+	groq := catalog.Load(catalog.DefaultCachePath())
+	if groq.Source != catalog.SourceCache || len(groq.Models) != 3 {
+		t.Fatalf("catalog = %s with %d models, want the server's 3", groq.Source, len(groq.Models))
+	}
+
+	// Another provider's models are cached apart from Groq's
+	runCLI(t, append([]string{"models", "list", "--refresh", "--set", "provider.name=ollama"}, flags...)...)
+	ollama := filepath.Join(filepath.Dir(catalog.DefaultCachePath()), "models-ollama.json")
+	if _, err := os.Stat(ollama); err != nil {
+		t.Errorf("ollama catalog was not cached: %v", err)
+	}
+}
+
+func TestConfigAgentKeepsOtherLayersOut(t *testing.T) {
+	server := llmtest.NewServer(t)
+	_, flags := setupCLI(t, server)
+	t.Setenv("GOCODE_PROFILE", "fast")
+
+	runCLI(t, append([]string{"config", "agent", "backend", "--max-tokens", "4096"}, flags...)...)
+
+	home, _ := os.UserHomeDir()
+	data, err := os.ReadFile(filepath.Join(home, ".go-code", "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved models.Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the changed key is saved; the profile's model and the --set
+	// overrides stay out of the user file
+	backend := saved.AgentPreferences[models.BackendAgent]
+	want := models.DefaultConfig().AgentPreferences[models.BackendAgent].Model
+	if backend.MaxTokens != 4096 || backend.Model != want {
+		t.Errorf("saved backend = %+v, want max tokens 4096 and model %s", backend, want)
+	}
+	if saved.GroqAPIKey != "" || saved.Provider.BaseURL == server.BaseURL() {
+		t.Errorf("--set overrides were saved: key %q, base URL %q", saved.GroqAPIKey, saved.Provider.BaseURL)
+	}
+}
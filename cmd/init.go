@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"go-code/internal/config"
 )
 
 // initCmd represents the init command
@@ -18,7 +17,7 @@ This command creates ~/.go-code/config.json with default agent configurations.
 You'll need to set your Groq API key after initialization:
   go-code config set-key YOUR_API_KEY`,
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := config.NewManager()
+		manager := newConfigManager()
 		
 		if err := manager.Load(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
@@ -37,7 +36,7 @@ You'll need to set your Groq API key after initialization:
 		fmt.Println("3. Start chatting with an agent:")
 		fmt.Println("   go-code chat @planner \"Help me plan a web application\"")
 		fmt.Println()
-		fmt.Println("Configuration saved to:", fmt.Sprintf("%s/.go-code/config.json", os.Getenv("HOME")))
+		fmt.Println("Configuration saved to:", manager.ConfigPath())
 	},
 }
 
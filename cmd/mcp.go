@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"go-code/internal/mcp"
+	"go-code/internal/ui"
+)
+
+var mcpDir string
+
+// mcpCmd serves the agents to other assistants over the Model Context Protocol
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio",
+	Long: `Run an MCP server on stdin/stdout so MCP-capable assistants can delegate to
+go-code's specialists.
+
+Tools:
+  ask_<agent>   Ask one agent, e.g. ask_backend or ask_security ({"message", "context"})
+  plan          Break a project down into agent tasks ({"description"})
+  build         Plan and generate a project into --dir ({"description"})
+
+The files below --dir are exposed as file:// resources. Builds are journaled
+like any other and can be reverted with 'go-code undo'.
+
+Example client configuration:
+  {"mcpServers": {"go-code": {"command": "go-code", "args": ["mcp"]}}}`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// stdin and stdout carry the protocol, so nothing else may be
+		// printed there or read from there
+		ui.Silence()
+		promptForPermission = false
+
+		manager := newConfigManager()
+		if err := manager.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := manager.ValidateConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		server := mcp.NewServer(newTeam(manager), mcpDir)
+		if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "MCP server error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.Flags().StringVar(&mcpDir, "dir", "generated-project", "Directory builds write into and whose files are resources")
+}
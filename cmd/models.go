@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/internal/catalog"
+	"go-code/pkg/models"
+)
+
+var refreshModels bool
+
+// modelsCmd groups the model catalog commands
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Inspect the model catalog",
+	Long: `Inspect the models go-code knows about.
+
+The catalog is fetched from the configured provider's models endpoint,
+cached in ~/.go-code/models.json for 24 hours (models-<provider>.json for
+providers other than Groq), and falls back to a list built into go-code when
+offline. Config validation uses the same catalog.`,
+}
+
+// modelsListCmd lists the catalog
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available models and their capabilities",
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := newConfigManager()
+		if err := manager.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg := manager.GetConfig()
+		client := newAPIClient(manager)
+		cachePath := catalogCachePath(cfg.Provider)
+
+		var models *catalog.Catalog
+		switch {
+		case refreshModels && cfg.Provider.IsGroq() && client.APIKey == "":
+			fmt.Fprintln(os.Stderr, "❌ Refreshing the catalog requires a Groq API key")
+			os.Exit(1)
+		case refreshModels:
+			refreshed, err := catalog.Refresh(client, cachePath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error refreshing models: %v\n", err)
+				os.Exit(1)
+			}
+			models = refreshed
+		default:
+			models = catalog.LoadFresh(client, cachePath, catalog.DefaultTTL)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "MODEL\tCONTEXT\tMAX OUTPUT\tTOOLS\tJSON\t$/M IN\t$/M OUT\tOWNER")
+		for _, model := range models.Models {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				model.ID,
+				formatTokens(model.ContextWindow),
+				formatTokens(model.MaxCompletionTokens),
+				yesNo(model.Tools),
+				yesNo(model.JSONMode),
+				formatPrice(model.InputPrice),
+				formatPrice(model.OutputPrice),
+				model.OwnedBy)
+		}
+		writer.Flush()
+
+		fmt.Println()
+		source := models.Source
+		if !models.FetchedAt.IsZero() {
+			source = fmt.Sprintf("%s, fetched %s", source, models.FetchedAt.Format("2006-01-02 15:04"))
+		}
+		color.HiBlack("Source: %s", source)
+	},
+}
+
+// catalogCachePath returns where the provider's model list is cached. Other
+// providers get their own file so their models never replace Groq's, which
+// config validation and pricing rely on.
+func catalogCachePath(provider models.ProviderConfig) string {
+	path := catalog.DefaultCachePath()
+	if provider.IsGroq() {
+		return path
+	}
+	return filepath.Join(filepath.Dir(path), "models-"+provider.Name+".json")
+}
+
+// formatTokens renders a token count compactly, e.g. 131072 as 128k
+func formatTokens(tokens int) string {
+	switch {
+	case tokens <= 0:
+		return "-"
+	case tokens%1024 == 0:
+		return fmt.Sprintf("%dk", tokens/1024)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
+
+// formatPrice renders a price per million tokens
+func formatPrice(price float64) string {
+	if price <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", price)
+}
+
+// yesNo renders a capability flag
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "-"
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+	modelsCmd.AddCommand(modelsListCmd)
+	modelsListCmd.Flags().BoolVar(&refreshModels, "refresh", false, "Fetch the latest model list from the API")
+}
@@ -3,13 +3,25 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"go-code/internal/api"
+	"go-code/internal/config"
+	"go-code/internal/ui"
+	"go-code/pkg/gocode"
 )
 
 var cfgFile string
+var configOverrides []string
+var profileName string
 var useGptOss120b bool
+var noCache bool
+var cacheOnly bool
+var recordPath string
+var replayPath string
+var outputFormat string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -31,6 +43,12 @@ for software development using the Groq API. Each agent has distinct roles and e
 Use @agent syntax for auto-completion and direct agent communication.`,
 	// Uncomment the following line if your bare application has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOverrides(); err != nil {
+			return err
+		}
+		return setOutput(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -40,45 +58,129 @@ func Execute() error {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-code/config.json)")
-	rootCmd.PersistentFlags().BoolVar(&useGptOss120b, "gpt-oss-120b", false, "Use the new OpenAI GPT-OSS-120B model")
+	rootCmd.PersistentFlags().StringArrayVar(&configOverrides, "set", nil, "override a config value for this run, e.g. --set agent_preferences.backend.temperature=0.1")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "use a named profile for this run (also GOCODE_PROFILE), e.g. fast, quality, local")
+	rootCmd.PersistentFlags().BoolVar(&useGptOss120b, "gpt-oss-120b", false, "Use the openai/gpt-oss-120b model for every agent")
+	rootCmd.PersistentFlags().MarkDeprecated("gpt-oss-120b", "use --profile quality instead")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "don't use the response cache for this run")
+	rootCmd.PersistentFlags().BoolVar(&cacheOnly, "cache-only", false, "answer only from the response cache, failing on a miss")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record every API exchange to this cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "answer API requests from this cassette file instead of the network")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "", "output format: text, plain, json or ndjson (default text on a terminal, plain otherwise)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
-
-		// Search config in home directory with name ".go-code" (without extension).
-		viper.AddConfigPath(home + "/.go-code")
-		viper.SetConfigType("json")
-		viper.SetConfigName("config")
+// newConfigManager returns a config manager that honors --config, --set and
+// --profile. Every command loads configuration through it so the layers stay
+// consistent: defaults, user file, project .go-code.{json,yaml,toml}, the
+// selected profile, GOCODE_* env and finally flags.
+func newConfigManager() *config.Manager {
+	manager := config.NewManagerWithPath(cfgFile)
+	for _, override := range configOverrides {
+		key, value, _ := strings.Cut(override, "=")
+		manager.SetOverride(strings.TrimSpace(key), value)
+	}
+
+	// --gpt-oss-120b predates profiles and is kept as an alias
+	if profileName == "" && useGptOss120b {
+		profileName = "quality"
+	}
+	if profileName != "" {
+		manager.SetOverride("profile", profileName)
+	}
+	return manager
+}
+
+// newAPIClient returns a client for the configured provider. Its rate
+// limiter is shared by every agent using the client.
+func newAPIClient(manager *config.Manager) *api.GroqClient {
+	cfg := manager.GetConfig()
+	client := api.NewClient(manager.ProviderAPIKey(), cfg.Provider.BaseURL)
+	client.Provider = cfg.Provider.Name
+	client.Limiter = api.NewRateLimiter(manager.RateLimit)
+
+	if (cfg.Cache.Enabled && !noCache) || cacheOnly {
+		client.Cache = newResponseCache(cfg)
+		client.CacheOnly = cacheOnly
+	}
+
+	if recordPath != "" || replayPath != "" {
+		path, mode := recordPath, api.ModeRecord
+		if replayPath != "" {
+			path, mode = replayPath, api.ModeReplay
+		}
+		recorder, err := api.NewRecorder(path, mode, client.HTTPClient.Transport)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		client.HTTPClient.Transport = recorder
+	}
+	return client
+}
+
+// newTeam creates the agent team from the loaded configuration
+func newTeam(manager *config.Manager, opts ...gocode.Option) *gocode.Team {
+	opts = append([]gocode.Option{
+		gocode.WithConfig(manager.GetConfig()),
+		gocode.WithClient(newAPIClient(manager)),
+	}, opts...)
+	// Agents work on the project whose config file was found, or the
+	// working directory
+	if path := manager.ProjectPath(); path != "" {
+		opts = append(opts, gocode.WithProjectDir(filepath.Dir(path)))
+	}
+	if len(manager.GetConfig().MCPServers) > 0 {
+		opts = append(opts, gocode.WithTools(newToolbox(manager)))
+	}
+	if canPrompt() {
+		opts = append(opts, gocode.WithCommandConfirm(confirmCommand))
+	}
+
+	team, err := gocode.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	return team
+}
 
-	viper.AutomaticEnv() // read in environment variables that match
+// jsonAnnotation marks the commands that can write JSON
+const jsonAnnotation = "output-json"
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+// setOutput applies --output. Only commands annotated with jsonAnnotation
+// support the JSON formats.
+func setOutput(cmd *cobra.Command) error {
+	format := ui.OutputFormat(strings.ToLower(outputFormat))
+	if err := ui.SetOutput(format); err != nil {
+		return err
 	}
+	if ui.IsMachineReadable() && cmd.Annotations[jsonAnnotation] == "" {
+		return fmt.Errorf("%s doesn't support --output %s", cmd.CommandPath(), format)
+	}
+	return nil
 }
 
-// IsGptOss120bEnabled returns whether the --gpt-oss-120b flag is set
-func IsGptOss120bEnabled() bool {
-	return useGptOss120b
-}
\ No newline at end of file
+// validateOverrides checks the --set and cache flags are well formed
+func validateOverrides() error {
+	if noCache && cacheOnly {
+		return fmt.Errorf("--no-cache and --cache-only can't be used together")
+	}
+	if recordPath != "" && replayPath != "" {
+		return fmt.Errorf("--record and --replay can't be used together")
+	}
+	for _, override := range configOverrides {
+		if key, _, ok := strings.Cut(override, "="); !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid --set %q, expected key=value", override)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go-code/internal/scanner"
+	"go-code/internal/ui"
+)
+
+var scanSARIFPath string
+
+// scanCmd runs the offline security scanner over a directory
+var scanCmd = &cobra.Command{
+	Use:   "scan [path]",
+	Short: "Scan code for common security issues (offline)",
+	Long: `Run the built-in rule-based security scanner over a directory.
+It needs no API key or network access and checks for hard-coded secrets, eval,
+SQL string concatenation, disabled TLS verification, wildcard CORS, weak JWT
+secrets and missing input validation.
+
+The path defaults to ./generated-project.
+
+Examples:
+  go-code scan
+  go-code scan ./my-service --sarif results.sarif`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "generated-project"
+		if len(args) == 1 {
+			root = args[0]
+		}
+
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving path: %v\n", err)
+			os.Exit(1)
+		}
+
+		findings, err := scanner.New().ScanDir(absRoot)
+		if err != nil {
+			ui.DisplayError(fmt.Errorf("scan failed: %w", err))
+			os.Exit(1)
+		}
+
+		if scanSARIFPath != "" {
+			if err := scanner.WriteSARIFFile(scanSARIFPath, findings); err != nil {
+				ui.DisplayError(err)
+				os.Exit(1)
+			}
+		}
+
+		ui.DisplayScanFindings(findings, scanSARIFPath)
+
+		if len(findings) > 0 {
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().StringVar(&scanSARIFPath, "sarif", "", "Write findings as a SARIF report to this path")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go-code/internal/server"
+	"go-code/internal/ui"
+)
+
+// serveTokenEnvVar holds the API token when --token isn't given
+const serveTokenEnvVar = "GO_CODE_SERVE_TOKEN"
+
+var (
+	serveAddr      string
+	serveToken     string
+	serveMaxBuilds int
+)
+
+// serveCmd exposes the agents and builds over a local HTTP API
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the agents and builds over a local HTTP/JSON API",
+	Long: `Run a local HTTP server that exposes the agent team, so dashboards and editor
+plugins can drive go-code without shelling out.
+
+Endpoints:
+  GET    /agents                  List the agents and their models
+  POST   /chat                    Chat with an agent; "stream": true streams the reply as SSE
+  GET    /builds                  List builds
+  POST   /builds                  Start a build of {"description": "..."}
+  GET    /builds/{id}             Build status and result (SSE events with Accept: text/event-stream)
+  GET    /builds/{id}/events      Build events as server-sent events
+  GET    /builds/{id}/download    The generated project as a zip
+  DELETE /builds/{id}             Cancel a build
+  GET    /v1/models               The agents as OpenAI-compatible models (go-code/<agent>)
+  POST   /v1/chat/completions     OpenAI-compatible chat completions with an agent as model
+
+OpenAI-compatible tools use http://<addr>/v1 as base URL and the token as API
+key. Every request needs the token, as "Authorization: Bearer <token>" or ?token=.
+It is taken from --token or $GO_CODE_SERVE_TOKEN, or generated and printed at
+startup. Builds are kept in memory; only --max-builds run at a time.
+
+Examples:
+  go-code serve
+  go-code serve --addr 127.0.0.1:9000 --max-builds 4`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		manager := newConfigManager()
+		if err := manager.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := manager.ValidateConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+
+		token := serveToken
+		if token == "" {
+			token = os.Getenv(serveTokenEnvVar)
+		}
+		generated := token == ""
+		if generated {
+			token = newServeToken()
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if host, _, err := net.SplitHostPort(listener.Addr().String()); err == nil {
+			if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+				ui.DisplayWarning(fmt.Sprintf("Listening on %s, which is reachable from other machines", host))
+			}
+		}
+
+		// Requests can't wait for an answer on the terminal
+		promptForPermission = false
+		api := server.New(newTeam(manager), server.Options{Token: token, MaxBuilds: serveMaxBuilds})
+		httpServer := &http.Server{Handler: api, ReadHeaderTimeout: 10 * time.Second}
+
+		ui.DisplaySuccess(fmt.Sprintf("go-code API listening on http://%s", listener.Addr()))
+		if generated {
+			ui.DisplayInfo(fmt.Sprintf("Token: %s", token))
+		}
+
+		// Ctrl-C cancels the running builds and stops the server
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			<-ctx.Done()
+			api.Close()
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
+
+		if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+			os.Exit(1)
+		}
+		<-stopped
+	},
+}
+
+// newServeToken generates a random API token
+func newServeToken() string {
+	token := make([]byte, 24)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token clients must send (default $"+serveTokenEnvVar+" or a generated one)")
+	serveCmd.Flags().IntVar(&serveMaxBuilds, "max-builds", server.DefaultMaxBuilds, "Maximum number of builds running at once")
+}
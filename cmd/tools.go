@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go-code/internal/config"
+	"go-code/internal/mcp"
+	"go-code/internal/ui"
+	"go-code/pkg/models"
+)
+
+// toolStartTimeout bounds how long the configured MCP servers may take to
+// start and list their tools
+const toolStartTimeout = 30 * time.Second
+
+// promptForPermission is whether agents' tool calls and the commands builds
+// run may be confirmed on the terminal. Commands that need stdin or the
+// screen for something else turn it off; their tool calls and commands then
+// need a session permission.
+var promptForPermission = true
+
+// newToolbox starts the MCP servers in the configuration. Servers that fail
+// are reported and left out.
+func newToolbox(manager *config.Manager) *mcp.Toolbox {
+	var confirmCall mcp.ConfirmFunc
+	if canPrompt() {
+		confirmCall = confirmToolCall
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolStartTimeout)
+	defer cancel()
+	toolbox, err := mcp.StartToolbox(ctx, manager.GetConfig(), confirmCall)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %v\n", err)
+	}
+	return toolbox
+}
+
+// canPrompt reports whether permission may be asked for on the terminal
+func canPrompt() bool {
+	return promptForPermission && !ui.IsMachineReadable()
+}
+
+// confirmCommand asks whether a build may run a command
+func confirmCommand(command, dir string) bool {
+	return confirm(fmt.Sprintf("▶️  go-code wants to run `%s` in %s. Allow?", command, dir))
+}
+
+// confirmToolCall asks whether an agent may call a server's tool
+func confirmToolCall(agentType models.AgentType, server, tool, arguments string) bool {
+	return confirm(fmt.Sprintf("🔧 The %s agent wants to call %s/%s with %s. Allow?", agentType, server, tool, arguments))
+}
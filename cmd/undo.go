@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/internal/filewriter"
+	"go-code/internal/ui"
+)
+
+var listRuns bool
+
+// undoCmd restores the files touched by a previous build
+var undoCmd = &cobra.Command{
+	Use:   "undo [run-id]",
+	Short: "Undo the file changes made by a build",
+	Long: `Restore the project tree to its state before a build.
+Every file go-code writes is journaled under ~/.go-code/runs/<run-id>. Undo puts
+back the previous version of overwritten files and removes files the run created.
+
+Without a run ID the most recent run is undone.
+
+Examples:
+  go-code undo
+  go-code undo 20240101-120000-a1b2c3
+  go-code undo --list`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		journalDir := filewriter.DefaultJournalDir()
+
+		runs, err := filewriter.ListRuns(journalDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		if listRuns {
+			displayRuns(runs)
+			return
+		}
+
+		var runID string
+		if len(args) == 1 {
+			runID = args[0]
+		} else {
+			for _, run := range runs {
+				if run.UndoneAt == nil {
+					runID = run.RunID
+					break
+				}
+			}
+			if runID == "" {
+				fmt.Fprintln(os.Stderr, "No runs to undo")
+				os.Exit(1)
+			}
+		}
+
+		changed, err := filewriter.Undo(journalDir, runID)
+		for _, path := range changed {
+			fmt.Printf("  ↩️  %s\n", path)
+		}
+		if err != nil {
+			ui.DisplayError(fmt.Errorf("undo failed: %w", err))
+			os.Exit(1)
+		}
+
+		ui.DisplaySuccess(fmt.Sprintf("Undid run %s (%d files)", runID, len(changed)))
+	},
+}
+
+// displayRuns prints the journaled runs
+func displayRuns(runs []*filewriter.RunManifest) {
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded")
+		return
+	}
+
+	gray := color.New(color.FgHiBlack)
+	for _, run := range runs {
+		status := ""
+		if run.UndoneAt != nil {
+			status = " (undone)"
+		}
+		fmt.Printf("%s  %d files%s\n", run.RunID, len(run.Entries), status)
+		gray.Printf("   %s\n", run.Description)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().BoolVar(&listRuns, "list", false, "List recorded runs instead of undoing")
+}
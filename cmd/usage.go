@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go-code/internal/ui"
+	"go-code/internal/usage"
+	"go-code/pkg/models"
+)
+
+var usageSince string
+var usageBy string
+
+// usageCmd summarizes the usage ledger
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and cost",
+	Long: `Summarize the tokens and cost recorded in ~/.go-code/usage.jsonl by chat and
+build. Costs use the per-model prices in the model catalog ('go-code models list').
+
+Examples:
+  go-code usage
+  go-code usage --since 7d --by model
+  go-code usage --since 2024-06-01 --by day`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		since, err := usage.ParseSince(usageSince, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		entries, err := usage.Read(usage.DefaultLedgerPath(), since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading usage: %v\n", err)
+			os.Exit(1)
+		}
+
+		totals, err := usage.Summarize(entries, usageBy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No usage recorded")
+			return
+		}
+
+		var grand models.Usage
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(writer, "%s\tREQUESTS\tPROMPT\tCOMPLETION\tTOTAL\tCOST\t\n", strings.ToUpper(usageBy))
+		for _, total := range totals {
+			grand.Add(total.Usage)
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%s\t\n", total.Key, total.Requests,
+				total.PromptTokens, total.CompletionTokens, total.TotalTokens(), ui.FormatCost(total.Cost))
+		}
+		fmt.Fprintf(writer, "TOTAL\t%d\t%d\t%d\t%d\t%s\t\n", grand.Requests,
+			grand.PromptTokens, grand.CompletionTokens, grand.TotalTokens(), ui.FormatCost(grand.Cost))
+		writer.Flush()
+
+		if !since.IsZero() {
+			color.HiBlack("\nSince %s", since.Local().Format("2006-01-02 15:04"))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only count usage since a duration ago (7d, 12h, 2w) or a date (YYYY-MM-DD)")
+	usageCmd.Flags().StringVar(&usageBy, "by", "agent", "Group by "+strings.Join(usage.Groupings, ", "))
+}
@@ -1,10 +1,11 @@
 package agents
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/fatih/color"
 	"go-code/internal/api"
+	"go-code/internal/prompts"
 	"go-code/pkg/models"
 )
 
@@ -15,12 +16,15 @@ type BaseAgent struct {
 	icon         string
 	role         string
 	color        *color.Color
-	systemPrompt string
+	systemPrompt *prompts.SystemTemplate
 	client       *api.GroqClient
 	config       models.AgentConfig
+	tools        api.Toolset
+	facts        *prompts.Facts
 }
 
-// NewBaseAgent creates a new base agent
+// NewBaseAgent creates a new base agent. systemPrompt is a template rendered
+// with the project facts; it panics if the template is invalid.
 func NewBaseAgent(
 	agentType models.AgentType,
 	name, icon, role, systemPrompt string,
@@ -34,7 +38,7 @@ func NewBaseAgent(
 		icon:         icon,
 		role:         role,
 		color:        color.New(colorAttr),
-		systemPrompt: systemPrompt,
+		systemPrompt: prompts.MustParseSystem(systemPrompt),
 		client:       client,
 		config:       config,
 	}
@@ -65,17 +69,50 @@ func (a *BaseAgent) Role() string {
 	return a.role
 }
 
-// GetSystemPrompt returns the agent's system prompt
+// GetSystemPrompt returns the agent's system prompt rendered with the
+// project facts
 func (a *BaseAgent) GetSystemPrompt() string {
-	return a.systemPrompt
+	return a.systemPrompt.Render(a.facts)
+}
+
+// SetFacts gives the agent's prompts facts about the project it works on
+func (a *BaseAgent) SetFacts(facts *prompts.Facts) {
+	a.facts = facts
+}
+
+// SetTools gives the agent tools it may call during its requests; nil takes
+// them away
+func (a *BaseAgent) SetTools(tools api.Toolset) {
+	a.tools = tools
 }
 
 // Process sends a message to the agent and returns the response
-func (a *BaseAgent) Process(context string, message string) (*models.Response, error) {
-	fullMessage := message
-	if context != "" {
-		fullMessage = fmt.Sprintf("Context: %s\n\nUser Request: %s", context, message)
+func (a *BaseAgent) Process(taskContext string, message string) (*models.Response, error) {
+	return a.ProcessStream(context.Background(), taskContext, message, nil)
+}
+
+// ProcessStream is Process with the response passed to onDelta as it streams in
+func (a *BaseAgent) ProcessStream(ctx context.Context, taskContext, message string, onDelta func(content string)) (*models.Response, error) {
+	fullMessage := prompts.User(taskContext, message)
+	return a.send(ctx, []api.Message{{Role: "user", Content: fullMessage}}, onDelta)
+}
+
+// Converse continues a conversation with the agent, oldest message first,
+// passing the reply to onDelta as it streams in when onDelta is set
+func (a *BaseAgent) Converse(ctx context.Context, messages []models.Message, onDelta func(content string)) (*models.Response, error) {
+	apiMessages := make([]api.Message, len(messages))
+	for i, message := range messages {
+		apiMessages[i] = api.Message{Role: message.Role, Content: message.Content}
 	}
 
-	return a.client.ProcessAgentRequest(a.agentType, a.systemPrompt, fullMessage, a.config)
-}
\ No newline at end of file
+	return a.send(ctx, apiMessages, onDelta)
+}
+
+// send sends messages to the model, offering the agent's tools if it has any
+func (a *BaseAgent) send(ctx context.Context, messages []api.Message, onDelta func(content string)) (*models.Response, error) {
+	systemPrompt := a.GetSystemPrompt()
+	if a.tools != nil {
+		return a.client.ProcessAgentMessagesTools(ctx, a.agentType, systemPrompt, messages, a.config, a.tools, onDelta)
+	}
+	return a.client.ProcessAgentMessagesStream(ctx, a.agentType, systemPrompt, messages, a.config, onDelta)
+}
@@ -47,8 +47,10 @@ When responding:
 4. Consider accessibility and performance
 5. Suggest testing strategies
 
-Always prioritize user experience, code maintainability, and modern web standards.
-Always include proper file paths in your code blocks to ensure correct project structure.`
+{{with .Stack}}This project is built with {{.}}. Use its frameworks rather than introducing new ones.
+
+{{end}}Always prioritize user experience, code maintainability, and modern web standards.
+Always include proper file paths in your code blocks to ensure correct project structure.{{template "project" .}}`
 
 // FrontendAgent represents the frontend development specialist
 type FrontendAgent struct {
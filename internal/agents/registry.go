@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"go-code/internal/api"
+	"go-code/internal/prompts"
 	"go-code/pkg/models"
 )
 
@@ -34,14 +35,44 @@ func (r *Registry) initializeAgents() {
 	frontendConfig := r.getAgentConfig(models.FrontendAgent)
 	backendConfig := r.getAgentConfig(models.BackendAgent)
 	securityConfig := r.getAgentConfig(models.SecurityAgent)
+	reviewerConfig := r.getAgentConfig(models.ReviewerAgent)
 
 	// Create agents
 	r.agents[models.PlannerAgent] = NewPlannerAgent(r.client, plannerConfig)
 	r.agents[models.FrontendAgent] = NewFrontendAgent(r.client, frontendConfig)
 	r.agents[models.BackendAgent] = NewBackendAgent(r.client, backendConfig)
 	r.agents[models.SecurityAgent] = NewSecurityAgent(r.client, securityConfig)
+	r.agents[models.ReviewerAgent] = NewReviewerAgent(r.client, reviewerConfig)
 
-	// TODO: Add remaining agents (DevOps, Reviewer, Manager, Tools, Research)
+	// TODO: Add remaining agents (DevOps, Manager, Tools, Research)
+}
+
+// ToolProvider hands out the tools each agent may use
+type ToolProvider interface {
+	// ToolsFor returns the tools of agentType, or nil if it has none
+	ToolsFor(agentType models.AgentType) api.Toolset
+}
+
+// SetToolProvider gives every agent that can use tools the ones provider
+// allows it
+func (r *Registry) SetToolProvider(provider ToolProvider) {
+	for agentType, agent := range r.agents {
+		if user, ok := agent.(interface{ SetTools(api.Toolset) }); ok {
+			if tools := provider.ToolsFor(agentType); tools != nil {
+				user.SetTools(tools)
+			}
+		}
+	}
+}
+
+// SetFacts gives the prompts of every agent that renders them facts about
+// the project
+func (r *Registry) SetFacts(facts *prompts.Facts) {
+	for _, agent := range r.agents {
+		if user, ok := agent.(interface{ SetFacts(*prompts.Facts) }); ok {
+			user.SetFacts(facts)
+		}
+	}
 }
 
 // getAgentConfig returns the configuration for a specific agent
@@ -64,6 +95,11 @@ func (r *Registry) getAgentConfig(agentType models.AgentType) models.AgentConfig
 	}
 }
 
+// AgentConfig returns the model settings an agent was created with
+func (r *Registry) AgentConfig(agentType models.AgentType) models.AgentConfig {
+	return r.getAgentConfig(agentType)
+}
+
 // GetAgent returns an agent by type
 func (r *Registry) GetAgent(agentType models.AgentType) (models.Agent, error) {
 	agent, exists := r.agents[agentType]
@@ -73,6 +109,11 @@ func (r *Registry) GetAgent(agentType models.AgentType) (models.Agent, error) {
 	return agent, nil
 }
 
+// Register adds an agent to the registry, replacing any agent of its type
+func (r *Registry) Register(agent models.Agent) {
+	r.agents[agent.Type()] = agent
+}
+
 // GetAgentByName returns an agent by name (fuzzy matching)
 func (r *Registry) GetAgentByName(name string) (models.Agent, error) {
 	name = strings.ToLower(strings.TrimSpace(name))
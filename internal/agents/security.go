@@ -53,8 +53,10 @@ When responding:
 4. Include monitoring and detection strategies
 5. Consider compliance requirements
 
-Always prioritize protection, defense, and legitimate security practices.
-Always include proper file paths when providing code or documentation.`
+{{with .Stack}}This project is built with {{.}}. Recommend mitigations and libraries that fit that stack.
+
+{{end}}Always prioritize protection, defense, and legitimate security practices.
+Always include proper file paths when providing code or documentation.{{template "project" .}}`
 
 // SecurityAgent represents the security specialist agent
 type SecurityAgent struct {
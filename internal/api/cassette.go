@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteMode selects whether a Recorder records or replays
+type CassetteMode int
+
+const (
+	// ModeRecord sends requests to the network and saves every exchange
+	ModeRecord CassetteMode = iota
+	// ModeReplay answers requests from the cassette without touching the network
+	ModeReplay
+)
+
+// Interaction is one recorded request/response exchange
+type Interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Body   string `json:"body"`
+	} `json:"request"`
+	Response struct {
+		Status int               `json:"status"`
+		Header map[string]string `json:"header,omitempty"`
+		Body   string            `json:"body"`
+	} `json:"response"`
+}
+
+// Cassette is the file format of recorded exchanges
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records exchanges to a cassette file
+// or replays them from one. Requests are matched by method, path and body,
+// in recorded order, so identical requests replay their responses in turn.
+// Chat requests match on their model and non-system messages only: system
+// prompts carry the project's facts, which change with the directory and
+// machine the cassette is replayed on.
+// Authorization headers are never written to the cassette.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	mode     CassetteMode
+	next     http.RoundTripper
+	cassette Cassette
+	used     []bool
+}
+
+// recordedHeaders are the response headers worth keeping
+var recordedHeaders = []string{
+	"Content-Type",
+	"Retry-After",
+	"X-Ratelimit-Limit-Requests",
+	"X-Ratelimit-Limit-Tokens",
+	"X-Ratelimit-Remaining-Requests",
+	"X-Ratelimit-Remaining-Tokens",
+	"X-Ratelimit-Reset-Requests",
+	"X-Ratelimit-Reset-Tokens",
+}
+
+// NewRecorder creates a recorder for the cassette at path. In replay mode the
+// cassette must exist; in record mode it is (re)written as requests are made.
+// next is the transport used when recording; nil means http.DefaultTransport.
+func NewRecorder(path string, mode CassetteMode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+// replay returns the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := matchKey(body)
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.Method != req.Method ||
+			interaction.Request.Path != req.URL.Path || matchKey([]byte(interaction.Request.Body)) != key {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header)
+		for name, value := range interaction.Response.Header {
+			header.Set(name, value)
+		}
+		return &http.Response{
+			StatusCode:    interaction.Response.Status,
+			Status:        http.StatusText(interaction.Response.Status),
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL.Path, r.path)
+}
+
+// matchKey reduces a request body to what a replay must match. Bodies that
+// aren't chat requests are compared as they are.
+func matchKey(body []byte) string {
+	var req ChatRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Model == "" {
+		return string(body)
+	}
+
+	var messages []Message
+	for _, message := range req.Messages {
+		if message.Role != "system" {
+			messages = append(messages, message)
+		}
+	}
+	key, err := json.Marshal(struct {
+		Model    string    `json:"model"`
+		Messages []Message `json:"messages"`
+	}{req.Model, messages})
+	if err != nil {
+		return string(body)
+	}
+	return string(key)
+}
+
+// record forwards the request and saves the exchange
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var interaction Interaction
+	interaction.Request.Method = req.Method
+	interaction.Request.Path = req.URL.Path
+	interaction.Request.Body = string(body)
+	interaction.Response.Status = resp.StatusCode
+	interaction.Response.Body = string(respBody)
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if interaction.Response.Header == nil {
+				interaction.Response.Header = make(map[string]string)
+			}
+			interaction.Response.Header[name] = value
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// save writes the cassette after every exchange so an interrupted run keeps
+// what it recorded
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
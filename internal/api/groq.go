@@ -2,13 +2,16 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"go-code/internal/cache"
 	"go-code/pkg/models"
 )
 
@@ -21,13 +24,26 @@ type GroqClient struct {
 	APIKey     string
 	HTTPClient *http.Client
 	BaseURL    string
+	Provider   string       // provider name; with BaseURL, keeps cached responses apart
+	Limiter    *RateLimiter // optional; nil sends requests without limiting
+	Cache      *cache.Cache // optional; nil disables response caching
+	CacheOnly  bool         // answer only from Cache, never from the network
 }
 
+// ErrCacheMiss is returned in cache-only mode for a request that isn't cached
+var ErrCacheMiss = errors.New("response not in cache (cache-only mode)")
+
 // NewGroqClient creates a new Groq API client
 func NewGroqClient(apiKey string) *GroqClient {
+	return NewClient(apiKey, strings.TrimSuffix(GroqAPIURL, "/chat/completions"))
+}
+
+// NewClient creates a client for any OpenAI-compatible API rooted at baseURL,
+// e.g. http://localhost:11434/v1
+func NewClient(apiKey, baseURL string) *GroqClient {
 	return &GroqClient{
 		APIKey:  apiKey,
-		BaseURL: GroqAPIURL,
+		BaseURL: strings.TrimSuffix(baseURL, "/") + "/chat/completions",
 		HTTPClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -41,12 +57,17 @@ type ChatRequest struct {
 	Temperature float32   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
+	Tools       []Tool    `json:"tools,omitempty"`
 }
 
-// Message represents a chat message
+// Message represents a chat message. An assistant message may call tools
+// instead of, or as well as, answering; each call is answered by a "tool"
+// message with the call's ID.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 // ChatResponse represents a chat completion response
@@ -57,6 +78,7 @@ type ChatResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+	Cached  bool     `json:"-"`
 }
 
 // Choice represents a completion choice
@@ -87,20 +109,172 @@ func (e GroqError) Error() string {
 		e.ErrorInfo.Message, e.ErrorInfo.Type, e.ErrorInfo.Code)
 }
 
+// maxRateLimitRetries is how often a request rejected with 429 is retried
+// once the limiter's wait is over
+const maxRateLimitRetries = 3
+
 // SendChatRequest sends a chat completion request to Groq API
 func (c *GroqClient) SendChatRequest(req ChatRequest) (*ChatResponse, error) {
+	return c.SendChatRequestContext(context.Background(), req)
+}
+
+// SendChatRequestContext is SendChatRequest with a context that cancels the
+// request, including any wait for the rate limiter
+func (c *GroqClient) SendChatRequestContext(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonData))
+	var cacheKey string
+	if c.Cache != nil && !req.Stream && len(req.Tools) == 0 {
+		cacheKey = cache.Key(c.Provider, c.BaseURL, req.Model, req.Messages, req.Temperature, req.MaxTokens)
+		if data, ok := c.Cache.Get(cacheKey); ok {
+			var cached ChatResponse
+			if err := json.Unmarshal(data, &cached); err == nil {
+				cached.Cached = true
+				return &cached, nil
+			}
+		}
+		if c.CacheOnly {
+			return nil, ErrCacheMiss
+		}
+	}
+
+	estimated := EstimateTokens(req.Messages)
+
+	status, body, err := c.post(ctx, req.Model, estimated, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		var groqErr GroqError
+		if err := json.Unmarshal(body, &groqErr); err != nil {
+			return nil, fmt.Errorf("API request failed with status %d: %s", status, string(body))
+		}
+		return nil, groqErr
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if c.Limiter != nil && chatResp.Usage.TotalTokens > 0 {
+		c.Limiter.Adjust(req.Model, estimated, chatResp.Usage.TotalTokens)
+	}
+
+	if cacheKey != "" {
+		// A failed cache write only costs a future cache hit
+		c.Cache.Put(cacheKey, body)
+	}
+
+	return &chatResp, nil
+}
+
+// post sends a chat completion request body and returns the raw response
+func (c *GroqClient) post(ctx context.Context, model string, estimated int, jsonData []byte) (int, []byte, error) {
+	resp, err := c.send(ctx, model, estimated, jsonData, "application/json")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// send posts a chat completion request body once the rate limiter allows it.
+// A request rejected with 429 is retried after Retry-After, or a backoff
+// doubling from a second, up to maxRateLimitRetries times. The caller closes
+// the response body.
+func (c *GroqClient) send(ctx context.Context, model string, estimated int, jsonData []byte, accept string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx, model, estimated); err != nil {
+				return nil, fmt.Errorf("rate limiter: %w", err)
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", accept)
+		if c.APIKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+		}
+
+		resp, err := c.HTTPClient.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		if c.Limiter == nil {
+			return resp, nil
+		}
+
+		c.Limiter.Update(model, resp.Header)
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			retryAfter := headerDuration(resp.Header, "retry-after")
+			if retryAfter <= 0 {
+				retryAfter = time.Second << attempt
+			}
+			// The rejected request used none of its tokens; give them back
+			// so the retry's Wait reserves them only once
+			c.Limiter.Adjust(model, estimated, 0)
+			c.Limiter.Block(model, retryAfter)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// ModelInfo describes a model returned by the models endpoint
+type ModelInfo struct {
+	ID                  string `json:"id"`
+	Object              string `json:"object"`
+	Created             int64  `json:"created"`
+	OwnedBy             string `json:"owned_by"`
+	Active              bool   `json:"active"`
+	ContextWindow       int    `json:"context_window"`
+	MaxCompletionTokens int    `json:"max_completion_tokens"`
+}
+
+// modelsResponse represents the models endpoint response
+type modelsResponse struct {
+	Object string      `json:"object"`
+	Data   []ModelInfo `json:"data"`
+}
+
+// ModelsURL returns the models endpoint that belongs to the client's chat endpoint
+func (c *GroqClient) ModelsURL() string {
+	return strings.TrimSuffix(c.BaseURL, "/chat/completions") + "/models"
+}
+
+// listModelsTimeout keeps catalog refreshes from stalling commands when offline
+const listModelsTimeout = 10 * time.Second
+
+// ListModels fetches the models available to the API key
+func (c *GroqClient) ListModels() ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), listModelsTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.ModelsURL(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
 
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
@@ -121,69 +295,84 @@ func (c *GroqClient) SendChatRequest(req ChatRequest) (*ChatResponse, error) {
 		return nil, groqErr
 	}
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
+	var modelsResp modelsResponse
+	if err := json.Unmarshal(body, &modelsResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &chatResp, nil
+	return modelsResp.Data, nil
 }
 
-// GetAvailableModels returns a list of available Groq models
-func GetAvailableModels() []string {
-	return []string{
-		"llama-3.1-70b-versatile",
-		"llama-3.1-8b-instant",
-		"llama-3.2-90b-text-preview",
-		"llama-3.2-11b-text-preview",
-		"llama-3.2-3b-preview",
-		"llama-3.2-1b-preview",
-		"mixtral-8x7b-32768",
-		"gemma2-9b-it",
-		"gemma-7b-it",
-		"qwen2.5-coder-32b-instruct",
-		"llama3-groq-70b-8192-tool-use-preview",
-		"llama3-groq-8b-8192-tool-use-preview",
-		"openai/gpt-oss-120b",
-	}
-}
-
-// IsValidModel checks if a model is available
-func IsValidModel(model string) bool {
-	models := GetAvailableModels()
-	for _, m := range models {
-		if strings.EqualFold(m, model) {
-			return true
-		}
+// ProcessAgentRequest processes a request using the specified agent configuration
+func (c *GroqClient) ProcessAgentRequest(agentType models.AgentType, systemPrompt, userMessage string, config models.AgentConfig) (*models.Response, error) {
+	return c.ProcessAgentMessages(context.Background(), agentType, systemPrompt, []Message{{Role: "user", Content: userMessage}}, config)
+}
+
+// ProcessAgentMessages continues a conversation with an agent: the system
+// prompt followed by messages, oldest first
+func (c *GroqClient) ProcessAgentMessages(ctx context.Context, agentType models.AgentType, systemPrompt string, messages []Message, config models.AgentConfig) (*models.Response, error) {
+	req := ChatRequest{
+		Model:       config.Model,
+		Messages:    append([]Message{{Role: "system", Content: systemPrompt}}, messages...),
+		Temperature: config.Temperature,
+		MaxTokens:   config.MaxTokens,
+	}
+
+	resp, err := c.SendChatRequestContext(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send chat request: %w", err)
 	}
-	return false
+
+	return agentResponse(agentType, resp)
 }
 
-// ProcessAgentRequest processes a request using the specified agent configuration
-func (c *GroqClient) ProcessAgentRequest(agentType models.AgentType, systemPrompt, userMessage string, config models.AgentConfig) (*models.Response, error) {
+// ProcessAgentStream is ProcessAgentRequest with the response streamed to
+// onDelta as it is generated. With a response cache the request is not
+// streamed, so cached answers still apply; onDelta then gets the whole content.
+// Without onDelta it is an ordinary request that ctx can cancel.
+func (c *GroqClient) ProcessAgentStream(ctx context.Context, agentType models.AgentType, systemPrompt, userMessage string, config models.AgentConfig, onDelta func(content string)) (*models.Response, error) {
+	return c.ProcessAgentMessagesStream(ctx, agentType, systemPrompt, []Message{{Role: "user", Content: userMessage}}, config, onDelta)
+}
+
+// ProcessAgentMessagesStream is ProcessAgentMessages with the response
+// streamed to onDelta, like ProcessAgentStream
+func (c *GroqClient) ProcessAgentMessagesStream(ctx context.Context, agentType models.AgentType, systemPrompt string, messages []Message, config models.AgentConfig, onDelta func(content string)) (*models.Response, error) {
+	if c.Cache != nil || onDelta == nil {
+		response, err := c.ProcessAgentMessages(ctx, agentType, systemPrompt, messages, config)
+		if err == nil && onDelta != nil {
+			onDelta(response.Content)
+		}
+		return response, err
+	}
+
 	req := ChatRequest{
-		Model: config.Model,
-		Messages: []Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userMessage},
-		},
+		Model:       config.Model,
+		Messages:    append([]Message{{Role: "system", Content: systemPrompt}}, messages...),
 		Temperature: config.Temperature,
 		MaxTokens:   config.MaxTokens,
 	}
 
-	resp, err := c.SendChatRequest(req)
+	resp, err := c.StreamChatRequest(ctx, req, onDelta)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send chat request: %w", err)
 	}
 
+	return agentResponse(agentType, resp)
+}
+
+// agentResponse converts a chat completion into an agent response
+func agentResponse(agentType models.AgentType, resp *ChatResponse) (*models.Response, error) {
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no choices returned from API")
 	}
 
 	return &models.Response{
 		Content:    resp.Choices[0].Message.Content,
-		TokensUsed: resp.Usage.TotalTokens,
-		Model:      resp.Model,
-		Agent:      agentType,
+		TokensUsed:       resp.Usage.TotalTokens,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		Model:            resp.Model,
+		Agent:            agentType,
+		Cached:           resp.Cached,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,398 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+	"go-code/internal/cache"
+	"go-code/internal/llmtest"
+	"go-code/pkg/models"
+)
+
+func chatRequest(content string) api.ChatRequest {
+	return api.ChatRequest{
+		Model: "llama-3.3-70b-versatile",
+		Messages: []api.Message{
+			{Role: "system", Content: "You are a test agent."},
+			{Role: "user", Content: content},
+		},
+		Temperature: 0.2,
+		MaxTokens:   256,
+	}
+}
+
+func TestSendChatRequest(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "hello back", PromptTokens: 12, CompletionTokens: 3})
+
+	resp, err := server.Client().SendChatRequest(chatRequest("hello"))
+	if err != nil {
+		t.Fatalf("SendChatRequest: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "hello back" {
+		t.Errorf("content = %q, want %q", got, "hello back")
+	}
+	if resp.Usage.TotalTokens != 15 {
+		t.Errorf("total tokens = %d, want 15", resp.Usage.TotalTokens)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(requests))
+	}
+	if got := llmtest.UserMessage(requests[0]); got != "hello" {
+		t.Errorf("user message = %q, want %q", got, "hello")
+	}
+}
+
+func TestSendChatRequestError(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Status: http.StatusBadRequest, Error: "model not found"})
+
+	_, err := server.Client().SendChatRequest(chatRequest("hello"))
+	var groqErr api.GroqError
+	if !errors.As(err, &groqErr) {
+		t.Fatalf("error = %v, want a GroqError", err)
+	}
+	if !strings.Contains(groqErr.Error(), "model not found") {
+		t.Errorf("error = %q, want it to mention the API message", groqErr.Error())
+	}
+}
+
+func TestSendChatRequestRetriesRateLimit(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(
+		llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down", Header: map[string]string{"Retry-After": "0.01"}},
+		llmtest.Reply{Content: "done"},
+	)
+
+	client := server.Client()
+	client.Limiter = api.NewRateLimiter(func(string) models.RateLimit {
+		return models.RateLimit{RequestsPerMinute: 1000, TokensPerMinute: 1000000}
+	})
+
+	resp, err := client.SendChatRequest(chatRequest("hello"))
+	if err != nil {
+		t.Fatalf("SendChatRequest: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "done" {
+		t.Errorf("content = %q, want %q", got, "done")
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+func TestSendChatRequestCache(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "first"}, llmtest.Reply{Content: "second"})
+
+	client := server.Client()
+	client.Cache = cache.New(t.TempDir(), time.Hour, 1<<20)
+
+	first, err := client.SendChatRequest(chatRequest("hello"))
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	second, err := client.SendChatRequest(chatRequest("hello"))
+	if err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+
+	if first.Cached || !second.Cached {
+		t.Errorf("cached = %v, %v, want false, true", first.Cached, second.Cached)
+	}
+	if got := second.Choices[0].Message.Content; got != "first" {
+		t.Errorf("cached content = %q, want %q", got, "first")
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+
+	// Another provider or endpoint sharing the cache doesn't get these answers
+	other := server.Client()
+	other.Provider = "ollama"
+	for _, c := range []*api.GroqClient{other, api.NewClient("", "http://localhost:11434/v1")} {
+		c.Cache = client.Cache
+		c.CacheOnly = true
+		if _, err := c.SendChatRequest(chatRequest("hello")); !errors.Is(err, api.ErrCacheMiss) {
+			t.Errorf("provider %q at %s: error = %v, want ErrCacheMiss", c.Provider, c.BaseURL, err)
+		}
+	}
+
+	client.CacheOnly = true
+	if _, err := client.SendChatRequest(chatRequest("something else")); !errors.Is(err, api.ErrCacheMiss) {
+		t.Errorf("cache-only miss error = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestProcessAgentRequest(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		return llmtest.Reply{Content: "system: " + llmtest.SystemPrompt(req), PromptTokens: 40, CompletionTokens: 10}
+	})
+
+	config := models.AgentConfig{Model: "llama-3.1-8b-instant", Temperature: 0.5, MaxTokens: 100}
+	resp, err := server.Client().ProcessAgentRequest(models.BackendAgent, "Be brief.", "hi", config)
+	if err != nil {
+		t.Fatalf("ProcessAgentRequest: %v", err)
+	}
+
+	if resp.Content != "system: Be brief." {
+		t.Errorf("content = %q", resp.Content)
+	}
+	if resp.Agent != models.BackendAgent || resp.Model != "llama-3.1-8b-instant" {
+		t.Errorf("agent, model = %s, %s", resp.Agent, resp.Model)
+	}
+	if resp.PromptTokens != 40 || resp.CompletionTokens != 10 || resp.TokensUsed != 50 {
+		t.Errorf("tokens = %d prompt, %d completion, %d total", resp.PromptTokens, resp.CompletionTokens, resp.TokensUsed)
+	}
+
+	req := server.Requests()[0]
+	if req.Model != config.Model || req.Temperature != config.Temperature || req.MaxTokens != config.MaxTokens {
+		t.Errorf("request = %s %v %d, want the agent config", req.Model, req.Temperature, req.MaxTokens)
+	}
+}
+
+func TestStreamChatRequest(t *testing.T) {
+	server := llmtest.NewServer(t)
+	content := "Here is a longer answer\nthat arrives in several chunks."
+	server.Enqueue(llmtest.Reply{Content: content, PromptTokens: 20, CompletionTokens: 11})
+
+	var deltas []string
+	resp, err := server.Client().StreamChatRequest(context.Background(), chatRequest("stream please"), func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("StreamChatRequest: %v", err)
+	}
+
+	if len(deltas) < 2 {
+		t.Errorf("got %d deltas, want the content in several chunks", len(deltas))
+	}
+	if got := strings.Join(deltas, ""); got != content {
+		t.Errorf("deltas = %q, want %q", got, content)
+	}
+	if got := resp.Choices[0].Message.Content; got != content {
+		t.Errorf("content = %q, want %q", got, content)
+	}
+	if resp.Usage.TotalTokens != 31 {
+		t.Errorf("total tokens = %d, want 31", resp.Usage.TotalTokens)
+	}
+	if !server.Requests()[0].Stream {
+		t.Error("request was not sent with stream: true")
+	}
+}
+
+func TestStreamChatRequestRetriesRateLimit(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(
+		llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down", Header: map[string]string{"Retry-After": "0.01"}},
+		llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down", Header: map[string]string{"Retry-After": "0.01"}},
+		llmtest.Reply{Content: "streamed after all"},
+	)
+
+	client := server.Client()
+	client.Limiter = api.NewRateLimiter(func(string) models.RateLimit {
+		return models.RateLimit{RequestsPerMinute: 1000, TokensPerMinute: 1000000}
+	})
+
+	resp, err := client.StreamChatRequest(context.Background(), chatRequest("stream please"), nil)
+	if err != nil {
+		t.Fatalf("StreamChatRequest: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "streamed after all" {
+		t.Errorf("content = %q", got)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("server saw %d requests, want 3", n)
+	}
+
+	// Without a limiter a 429 is returned as it is
+	server.Enqueue(llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down"})
+	if _, err := server.Client().StreamChatRequest(context.Background(), chatRequest("again"), nil); err == nil || !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("error = %v, want the rate limit error", err)
+	}
+}
+
+func TestRateLimitRetryReservesTokensOnce(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(
+		llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down", Header: map[string]string{"Retry-After": "0.01"}},
+		llmtest.Reply{Status: http.StatusTooManyRequests, Error: "slow down", Header: map[string]string{"Retry-After": "0.01"}},
+		llmtest.Reply{Content: "fits"},
+	)
+
+	// The bucket holds the request's estimate once but not twice, so
+	// reserving again on each retry would stall for most of a minute
+	req := chatRequest(strings.Repeat("x", 400))
+	client := server.Client()
+	client.Limiter = api.NewRateLimiter(func(string) models.RateLimit {
+		return models.RateLimit{RequestsPerMinute: 1000, TokensPerMinute: api.EstimateTokens(req.Messages) * 3 / 2}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := client.StreamChatRequest(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("StreamChatRequest: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "fits" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+// weatherTools offers a single weather tool and records its calls
+type weatherTools struct {
+	calls []string
+}
+
+func (w *weatherTools) Tools() []api.Tool {
+	return []api.Tool{{Type: "function", Function: api.ToolFunction{Name: "weather", Parameters: []byte(`{"type": "object"}`)}}}
+}
+
+func (w *weatherTools) Call(ctx context.Context, name, arguments string) (string, error) {
+	w.calls = append(w.calls, name+" "+arguments)
+	if arguments == `{"city": "Atlantis"}` {
+		return "", errors.New("unknown city")
+	}
+	return "sunny", nil
+}
+
+func TestProcessAgentMessagesTools(t *testing.T) {
+	server := llmtest.NewServer(t)
+	call := func(id, city string) api.ToolCall {
+		return api.ToolCall{ID: id, Type: "function", Function: api.ToolCallFunction{Name: "weather", Arguments: `{"city": "` + city + `"}`}}
+	}
+	server.Enqueue(
+		llmtest.Reply{ToolCalls: []api.ToolCall{call("call_1", "Oslo"), call("call_2", "Atlantis")}, PromptTokens: 10, CompletionTokens: 5},
+		llmtest.Reply{Content: "Sunny in Oslo.", PromptTokens: 30, CompletionTokens: 4},
+	)
+
+	tools := &weatherTools{}
+	var streamed string
+	messages := []api.Message{{Role: "user", Content: "Weather?"}}
+	config := models.AgentConfig{Model: "llama-3.3-70b-versatile"}
+	resp, err := server.Client().ProcessAgentMessagesTools(context.Background(), models.BackendAgent, "Be brief.", messages, config, tools, func(content string) {
+		streamed += content
+	})
+	if err != nil {
+		t.Fatalf("ProcessAgentMessagesTools: %v", err)
+	}
+
+	if resp.Content != "Sunny in Oslo." || streamed != resp.Content {
+		t.Errorf("content = %q, streamed %q", resp.Content, streamed)
+	}
+	if resp.PromptTokens != 40 || resp.CompletionTokens != 9 || resp.TokensUsed != 49 {
+		t.Errorf("tokens = %d prompt, %d completion, %d total, want both rounds", resp.PromptTokens, resp.CompletionTokens, resp.TokensUsed)
+	}
+	if len(tools.calls) != 2 {
+		t.Errorf("calls = %q", tools.calls)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 || len(requests[0].Tools) != 1 || requests[0].Stream {
+		t.Fatalf("requests = %+v", requests)
+	}
+	sent := requests[1].Messages
+	if len(sent) != 5 || len(sent[2].ToolCalls) != 2 {
+		t.Fatalf("second request messages = %+v", sent)
+	}
+	if sent[3].Role != "tool" || sent[3].ToolCallID != "call_1" || sent[3].Content != "sunny" {
+		t.Errorf("first tool result = %+v", sent[3])
+	}
+	if sent[4].ToolCallID != "call_2" || sent[4].Content != "Error: unknown city" {
+		t.Errorf("failed tool result = %+v", sent[4])
+	}
+}
+
+func TestProcessAgentMessagesToolsRoundLimit(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		if len(req.Tools) == 0 {
+			return llmtest.Reply{Content: "Giving up on tools."}
+		}
+		return llmtest.Reply{ToolCalls: []api.ToolCall{{ID: "call", Type: "function", Function: api.ToolCallFunction{Name: "weather", Arguments: "{}"}}}}
+	})
+
+	tools := &weatherTools{}
+	messages := []api.Message{{Role: "user", Content: "Weather?"}}
+	resp, err := server.Client().ProcessAgentMessagesTools(context.Background(), models.BackendAgent, "", messages, models.AgentConfig{Model: "m"}, tools, nil)
+	if err != nil {
+		t.Fatalf("ProcessAgentMessagesTools: %v", err)
+	}
+	if resp.Content != "Giving up on tools." || len(tools.calls) != 8 {
+		t.Errorf("content = %q after %d calls, want an answer after 8", resp.Content, len(tools.calls))
+	}
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.Enqueue(llmtest.Reply{Content: "recorded answer"})
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+
+	recorder, err := api.NewRecorder(path, api.ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder(record): %v", err)
+	}
+	client := server.Client()
+	client.HTTPClient.Transport = recorder
+	if _, err := client.SendChatRequest(chatRequest("hello")); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	if strings.Contains(string(data), "test-key") {
+		t.Error("cassette contains the API key")
+	}
+
+	// Replaying must not reach the server, which has no replies left
+	replayer, err := api.NewRecorder(path, api.ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder(replay): %v", err)
+	}
+	client = api.NewClient("other-key", server.BaseURL())
+	client.HTTPClient.Transport = replayer
+
+	// The system prompt carries facts about the machine it was recorded on,
+	// so a different one still replays
+	req := chatRequest("hello")
+	req.Messages[0].Content = "You are a test agent in /somewhere/else."
+	resp, err := client.SendChatRequest(req)
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "recorded answer" {
+		t.Errorf("replayed content = %q, want %q", got, "recorded answer")
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+
+	if _, err := client.SendChatRequest(chatRequest("hello")); err == nil {
+		t.Error("replaying the same request twice succeeded, want an exhausted cassette error")
+	}
+	if _, err := client.SendChatRequest(chatRequest("not recorded")); err == nil {
+		t.Error("replaying an unrecorded request succeeded")
+	}
+
+	// The model is part of the match
+	replayer, _ = api.NewRecorder(path, api.ModeReplay, nil)
+	client.HTTPClient.Transport = replayer
+	other := chatRequest("hello")
+	other.Model = "llama-3.1-8b-instant"
+	if _, err := client.SendChatRequest(other); err == nil {
+		t.Error("replaying a request for another model succeeded")
+	}
+}
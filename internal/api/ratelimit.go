@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// LimitsFunc returns the rate limits for a model. Zero fields mean no limit.
+type LimitsFunc func(model string) models.RateLimit
+
+// RateLimiter keeps requests under each model's requests-per-minute and
+// tokens-per-minute limits with a pair of token buckets per model. The
+// buckets start from the configured limits and are corrected from the
+// x-ratelimit-* headers of every response. It is safe for concurrent use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  LimitsFunc
+	buckets map[string]*modelBuckets
+	// now and sleep are the limiter's clock; tests replace them
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// modelBuckets holds one model's request and token buckets
+type modelBuckets struct {
+	requests     *bucket
+	tokens       *bucket
+	blockedUntil time.Time
+}
+
+// bucket is a token bucket refilled continuously up to capacity over a minute
+type bucket struct {
+	capacity float64
+	level    float64
+	last     time.Time
+}
+
+// NewRateLimiter creates a limiter that looks up each model's limits once,
+// the first time the model is used
+func NewRateLimiter(limits LimitsFunc) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[string]*modelBuckets),
+		now:     time.Now,
+		sleep:   sleep,
+	}
+}
+
+// Wait blocks until model can take one more request of about tokens tokens,
+// then reserves them. It returns early with the context's error.
+func (l *RateLimiter) Wait(ctx context.Context, model string, tokens int) error {
+	for {
+		delay := l.reserve(model, tokens)
+		if delay <= 0 {
+			return nil
+		}
+		if err := l.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d, returning early with the context's error
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a request and tokens from the model's buckets if both have
+// room, returning zero, or returns how long to wait before trying again
+func (l *RateLimiter) reserve(model string, tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.bucketsFor(model, now)
+
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+
+	need := float64(tokens)
+	if b.tokens != nil && need > b.tokens.capacity {
+		// A request bigger than the whole allowance can only wait for a full bucket
+		need = b.tokens.capacity
+	}
+
+	delay := b.requests.wait(now, 1)
+	if d := b.tokens.wait(now, need); d > delay {
+		delay = d
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	b.requests.take(1)
+	b.tokens.take(need)
+	return 0
+}
+
+// Adjust corrects the model's token bucket by the difference between the
+// estimated and the actual tokens of a completed request
+func (l *RateLimiter) Adjust(model string, estimated, actual int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucketsFor(model, l.now())
+	b.tokens.take(float64(actual - estimated))
+}
+
+// Update corrects the model's buckets from the x-ratelimit-* headers of a
+// response. Groq reports tokens per minute and requests per day; the daily
+// request count only ever lowers the per-minute bucket.
+func (l *RateLimiter) Update(model string, header http.Header) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.bucketsFor(model, now)
+
+	if limit, ok := headerInt(header, "x-ratelimit-limit-tokens"); ok && limit > 0 {
+		if b.tokens == nil {
+			b.tokens = newBucket(limit, now)
+		}
+		b.tokens.capacity = float64(limit)
+	}
+	if remaining, ok := headerInt(header, "x-ratelimit-remaining-tokens"); ok && b.tokens != nil {
+		b.tokens.refill(now)
+		b.tokens.level = minFloat(float64(remaining), b.tokens.capacity)
+		if remaining <= 0 {
+			l.blockFor(b, now, headerDuration(header, "x-ratelimit-reset-tokens"))
+		}
+	}
+
+	if remaining, ok := headerInt(header, "x-ratelimit-remaining-requests"); ok {
+		if b.requests != nil {
+			b.requests.refill(now)
+			b.requests.level = minFloat(float64(remaining), b.requests.level)
+		}
+		if remaining <= 0 {
+			l.blockFor(b, now, headerDuration(header, "x-ratelimit-reset-requests"))
+		}
+	}
+}
+
+// Block stops requests to model for d, e.g. after a 429 with Retry-After
+func (l *RateLimiter) Block(model string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.blockFor(l.bucketsFor(model, now), now, d)
+}
+
+// blockFor extends a model's block to at least now+d
+func (l *RateLimiter) blockFor(b *modelBuckets, now time.Time, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if until := now.Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// bucketsFor returns the model's buckets, creating them from its limits
+func (l *RateLimiter) bucketsFor(model string, now time.Time) *modelBuckets {
+	b, ok := l.buckets[model]
+	if !ok {
+		var limits models.RateLimit
+		if l.limits != nil {
+			limits = l.limits(model)
+		}
+		b = &modelBuckets{
+			requests: newBucket(limits.RequestsPerMinute, now),
+			tokens:   newBucket(limits.TokensPerMinute, now),
+		}
+		l.buckets[model] = b
+	}
+	return b
+}
+
+// newBucket returns a full bucket, or nil for no limit
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{capacity: float64(perMinute), level: float64(perMinute), last: now}
+}
+
+// refill adds what has accrued since the last refill
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.level = minFloat(b.capacity, b.level+b.capacity*elapsed.Minutes())
+	b.last = now
+}
+
+// wait returns how long until the bucket holds n, or zero if it does now.
+// A nil bucket is unlimited.
+func (b *bucket) wait(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.refill(now)
+	if b.level >= n {
+		return 0
+	}
+	missing := n - b.level
+	return time.Duration(missing / b.capacity * float64(time.Minute))
+}
+
+// take removes n from the bucket; a negative n gives tokens back
+func (b *bucket) take(n float64) {
+	if b == nil {
+		return
+	}
+	b.level = minFloat(b.capacity, b.level-n)
+}
+
+// EstimateTokens roughly counts the prompt tokens of a request, at about four
+// characters per token plus a small overhead per message
+func EstimateTokens(messages []Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += len(message.Content)/4 + 4
+	}
+	return tokens
+}
+
+// headerInt parses an integer header
+func headerInt(header http.Header, name string) (int, bool) {
+	value := header.Get(name)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// headerDuration parses a reset header such as "7.66s" or "2m59.56s", or a
+// Retry-After header in whole seconds
+func headerDuration(header http.Header, name string) time.Duration {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return 0
+}
+
+// minFloat returns the smaller of two floats
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// fakeClock is a clock whose sleeps only move its time forward
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	slept  time.Duration
+	sleeps int
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+	c.sleeps++
+	return nil
+}
+
+// testLimiter returns a limiter on a fake clock with the same limits for
+// every model
+func testLimiter(limit models.RateLimit) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(func(string) models.RateLimit { return limit })
+	limiter.now = clock.Now
+	limiter.sleep = clock.Sleep
+	return limiter, clock
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter, clock := testLimiter(models.RateLimit{RequestsPerMinute: 2, TokensPerMinute: 1000})
+
+	// Requests per minute
+	for i := 0; i < 2; i++ {
+		if delay := limiter.reserve("requests", 10); delay != 0 {
+			t.Fatalf("request %d delay = %v, want 0", i+1, delay)
+		}
+	}
+	if delay := limiter.reserve("requests", 10); delay != 30*time.Second {
+		t.Errorf("third request delay = %v, want 30s for one request to refill", delay)
+	}
+	clock.Advance(30 * time.Second)
+	if delay := limiter.reserve("requests", 10); delay != 0 {
+		t.Errorf("delay after refilling = %v, want 0", delay)
+	}
+
+	// Tokens per minute, per model
+	if delay := limiter.reserve("tokens", 900); delay != 0 {
+		t.Fatalf("delay = %v, want 0", delay)
+	}
+	if delay := limiter.reserve("tokens", 200); delay != 6*time.Second {
+		t.Errorf("delay = %v, want 6s for the missing 100 tokens", delay)
+	}
+	// A request bigger than the allowance waits for a full bucket
+	if delay := limiter.reserve("huge", 5000); delay != 0 {
+		t.Errorf("oversized request on a full bucket delay = %v, want 0", delay)
+	}
+	if delay := limiter.reserve("huge", 5000); delay != time.Minute {
+		t.Errorf("oversized request delay = %v, want a minute", delay)
+	}
+
+	// Models without limits are never delayed
+	unlimited := NewRateLimiter(nil)
+	for i := 0; i < 100; i++ {
+		if delay := unlimited.reserve("any", 1e6); delay != 0 {
+			t.Fatalf("unlimited delay = %v", delay)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter, clock := testLimiter(models.RateLimit{RequestsPerMinute: 1})
+
+	if err := limiter.Wait(context.Background(), "m", 0); err != nil || clock.sleeps != 0 {
+		t.Fatalf("first Wait = %v after %d sleeps, want no wait", err, clock.sleeps)
+	}
+	if err := limiter.Wait(context.Background(), "m", 0); err != nil {
+		t.Fatalf("second Wait: %v", err)
+	}
+	if clock.slept != time.Minute {
+		t.Errorf("slept %v, want a minute", clock.slept)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "m", 0); err != context.Canceled {
+		t.Errorf("Wait with a cancelled context = %v", err)
+	}
+	// The cancelled wait reserved nothing
+	clock.Advance(time.Minute)
+	if delay := limiter.reserve("m", 0); delay != 0 {
+		t.Errorf("delay after a cancelled wait = %v, want 0", delay)
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	limiter, clock := testLimiter(models.RateLimit{})
+
+	// Headers set up buckets the configuration didn't know about
+	limiter.Update("m", http.Header{
+		"X-Ratelimit-Limit-Tokens":     {"600"},
+		"X-Ratelimit-Remaining-Tokens": {"0"},
+		"X-Ratelimit-Reset-Tokens":     {"7.5s"},
+	})
+	if delay := limiter.reserve("m", 50); delay != 7500*time.Millisecond {
+		t.Errorf("delay = %v, want the 7.5s token reset", delay)
+	}
+	clock.Advance(7500 * time.Millisecond)
+	if delay := limiter.reserve("m", 50); delay != 0 {
+		t.Errorf("delay after the reset = %v, want 0 with 75 tokens refilled", delay)
+	}
+
+	// Remaining tokens lower the level, never raise it above the limit
+	limiter.Update("m", http.Header{"X-Ratelimit-Remaining-Tokens": {"100000"}})
+	if level := limiter.buckets["m"].tokens.level; level != 600 {
+		t.Errorf("token level = %v, want the 600 limit", level)
+	}
+
+	// Exhausted daily requests block until their reset
+	limiter.Update("m", http.Header{
+		"X-Ratelimit-Remaining-Requests": {"0"},
+		"X-Ratelimit-Reset-Requests":     {"2m59.56s"},
+	})
+	if delay := limiter.reserve("m", 1); delay != 2*time.Minute+59560*time.Millisecond {
+		t.Errorf("delay = %v, want the request reset", delay)
+	}
+
+	// Unparseable headers are ignored
+	limiter.Update("other", http.Header{"X-Ratelimit-Limit-Tokens": {"lots"}, "X-Ratelimit-Remaining-Requests": {"?"}})
+	if delay := limiter.reserve("other", 1e6); delay != 0 {
+		t.Errorf("delay = %v, want no limit", delay)
+	}
+}
+
+func TestRateLimiterBlockAndAdjust(t *testing.T) {
+	limiter, clock := testLimiter(models.RateLimit{TokensPerMinute: 1000})
+
+	// After a 429
+	limiter.Block("m", 5*time.Second)
+	limiter.Block("m", time.Second) // A shorter block doesn't shorten it
+	if delay := limiter.reserve("m", 1); delay != 5*time.Second {
+		t.Errorf("blocked delay = %v, want 5s", delay)
+	}
+	clock.Advance(5 * time.Second)
+
+	// The request was estimated at 100 tokens but used 900
+	if delay := limiter.reserve("m", 100); delay != 0 {
+		t.Fatalf("delay = %v, want 0", delay)
+	}
+	limiter.Adjust("m", 100, 900)
+	if delay := limiter.reserve("m", 200); delay != 6*time.Second {
+		t.Errorf("delay = %v, want 6s after the larger actual usage", delay)
+	}
+
+	// Overestimates are given back
+	limiter.Adjust("m", 900, 100)
+	if delay := limiter.reserve("m", 800); delay != 0 {
+		t.Errorf("delay = %v, want 0 after tokens were given back", delay)
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	limiter, _ := testLimiter(models.RateLimit{RequestsPerMinute: 50, TokensPerMinute: 1000000})
+
+	var granted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if limiter.reserve("m", 10) == 0 {
+				granted.Add(1)
+			}
+			switch i % 3 {
+			case 0:
+				limiter.Update("m", http.Header{"X-Ratelimit-Remaining-Tokens": {"999000"}})
+			case 1:
+				limiter.Adjust("m", 10, 20)
+			case 2:
+				limiter.Block("other", time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The clock stood still, so exactly the per-minute allowance got through
+	if n := granted.Load(); n != 50 {
+		t.Errorf("%d of 100 concurrent requests were granted, want 50", n)
+	}
+
+	// Concurrent waits all get their turn as the clock moves on
+	waiter, clock := testLimiter(models.RateLimit{RequestsPerMinute: 10})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := waiter.Wait(context.Background(), "m", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if clock.Now().Sub(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) < time.Minute {
+		t.Error("20 requests at 10 per minute took less than a minute")
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamChunk is one server-sent event of a streamed chat completion
+type streamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	// OpenAI reports usage in the last chunk, Groq under x_groq
+	Usage *Usage `json:"usage"`
+	XGroq *struct {
+		Usage *Usage `json:"usage"`
+	} `json:"x_groq"`
+}
+
+// StreamChatRequest sends a streaming chat completion request, calling
+// onDelta with every piece of content as it arrives, and returns the
+// assembled response
+func (c *GroqClient) StreamChatRequest(ctx context.Context, req ChatRequest, onDelta func(content string)) (*ChatResponse, error) {
+	req.Stream = true
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	estimated := EstimateTokens(req.Messages)
+	resp, err := c.send(ctx, req.Model, estimated, jsonData, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var groqErr GroqError
+		if err := json.Unmarshal(body, &groqErr); err != nil {
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, groqErr
+	}
+
+	result := &ChatResponse{Model: req.Model}
+	var content strings.Builder
+	finishReason := ""
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+			result.Usage = *chunk.XGroq.Usage
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	result.Choices = []Choice{{
+		Message:      Message{Role: "assistant", Content: content.String()},
+		FinishReason: finishReason,
+	}}
+
+	if c.Limiter != nil && result.Usage.TotalTokens > 0 {
+		c.Limiter.Adjust(req.Model, estimated, result.Usage.TotalTokens)
+	}
+
+	return result, nil
+}
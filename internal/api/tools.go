@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-code/pkg/models"
+)
+
+// maxToolRounds caps how many times in a row the model may call tools before
+// it has to answer
+const maxToolRounds = 8
+
+// Tool is a function offered to the model
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a tool: its name and a JSON schema of its arguments
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is the model's request to run a tool, with its arguments as a
+// JSON string
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the tool called and carries its arguments
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Toolset is the set of tools an agent may use during a request
+type Toolset interface {
+	// Tools lists the tools offered to the model
+	Tools() []Tool
+	// Call runs a tool and returns the text handed back to the model. An
+	// error is shown to the model too, so it can try something else.
+	Call(ctx context.Context, name, arguments string) (string, error)
+}
+
+// ProcessAgentMessagesTools is ProcessAgentMessagesStream with tools the
+// model may call before it answers. Calls are run one after the other and
+// their results sent back until the model answers or maxToolRounds is
+// reached; the usage of every round is added up. Requests with tools aren't
+// streamed, so onDelta gets the whole answer.
+func (c *GroqClient) ProcessAgentMessagesTools(ctx context.Context, agentType models.AgentType, systemPrompt string, messages []Message, config models.AgentConfig, tools Toolset, onDelta func(content string)) (*models.Response, error) {
+	offered := tools.Tools()
+	if len(offered) == 0 {
+		return c.ProcessAgentMessagesStream(ctx, agentType, systemPrompt, messages, config, onDelta)
+	}
+
+	req := ChatRequest{
+		Model:       config.Model,
+		Messages:    append([]Message{{Role: "system", Content: systemPrompt}}, messages...),
+		Temperature: config.Temperature,
+		MaxTokens:   config.MaxTokens,
+	}
+
+	var usage Usage
+	for round := 0; ; round++ {
+		// The last round offers no tools, so the model has to answer
+		req.Tools = nil
+		if round < maxToolRounds {
+			req.Tools = offered
+		}
+
+		resp, err := c.SendChatRequestContext(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send chat request: %w", err)
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+
+		if len(resp.Choices) == 0 || len(resp.Choices[0].Message.ToolCalls) == 0 {
+			resp.Usage = usage
+			response, err := agentResponse(agentType, resp)
+			if err == nil && onDelta != nil {
+				onDelta(response.Content)
+			}
+			return response, err
+		}
+
+		reply := resp.Choices[0].Message
+		req.Messages = append(req.Messages, Message{Role: "assistant", Content: reply.Content, ToolCalls: reply.ToolCalls})
+		for _, call := range reply.ToolCalls {
+			result, err := tools.Call(ctx, call.Function.Name, call.Function.Arguments)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				result = "Error: " + err.Error()
+			}
+			req.Messages = append(req.Messages, Message{Role: "tool", Content: result, ToolCallID: call.ID})
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entryExt is the extension of cache entry files
+const entryExt = ".json"
+
+// Cache is an on-disk key/value store with a time-to-live and a size limit.
+// Entries are files named by key; the least recently written are evicted
+// first once the cache grows past its limit. It is safe for concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+}
+
+// Stats describes the cache's contents
+type Stats struct {
+	Dir      string
+	Entries  int
+	Expired  int
+	Bytes    int64
+	MaxBytes int64
+	TTL      time.Duration
+	Oldest   time.Time
+	Newest   time.Time
+}
+
+// DefaultDir returns where cached responses are stored
+func DefaultDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-code", "cache")
+}
+
+// New creates a cache in dir. A zero ttl never expires entries and a zero
+// maxBytes never evicts them.
+func New(dir string, ttl time.Duration, maxBytes int64) *Cache {
+	return &Cache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+// Key hashes the JSON form of parts into a cache key
+func Key(parts ...interface{}) string {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, part := range parts {
+		encoder.Encode(part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the data stored under key, if it exists and hasn't expired
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.expired(info.ModTime()) {
+		os.Remove(path)
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data under key and evicts old entries if the cache is too big
+func (c *Cache) Put(key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return c.prune()
+}
+
+// Stats reports the number and size of entries
+func (c *Cache) Stats() (Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{Dir: c.dir, MaxBytes: c.maxBytes, TTL: c.ttl}
+	entries, err := c.entries()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, entry := range entries {
+		stats.Entries++
+		stats.Bytes += entry.size
+		if c.expired(entry.modTime) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || entry.modTime.Before(stats.Oldest) {
+			stats.Oldest = entry.modTime
+		}
+		if entry.modTime.After(stats.Newest) {
+			stats.Newest = entry.modTime
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every entry and returns how many were removed
+func (c *Cache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if err := os.Remove(entry.path); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// cacheEntry is a file in the cache directory
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists the cache entry files
+func (c *Cache) entries() ([]cacheEntry, error) {
+	files, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []cacheEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), entryExt) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntry{
+			path:    filepath.Join(c.dir, file.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// prune removes expired entries, then the oldest ones until the cache fits
+// in maxBytes
+func (c *Cache) prune() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	var total int64
+	var kept []cacheEntry
+	for _, entry := range entries {
+		if c.expired(entry.modTime) {
+			os.Remove(entry.path)
+			continue
+		}
+		total += entry.size
+		kept = append(kept, entry)
+	}
+
+	for _, entry := range kept {
+		if c.maxBytes <= 0 || total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(entry.path); err == nil {
+			total -= entry.size
+		}
+	}
+	return nil
+}
+
+// expired reports whether an entry written at modTime is past its TTL
+func (c *Cache) expired(modTime time.Time) bool {
+	return c.ttl > 0 && time.Since(modTime) > c.ttl
+}
+
+// path returns the file for key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+entryExt)
+}
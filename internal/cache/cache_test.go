@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// age backdates the entry stored under key
+func age(t *testing.T, c *Cache, key string, d time.Duration) {
+	t.Helper()
+	old := time.Now().Add(-d)
+	if err := os.Chtimes(c.path(key), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	base := Key("groq", "https://api.groq.com/openai/v1", "llama", 0.2)
+
+	if Key("groq", "https://api.groq.com/openai/v1", "llama", 0.2) != base {
+		t.Error("equal parts give different keys")
+	}
+	for _, other := range []string{
+		Key("ollama", "https://api.groq.com/openai/v1", "llama", 0.2),
+		Key("groq", "http://localhost:11434/v1", "llama", 0.2),
+		Key("groq", "https://api.groq.com/openai/v1", "llama", 0.3),
+	} {
+		if other == base {
+			t.Error("different parts give the same key")
+		}
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	c := New(t.TempDir(), time.Hour, 0)
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("Get of a missing key succeeded")
+	}
+	if err := c.Put("a", []byte("alpha")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get("a"); !ok || string(data) != "alpha" {
+		t.Errorf("Get = %q, %v, want alpha", data, ok)
+	}
+
+	// Put replaces an entry
+	if err := c.Put("a", []byte("beta")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := c.Get("a"); string(data) != "beta" {
+		t.Errorf("Get after replace = %q, want beta", data)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		age  time.Duration
+		hit  bool
+	}{
+		{"fresh", time.Hour, time.Minute, true},
+		{"expired", time.Hour, 2 * time.Hour, false},
+		{"zero ttl never expires", 0, 24 * 365 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(t.TempDir(), tt.ttl, 0)
+			if err := c.Put("k", []byte("v")); err != nil {
+				t.Fatal(err)
+			}
+			age(t, c, "k", tt.age)
+
+			stats, err := c.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expired := stats.Expired == 1; expired == tt.hit {
+				t.Errorf("stats.Expired = %d", stats.Expired)
+			}
+
+			if _, ok := c.Get("k"); ok != tt.hit {
+				t.Errorf("Get hit = %v, want %v", ok, tt.hit)
+			}
+			// An expired entry is removed when read
+			if _, err := os.Stat(c.path("k")); os.IsNotExist(err) == tt.hit {
+				t.Errorf("entry file exists = %v after Get", !os.IsNotExist(err))
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := New(t.TempDir(), time.Hour, 10)
+
+	for i, key := range []string{"old", "mid", "new"} {
+		if err := c.Put(key, []byte("12345")); err != nil {
+			t.Fatal(err)
+		}
+		age(t, c, key, time.Duration(3-i)*time.Minute)
+	}
+
+	// Three 5-byte entries don't fit in 10 bytes; the oldest goes first
+	if _, ok := c.Get("old"); ok {
+		t.Error("oldest entry survived pruning")
+	}
+	for _, key := range []string{"mid", "new"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("entry %s was pruned", key)
+		}
+	}
+
+	// Expired entries go whatever the size
+	age(t, c, "mid", 2*time.Hour)
+	if err := c.Put("newest", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.path("mid")); !os.IsNotExist(err) {
+		t.Error("expired entry survived pruning")
+	}
+}
+
+func TestStatsAndClear(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := New(dir, time.Hour, 1<<20)
+
+	// A cache directory that doesn't exist yet is empty
+	stats, err := c.Stats()
+	if err != nil || stats.Entries != 0 {
+		t.Fatalf("Stats of a missing dir = %+v, %v", stats, err)
+	}
+	if n, err := c.Clear(); err != nil || n != 0 {
+		t.Fatalf("Clear of a missing dir = %d, %v", n, err)
+	}
+
+	for key, data := range map[string]string{"a": "12", "b": "345"} {
+		if err := c.Put(key, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age(t, c, "a", 2*time.Hour)
+
+	stats, err = c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Dir != dir || stats.Entries != 2 || stats.Expired != 1 || stats.Bytes != 5 || stats.MaxBytes != 1<<20 || stats.TTL != time.Hour {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if !stats.Oldest.Before(stats.Newest) {
+		t.Errorf("oldest %v is not before newest %v", stats.Oldest, stats.Newest)
+	}
+
+	n, err := c.Clear()
+	if err != nil || n != 2 {
+		t.Errorf("Clear = %d, %v, want 2", n, err)
+	}
+	if stats, _ := c.Stats(); stats.Entries != 0 {
+		t.Errorf("%d entries left after Clear", stats.Entries)
+	}
+}
+
+func TestCorruptEntries(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, time.Hour, 0)
+
+	// Entries are opaque: a truncated one is returned as it is and left to
+	// the caller to reject
+	if err := os.WriteFile(c.path("truncated"), []byte(`{"choices": [`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get("truncated"); !ok || string(data) != `{"choices": [` {
+		t.Errorf("Get = %q, %v", data, ok)
+	}
+
+	// An entry that can't be read is a miss
+	if err := os.Mkdir(c.path("dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("dir"); ok {
+		t.Error("Get of a directory entry succeeded")
+	}
+
+	// Leftover temp files and other files aren't entries
+	for _, name := range []string{".tmp-123", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 1 {
+		t.Errorf("stats.Entries = %d, want 1", stats.Entries)
+	}
+	if n, err := c.Clear(); err != nil || n != 1 {
+		t.Errorf("Clear = %d, %v, want 1", n, err)
+	}
+	for _, name := range []string{".tmp-123", "notes.txt", "dir.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Clear removed %s", name)
+		}
+	}
+}
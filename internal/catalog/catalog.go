@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-code/internal/api"
+)
+
+// DefaultTTL is how long a fetched catalog is used before it is refreshed
+const DefaultTTL = 24 * time.Hour
+
+// Catalog sources
+const (
+	SourceEmbedded = "embedded"
+	SourceCache    = "cache"
+	SourceAPI      = "api"
+)
+
+//go:embed models.json
+var embeddedModels []byte
+
+// Model describes a model and what it can do
+type Model struct {
+	ID                  string `json:"id"`
+	OwnedBy             string `json:"owned_by"`
+	ContextWindow       int    `json:"context_window"`
+	MaxCompletionTokens int    `json:"max_completion_tokens"`
+	Tools               bool   `json:"tools"`
+	JSONMode            bool   `json:"json_mode"`
+	// Prices are in US dollars per million tokens; zero means unknown
+	InputPrice  float64 `json:"input_price"`
+	OutputPrice float64 `json:"output_price"`
+	// Rate limits of the entry-level plan; response headers correct them at run time
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+}
+
+// Cost returns the price in US dollars of a request to the model
+func (m Model) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*m.InputPrice + float64(completionTokens)*m.OutputPrice) / 1e6
+}
+
+// Catalog is the single source of truth for which models exist
+type Catalog struct {
+	Models    []Model   `json:"models"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Source    string    `json:"-"`
+}
+
+// DefaultCachePath returns where the fetched catalog is cached
+func DefaultCachePath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-code", "models.json")
+}
+
+// Embedded returns the catalog compiled into the binary
+func Embedded() *Catalog {
+	var models []Model
+	if err := json.Unmarshal(embeddedModels, &models); err != nil {
+		panic(fmt.Sprintf("catalog: invalid embedded models.json: %v", err))
+	}
+	return &Catalog{Models: models, Source: SourceEmbedded}
+}
+
+// Load returns the cached catalog, or the embedded one when there is no
+// usable cache. It never touches the network.
+func Load(cachePath string) *Catalog {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return Embedded()
+	}
+
+	var cached Catalog
+	if err := json.Unmarshal(data, &cached); err != nil || len(cached.Models) == 0 {
+		return Embedded()
+	}
+	cached.Source = SourceCache
+	cached.fillKnown()
+	return &cached
+}
+
+// fillKnown copies prices and rate limits from the embedded list into models
+// that have none, e.g. in a cache written by an older release
+func (c *Catalog) fillKnown() {
+	known := Embedded()
+	for i, model := range c.Models {
+		embedded, ok := known.Lookup(model.ID)
+		if !ok {
+			continue
+		}
+		if model.InputPrice == 0 && model.OutputPrice == 0 {
+			c.Models[i].InputPrice = embedded.InputPrice
+			c.Models[i].OutputPrice = embedded.OutputPrice
+		}
+		if model.RequestsPerMinute == 0 && model.TokensPerMinute == 0 {
+			c.Models[i].RequestsPerMinute = embedded.RequestsPerMinute
+			c.Models[i].TokensPerMinute = embedded.TokensPerMinute
+		}
+	}
+}
+
+// LoadFresh returns the cached catalog, refreshing it from the API first when
+// it is older than ttl. Any fetch failure falls back to what is available offline.
+func LoadFresh(client *api.GroqClient, cachePath string, ttl time.Duration) *Catalog {
+	current := Load(cachePath)
+	if client == nil || client.APIKey == "" {
+		return current
+	}
+	if current.Source == SourceCache && time.Since(current.FetchedAt) < ttl {
+		return current
+	}
+
+	refreshed, err := Refresh(client, cachePath)
+	if err != nil {
+		return current
+	}
+	return refreshed
+}
+
+// Refresh fetches the model list from the API, fills in capabilities known
+// from the embedded list and writes the result to the cache
+func Refresh(client *api.GroqClient, cachePath string) (*Catalog, error) {
+	infos, err := client.ListModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+
+	known := Embedded()
+	catalog := &Catalog{FetchedAt: time.Now(), Source: SourceAPI}
+	for _, info := range infos {
+		model, ok := known.Lookup(info.ID)
+		if !ok {
+			model = Model{ID: info.ID}
+		}
+		if info.OwnedBy != "" {
+			model.OwnedBy = info.OwnedBy
+		}
+		if info.ContextWindow > 0 {
+			model.ContextWindow = info.ContextWindow
+		}
+		if info.MaxCompletionTokens > 0 {
+			model.MaxCompletionTokens = info.MaxCompletionTokens
+		}
+		catalog.Models = append(catalog.Models, model)
+	}
+
+	sort.Slice(catalog.Models, func(i, j int) bool {
+		return catalog.Models[i].ID < catalog.Models[j].ID
+	})
+
+	if err := catalog.save(cachePath); err != nil {
+		return catalog, err
+	}
+	return catalog, nil
+}
+
+// Lookup finds a model by ID, ignoring case
+func (c *Catalog) Lookup(id string) (Model, bool) {
+	for _, model := range c.Models {
+		if strings.EqualFold(model.ID, id) {
+			return model, true
+		}
+	}
+	return Model{}, false
+}
+
+// IDs returns every model ID in the catalog
+func (c *Catalog) IDs() []string {
+	ids := make([]string, 0, len(c.Models))
+	for _, model := range c.Models {
+		ids = append(ids, model.ID)
+	}
+	return ids
+}
+
+// save writes the catalog to the cache file
+func (c *Catalog) save(cachePath string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal catalog: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write catalog cache: %w", err)
+	}
+	return nil
+}
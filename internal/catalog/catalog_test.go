@@ -0,0 +1,203 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+)
+
+// modelsServer serves a models endpoint listing ids, or failing with status
+// when it isn't 200, and counts its requests
+func modelsServer(t *testing.T, status int, ids ...string) (*api.GroqClient, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		var data []api.ModelInfo
+		for _, id := range ids {
+			data = append(data, api.ModelInfo{ID: id, OwnedBy: "Test", ContextWindow: 4096})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	}))
+	t.Cleanup(server.Close)
+	return api.NewClient("test-key", server.URL), &hits
+}
+
+// writeCache writes a cached catalog of models fetched at fetchedAt
+func writeCache(t *testing.T, path string, fetchedAt time.Time, models ...Model) {
+	t.Helper()
+	if err := (&Catalog{Models: models, FetchedAt: fetchedAt}).save(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	embedded := len(Embedded().Models)
+
+	tests := []struct {
+		name   string
+		data   string
+		source string
+		models int
+	}{
+		{"missing", "", SourceEmbedded, embedded},
+		{"corrupt", `{"models": [`, SourceEmbedded, embedded},
+		{"empty", `{"models": []}`, SourceEmbedded, embedded},
+		{"cached", `{"models": [{"id": "custom-model"}], "fetched_at": "2024-06-01T00:00:00Z"}`, SourceCache, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := Load(path)
+			if c.Source != tt.source || len(c.Models) != tt.models {
+				t.Errorf("source = %s with %d models, want %s with %d", c.Source, len(c.Models), tt.source, tt.models)
+			}
+		})
+	}
+}
+
+func TestFillKnown(t *testing.T) {
+	c := &Catalog{Models: []Model{
+		{ID: "llama-3.3-70b-versatile"},
+		{ID: "qwen/qwen3-32b", InputPrice: 9, RequestsPerMinute: 1},
+		{ID: "custom-model"},
+	}}
+	c.fillKnown()
+
+	tests := []struct {
+		model             Model
+		inputPrice        float64
+		outputPrice       float64
+		requestsPerMinute int
+		tokensPerMinute   int
+	}{
+		// Missing prices and limits come from the embedded list
+		{c.Models[0], 0.59, 0.79, 30, 12000},
+		// Prices and limits the cache has are kept as a pair
+		{c.Models[1], 9, 0, 1, 0},
+		// Unknown models stay unpriced
+		{c.Models[2], 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		m := tt.model
+		if m.InputPrice != tt.inputPrice || m.OutputPrice != tt.outputPrice || m.RequestsPerMinute != tt.requestsPerMinute || m.TokensPerMinute != tt.tokensPerMinute {
+			t.Errorf("%s = %+v", m.ID, m)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c := Embedded()
+
+	tests := []struct {
+		id    string
+		found string
+	}{
+		{"llama-3.3-70b-versatile", "llama-3.3-70b-versatile"},
+		{"MoonshotAI/Kimi-K2-Instruct", "moonshotai/kimi-k2-instruct"},
+		{"llama-3.3-70b", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		model, ok := c.Lookup(tt.id)
+		if ok != (tt.found != "") || model.ID != tt.found {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", tt.id, model.ID, ok, tt.found)
+		}
+	}
+
+	if ids := c.IDs(); len(ids) != len(c.Models) || ids[0] != c.Models[0].ID {
+		t.Errorf("IDs = %v", ids)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "models.json")
+	client, _ := modelsServer(t, http.StatusOK, "zeta-model", "llama-3.1-8b-instant")
+
+	c, err := Refresh(client, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Source != SourceAPI || len(c.Models) != 2 || c.FetchedAt.IsZero() {
+		t.Fatalf("unexpected catalog %+v", c)
+	}
+
+	// Sorted, with capabilities from the embedded list and sizes from the API
+	known, unknown := c.Models[0], c.Models[1]
+	if known.ID != "llama-3.1-8b-instant" || !known.Tools || known.InputPrice != 0.05 || known.ContextWindow != 4096 || known.OwnedBy != "Test" {
+		t.Errorf("known model = %+v", known)
+	}
+	if unknown.ID != "zeta-model" || unknown.Tools || unknown.InputPrice != 0 {
+		t.Errorf("unknown model = %+v", unknown)
+	}
+
+	cached := Load(path)
+	if cached.Source != SourceCache || len(cached.Models) != 2 || !cached.FetchedAt.Equal(c.FetchedAt) {
+		t.Errorf("cache = %+v", cached)
+	}
+
+	failing, _ := modelsServer(t, http.StatusServiceUnavailable)
+	if _, err := Refresh(failing, path); err == nil {
+		t.Error("Refresh from a failing endpoint succeeded")
+	}
+}
+
+func TestLoadFresh(t *testing.T) {
+	cachedModel := Model{ID: "cached-model"}
+
+	tests := []struct {
+		name    string
+		fetched time.Time // zero means no cache
+		status  int
+		noKey   bool
+		source  string
+		hits    int32
+	}{
+		{"fresh cache", time.Now().Add(-time.Hour), http.StatusOK, false, SourceCache, 0},
+		{"expired cache", time.Now().Add(-48 * time.Hour), http.StatusOK, false, SourceAPI, 1},
+		{"no cache", time.Time{}, http.StatusOK, false, SourceAPI, 1},
+		{"expired cache without a key", time.Now().Add(-48 * time.Hour), http.StatusOK, true, SourceCache, 0},
+		{"expired cache, API down", time.Now().Add(-48 * time.Hour), http.StatusServiceUnavailable, false, SourceCache, 1},
+		{"no cache, API down", time.Time{}, http.StatusServiceUnavailable, false, SourceEmbedded, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "models.json")
+			if !tt.fetched.IsZero() {
+				writeCache(t, path, tt.fetched, cachedModel)
+			}
+			client, hits := modelsServer(t, tt.status, "api-model")
+			if tt.noKey {
+				client.APIKey = ""
+			}
+
+			c := LoadFresh(client, path, DefaultTTL)
+			if c.Source != tt.source {
+				t.Errorf("source = %s, want %s", c.Source, tt.source)
+			}
+			if got := atomic.LoadInt32(hits); got != tt.hits {
+				t.Errorf("API was called %d times, want %d", got, tt.hits)
+			}
+		})
+	}
+}
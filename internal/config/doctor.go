@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// Problem is an issue found in the configuration by Doctor
+type Problem struct {
+	Key     string
+	Message string
+	Source  string
+	fix     func(config *models.Config)
+}
+
+// Fixable reports whether Fix can repair the problem. Only settings that come
+// from the user file (or the defaults) can be fixed; others must be changed
+// where they are set.
+func (p Problem) Fixable() bool {
+	return p.fix != nil
+}
+
+// Doctor checks the effective configuration for problems
+func (m *Manager) Doctor() []Problem {
+	var problems []Problem
+	report := func(key, message string, fix func(config *models.Config)) {
+		source := m.Source(key)
+		if source != SourceFile && source != SourceDefault {
+			fix = nil
+		}
+		problems = append(problems, Problem{Key: key, Message: message, Source: source, fix: fix})
+	}
+
+	config := m.config
+	defaults := models.DefaultConfig()
+
+	if m.fileConfig.Version > models.ConfigVersion {
+		problems = append(problems, Problem{
+			Key:     "version",
+			Message: fmt.Sprintf("config file is version %d but this go-code only knows version %d; upgrade go-code", m.fileConfig.Version, models.ConfigVersion),
+			Source:  SourceFile,
+		})
+	}
+
+	for _, key := range m.unknownKeys {
+		problems = append(problems, Problem{
+			Key:     key,
+			Message: "unknown setting, ignored",
+			Source:  SourceFile,
+			fix:     func(*models.Config) {}, // saving drops it
+		})
+	}
+
+	if config.GroqAPIKey == "" {
+		problems = append(problems, Problem{
+			Key:     "groq_api_key",
+			Message: "no API key; use 'go-code config set-key <key>', key_command or GROQ_API_KEY",
+			Source:  m.Source("groq_api_key"),
+		})
+	}
+
+	if err := m.ValidateModel(config.DefaultModel); err != nil {
+		report("default_model", err.Error(), func(c *models.Config) {
+			c.DefaultModel = defaults.DefaultModel
+		})
+	}
+
+	agentTypes := make([]string, 0, len(config.AgentPreferences))
+	for agentType := range config.AgentPreferences {
+		agentTypes = append(agentTypes, string(agentType))
+	}
+	sort.Strings(agentTypes)
+
+	for _, name := range agentTypes {
+		agentType := models.AgentType(name)
+		agentConfig := config.AgentPreferences[agentType]
+		prefix := "agent_preferences." + name
+		fallback := defaults.AgentPreferences[agentType]
+
+		if !models.IsValidAgentType(agentType) {
+			report(prefix+".model", fmt.Sprintf("unknown agent '%s'", name), func(c *models.Config) {
+				delete(c.AgentPreferences, agentType)
+			})
+			continue
+		}
+
+		if agentConfig.Model != "" {
+			if err := m.ValidateModel(agentConfig.Model); err != nil {
+				report(prefix+".model", err.Error(), func(c *models.Config) {
+					setAgentField(c, agentType, func(a *models.AgentConfig) { a.Model = fallback.Model })
+				})
+			}
+		}
+
+		if agentConfig.Temperature < 0 || agentConfig.Temperature > maxTemperature {
+			report(prefix+".temperature", fmt.Sprintf("%g is outside 0-%.0f", agentConfig.Temperature, maxTemperature), func(c *models.Config) {
+				setAgentField(c, agentType, func(a *models.AgentConfig) { a.Temperature = fallback.Temperature })
+			})
+		}
+
+		if agentConfig.MaxTokens <= 0 {
+			report(prefix+".max_tokens", "must be positive", func(c *models.Config) {
+				setAgentField(c, agentType, func(a *models.AgentConfig) { a.MaxTokens = fallback.MaxTokens })
+			})
+		} else if model, ok := m.lookupModel(agentConfig.Model); ok && model.MaxCompletionTokens > 0 && agentConfig.MaxTokens > model.MaxCompletionTokens {
+			limit := model.MaxCompletionTokens
+			report(prefix+".max_tokens", fmt.Sprintf("%d exceeds the %d output tokens %s supports", agentConfig.MaxTokens, limit, model.ID), func(c *models.Config) {
+				setAgentField(c, agentType, func(a *models.AgentConfig) { a.MaxTokens = limit })
+			})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, command := range config.AllowedCommands {
+		if seen[command] {
+			report("allowed_commands", fmt.Sprintf("'%s' is listed more than once", command), func(c *models.Config) {
+				c.AllowedCommands = dedupe(c.AllowedCommands)
+			})
+			break
+		}
+		seen[command] = true
+	}
+
+	if config.Review.Rounds < 0 {
+		report("review.rounds", "must not be negative", func(c *models.Config) {
+			c.Review.Rounds = defaults.Review.Rounds
+		})
+	}
+	if !models.IsValidAgentType(config.Review.Agent) {
+		report("review.agent", fmt.Sprintf("unknown agent '%s'", config.Review.Agent), func(c *models.Config) {
+			c.Review.Agent = defaults.Review.Agent
+		})
+	}
+	if config.Acceptance.Rounds < 0 {
+		report("acceptance.rounds", "must not be negative", func(c *models.Config) {
+			c.Acceptance.Rounds = defaults.Acceptance.Rounds
+		})
+	}
+	if !models.IsValidAgentType(config.Acceptance.Agent) {
+		report("acceptance.agent", fmt.Sprintf("unknown agent '%s'", config.Acceptance.Agent), func(c *models.Config) {
+			c.Acceptance.Agent = defaults.Acceptance.Agent
+		})
+	}
+	if _, err := time.ParseDuration(config.Acceptance.Timeout); err != nil {
+		report("acceptance.timeout", err.Error(), func(c *models.Config) {
+			c.Acceptance.Timeout = defaults.Acceptance.Timeout
+		})
+	}
+
+	return problems
+}
+
+// Fix repairs every fixable problem in the user file and saves it
+func (m *Manager) Fix(problems []Problem) error {
+	for _, problem := range problems {
+		if problem.fix != nil {
+			problem.fix(m.fileConfig)
+		}
+	}
+	m.fileConfig.Version = models.ConfigVersion
+
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.Load()
+}
+
+// setAgentField changes one agent's preferences in place
+func setAgentField(config *models.Config, agentType models.AgentType, change func(agentConfig *models.AgentConfig)) {
+	agentConfig := config.AgentPreferences[agentType]
+	change(&agentConfig)
+	config.AgentPreferences[agentType] = agentConfig
+}
+
+// dedupe removes repeated entries, keeping the first occurrence
+func dedupe(list []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, item := range list {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// unknownKeys lists the dotted keys in a raw config map that don't match
+// any setting
+func unknownKeys(values map[string]interface{}) []string {
+	var keys []string
+	flatten(values, "", func(key string, _ interface{}) {
+		if _, err := settingType(key); err != nil {
+			keys = append(keys, key)
+		}
+	})
+	sort.Strings(keys)
+	return keys
+}
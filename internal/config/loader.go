@@ -0,0 +1,372 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"go-code/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Layer sources beyond the default and user file
+const (
+	SourceFlag          = "flag"
+	SourceProjectPrefix = "project:"
+	SourceEnvPrefix     = "env:"
+)
+
+// envPrefix is prepended to a setting's dotted key to form its environment
+// variable, e.g. GOCODE_DEFAULT_MODEL or GOCODE_AGENT_PREFERENCES_BACKEND_MODEL
+const envPrefix = "GOCODE_"
+
+// projectFileNames are searched for from the working directory upwards
+var projectFileNames = []string{".go-code.json", ".go-code.yaml", ".go-code.yml", ".go-code.toml"}
+
+// userOnlyKeys can't be set by a project file, so that a checked-out
+// repository can't run commands, widen permissions or redirect requests
+// (and the API key) to another server on the user's behalf
+var userOnlyKeys = []string{
+	"groq_api_key",
+	"key_command",
+	"allowed_commands",
+	"require_command_permission",
+	"session_permissions",
+	"provider",
+	"profiles",
+	"mcp_servers",
+}
+
+// override is a dotted key set from the command line
+type override struct {
+	key   string
+	value string
+}
+
+// Setting is a single effective leaf value and the layer it came from
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// SetOverride sets a dotted key (e.g. agent_preferences.backend.temperature)
+// that takes precedence over every other layer. Call it before Load.
+func (m *Manager) SetOverride(key, value string) {
+	m.overrides = append(m.overrides, override{key: NormalizeKey(key), value: value})
+}
+
+// Settings returns every effective leaf setting with its source, sorted by key
+func (m *Manager) Settings() ([]Setting, error) {
+	data, err := toMap(m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []Setting
+	flatten(data, "", func(key string, value interface{}) {
+		settings = append(settings, Setting{Key: key, Value: value, Source: m.Source(key)})
+	})
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings, nil
+}
+
+// applyLayers merges every configuration layer on top of the defaults and
+// decodes the result into the effective and user configurations
+func (m *Manager) applyLayers(userData map[string]interface{}) error {
+	m.sources = make(map[string]string)
+
+	merged, err := toMap(models.DefaultConfig())
+	if err != nil {
+		return err
+	}
+
+	// User file
+	mergeLayer(merged, userData, "", SourceFile, m.sources)
+	fileConfig, err := decodeConfig(merged)
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", m.configPath, err)
+	}
+	m.mergeWithDefaults(fileConfig)
+	m.fileConfig = fileConfig
+
+	// Project file
+	cwd, _ := os.Getwd()
+	if path := findProjectFile(cwd); path != "" && path != m.configPath {
+		projectData, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		for _, key := range userOnlyKeys {
+			if _, ok := projectData[key]; ok {
+				fmt.Fprintf(os.Stderr, "⚠️  Warning: ignoring %q in %s; it can only be set in your user config\n", key, path)
+				delete(projectData, key)
+			}
+		}
+		for _, change := range migrateValues(projectData) {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %s is outdated (%s); update it or run 'go-code config doctor'\n", path, change)
+		}
+		delete(projectData, "version")
+		mergeLayer(merged, projectData, "", SourceProjectPrefix+path, m.sources)
+		m.projectPath = path
+	}
+
+	// Environment and CLI flags win over every other layer, the profile
+	// included, but may select the profile, so they are read first
+	explicit := make(map[string]interface{})
+	explicitSources := make(map[string]string)
+	if err := applyEnv(merged, explicit, explicitSources); err != nil {
+		return err
+	}
+	for _, o := range m.overrides {
+		value, err := parseSetting(o.key, o.value)
+		if err != nil {
+			return fmt.Errorf("invalid --set: %w", err)
+		}
+		setPath(explicit, o.key, value)
+		explicitSources[o.key] = SourceFlag
+	}
+
+	// Profile
+	profile, _ := getPath(merged, "profile").(string)
+	setBy := m.Source("profile")
+	if name, ok := getPath(explicit, "profile").(string); ok {
+		profile, setBy = name, explicitSources["profile"]
+	}
+	if err := m.applyProfile(merged, profile, setBy); err != nil {
+		return err
+	}
+
+	// Environment and CLI flags
+	for key, source := range explicitSources {
+		setPath(merged, key, getPath(explicit, key))
+		m.sources[key] = source
+	}
+
+	config, err := decodeConfig(merged)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	m.mergeWithDefaults(config)
+	m.config = config
+
+	return m.resolveAPIKey()
+}
+
+// applyEnv collects every known setting that has a GOCODE_* variable set
+// into layer, recording the variable in sources
+func applyEnv(merged, layer map[string]interface{}, sources map[string]string) error {
+	var keys []string
+	flatten(merged, "", func(key string, _ interface{}) {
+		keys = append(keys, key)
+	})
+
+	for _, key := range keys {
+		name := envName(key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		value, err := parseSetting(key, raw)
+		if err != nil {
+			return fmt.Errorf("invalid value in %s: %w", name, err)
+		}
+		setPath(layer, key, value)
+		sources[key] = SourceEnvPrefix + name
+	}
+
+	return nil
+}
+
+// envName returns the environment variable for a dotted key
+func envName(key string) string {
+	replacer := strings.NewReplacer(".", "_", "-", "_", "/", "_")
+	return envPrefix + strings.ToUpper(replacer.Replace(key))
+}
+
+// findProjectFile walks up from dir looking for a project config file
+func findProjectFile(dir string) string {
+	for dir != "" {
+		for _, name := range projectFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// readConfigFile reads a JSON, YAML or TOML config file into a generic map.
+// Keys are kept as written; viper would lower-case map keys such as MCP env
+// variables along with the field names.
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch fileExt(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// writeConfigFile writes config in the format matching the file's extension
+func writeConfigFile(path string, config *models.Config) error {
+	data, err := marshalConfig(fileExt(path), config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// marshalConfig encodes config as JSON, YAML or TOML
+func marshalConfig(ext string, config *models.Config) ([]byte, error) {
+	if ext == ".json" {
+		return json.MarshalIndent(config, "", "  ")
+	}
+
+	values, err := toMap(config)
+	if err != nil {
+		return nil, err
+	}
+	if ext == ".toml" {
+		return toml.Marshal(values)
+	}
+	return yaml.Marshal(values)
+}
+
+// toMap converts a config struct into a generic map through its JSON form
+func toMap(config *models.Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return values, nil
+}
+
+// decodeConfig converts a generic map back into a config struct
+func decodeConfig(values map[string]interface{}) (*models.Config, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var config models.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return nil, fmt.Errorf("%s must be a %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// mergeLayer deep-merges src into dst, recording source for every leaf it
+// sets. Field names match case-insensitively; map keys as written.
+func mergeLayer(dst, src map[string]interface{}, prefix, source string, sources map[string]string) {
+	for key, value := range src {
+		key = fieldKey(prefix, key)
+		path := joinKey(prefix, key)
+
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeLayer(dstMap, srcMap, path, source, sources)
+			continue
+		}
+
+		dst[key] = value
+		if srcIsMap {
+			flatten(srcMap, path, func(leaf string, _ interface{}) {
+				sources[leaf] = source
+			})
+		} else {
+			sources[path] = source
+		}
+	}
+}
+
+// flatten calls fn for every leaf of a nested map with its dotted key
+func flatten(values map[string]interface{}, prefix string, fn func(key string, value interface{})) {
+	for key, value := range values {
+		path := joinKey(prefix, key)
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(nested, path, fn)
+			continue
+		}
+		fn(path, value)
+	}
+}
+
+// getPath returns the value at a dotted key, or nil if it isn't set
+func getPath(values map[string]interface{}, key string) interface{} {
+	parts := strings.Split(key, ".")
+	current := values
+	for i, part := range parts {
+		value, ok := current[part]
+		if !ok {
+			return nil
+		}
+		if i == len(parts)-1 {
+			return value
+		}
+		if current, ok = value.(map[string]interface{}); !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// setPath sets the value at a dotted key, creating intermediate maps
+func setPath(values map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// joinKey joins a dotted prefix and a key
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
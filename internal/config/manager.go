@@ -1,82 +1,107 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"go-code/internal/catalog"
 	"go-code/pkg/models"
 )
 
 // Manager handles configuration loading and saving
 type Manager struct {
-	configPath string
-	config     *models.Config
+	configPath  string
+	projectPath string
+	overrides   []override
+	config      *models.Config
+	fileConfig  *models.Config
+	sources     map[string]string
+	catalog     *catalog.Catalog
+	unknownKeys []string
 }
 
 // NewManager creates a new configuration manager
 func NewManager() *Manager {
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".go-code")
-	configPath := filepath.Join(configDir, "config.json")
+	return NewManagerWithPath("")
+}
+
+// NewManagerWithPath creates a configuration manager that uses path as the
+// user config file. An empty path means ~/.go-code/config.json.
+func NewManagerWithPath(path string) *Manager {
+	if path == "" {
+		path = filepath.Join(defaultConfigDir(), "config.json")
+	}
 	
 	return &Manager{
-		configPath: configPath,
+		configPath: path,
 		config:     models.DefaultConfig(),
+		fileConfig: models.DefaultConfig(),
+		sources:    make(map[string]string),
 	}
 }
 
-// Load loads the configuration from file
+// Load builds the effective configuration from built-in defaults, the user
+// config file, the nearest project .go-code.{json,yaml,toml}, the selected
+// profile, GOCODE_* environment variables and CLI overrides, in that order
 func (m *Manager) Load() error {
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(m.configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// Check if config file exists
 	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
 		// Create default config file
-		return m.Save()
+		if err := m.Save(); err != nil {
+			return err
+		}
+	} else {
+		// The file may hold an API key, so keep it private to the user
+		m.enforcePermissions()
 	}
 
 	// Read config file
-	data, err := os.ReadFile(m.configPath)
+	userData, err := readConfigFile(m.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return err
 	}
 
-	// Parse config
-	var config models.Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+	migrated, err := m.migrateUserFile(userData)
+	if err != nil {
+		return err
 	}
+	m.unknownKeys = unknownKeys(userData)
 
-	// Merge with defaults to ensure all fields are present
-	m.mergeWithDefaults(&config)
-	m.config = &config
-
+	if err := m.applyLayers(userData); err != nil {
+		return err
+	}
+	if migrated {
+		return m.Save()
+	}
 	return nil
 }
 
-// Save saves the configuration to file
+// Save saves the user layer of the configuration to file. Values that came
+// from a project file, the environment or CLI flags are never persisted.
 func (m *Manager) Save() error {
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(m.configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Marshal config to JSON
-	data, err := json.MarshalIndent(m.config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	if err := writeConfigFile(m.configPath, m.fileConfig); err != nil {
+		return err
 	}
 
-	// Write to file
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	// Writing keeps the mode of an existing file, so tighten it explicitly.
+	// A file the user pointed --config at keeps the mode they gave it.
+	if m.ownsConfigDir() {
+		if err := os.Chmod(m.configPath, 0600); err != nil {
+			return fmt.Errorf("failed to set config file permissions: %w", err)
+		}
 	}
 
 	return nil
@@ -87,72 +112,114 @@ func (m *Manager) GetConfig() *models.Config {
 	return m.config
 }
 
-// SetGroqAPIKey sets the Groq API key
+// update applies a change to both the effective and the user configuration
+// and saves the user file
+func (m *Manager) update(change func(config *models.Config)) error {
+	change(m.config)
+	change(m.fileConfig)
+	return m.Save()
+}
+
+// SetGroqAPIKey sets the Groq API key stored in the config file
 func (m *Manager) SetGroqAPIKey(apiKey string) error {
-	m.config.GroqAPIKey = apiKey
+	m.fileConfig.GroqAPIKey = apiKey
+	if source := m.Source("groq_api_key"); source == SourceFile || source == SourceDefault {
+		m.config.GroqAPIKey = apiKey
+		m.sources["groq_api_key"] = SourceFile
+	}
 	return m.Save()
 }
 
+// ConfigPath returns the path of the config file
+func (m *Manager) ConfigPath() string {
+	return m.configPath
+}
+
+// ProjectPath returns the project config file that was applied, if any
+func (m *Manager) ProjectPath() string {
+	return m.projectPath
+}
+
 // SetDefaultModel sets the default model
 func (m *Manager) SetDefaultModel(model string) error {
-	m.config.DefaultModel = model
-	return m.Save()
+	return m.update(func(config *models.Config) {
+		config.DefaultModel = model
+	})
 }
 
 // SetAllowCommands toggles command execution permissions
 func (m *Manager) SetAllowCommands(allow bool) error {
-	m.config.RequireCommandPermission = !allow
-	return m.Save()
+	return m.update(func(config *models.Config) {
+		config.RequireCommandPermission = !allow
+	})
 }
 
 // AddAllowedCommand adds a command to the allowed list
 func (m *Manager) AddAllowedCommand(command string) error {
-	for _, cmd := range m.config.AllowedCommands {
+	for _, cmd := range m.fileConfig.AllowedCommands {
 		if cmd == command {
 			return nil // Already exists
 		}
 	}
-	m.config.AllowedCommands = append(m.config.AllowedCommands, command)
-	return m.Save()
+	return m.update(func(config *models.Config) {
+		config.AllowedCommands = append(config.AllowedCommands, command)
+	})
 }
 
 // RemoveAllowedCommand removes a command from the allowed list
 func (m *Manager) RemoveAllowedCommand(command string) error {
-	for i, cmd := range m.config.AllowedCommands {
-		if cmd == command {
-			m.config.AllowedCommands = append(m.config.AllowedCommands[:i], m.config.AllowedCommands[i+1:]...)
-			break
+	return m.update(func(config *models.Config) {
+		for i, cmd := range config.AllowedCommands {
+			if cmd == command {
+				config.AllowedCommands = append(config.AllowedCommands[:i:i], config.AllowedCommands[i+1:]...)
+				break
+			}
 		}
-	}
-	return m.Save()
-}
-
-// SetAgentConfig sets configuration for a specific agent
-func (m *Manager) SetAgentConfig(agentType models.AgentType, config models.AgentConfig) error {
-	if m.config.AgentPreferences == nil {
-		m.config.AgentPreferences = make(map[models.AgentType]models.AgentConfig)
-	}
-	m.config.AgentPreferences[agentType] = config
-	return m.Save()
+	})
 }
 
 // ValidateConfig validates the configuration
 func (m *Manager) ValidateConfig() error {
-	if m.config.GroqAPIKey == "" {
+	if m.config.Provider.IsGroq() && m.config.GroqAPIKey == "" {
 		return fmt.Errorf("Groq API key is required. Use 'go-code config set-key <key>' to set it")
 	}
 
-	if m.config.DefaultModel == "" {
-		return fmt.Errorf("default model is required")
+	return m.ValidateSettings()
+}
+
+// lookupModel finds a model in the model catalog
+func (m *Manager) lookupModel(model string) (catalog.Model, bool) {
+	if m.catalog == nil {
+		m.catalog = catalog.Load(catalog.DefaultCachePath())
 	}
+	return m.catalog.Lookup(model)
+}
 
-	// Validate models in agent preferences
-	for agentType, config := range m.config.AgentPreferences {
-		if config.Model != "" && !isValidModel(config.Model) {
-			return fmt.Errorf("invalid model '%s' for agent %s", config.Model, agentType)
-		}
+// RateLimit returns the requests and tokens per minute allowed for a model:
+// rate_limits from the config, else the model catalog for Groq models
+func (m *Manager) RateLimit(model string) models.RateLimit {
+	if limit, ok := m.config.RateLimits[model]; ok {
+		return limit
+	}
+	if !m.config.Provider.IsGroq() {
+		return models.RateLimit{}
+	}
+	info, _ := m.lookupModel(model)
+	return models.RateLimit{
+		RequestsPerMinute: info.RequestsPerMinute,
+		TokensPerMinute:   info.TokensPerMinute,
 	}
+}
 
+// ValidateModel checks a model against the model catalog
+func (m *Manager) ValidateModel(model string) error {
+	// The catalog only lists Groq models; other providers serve their own
+	if !m.config.Provider.IsGroq() {
+		return nil
+	}
+	if _, ok := m.lookupModel(model); !ok {
+		return fmt.Errorf("unknown model '%s' (see 'go-code models list', or add --refresh if it was released recently)", model)
+	}
 	return nil
 }
 
@@ -182,30 +249,32 @@ func (m *Manager) mergeWithDefaults(config *models.Config) {
 	if config.SessionPermissions == nil {
 		config.SessionPermissions = make(map[string]bool)
 	}
-}
 
-// isValidModel checks if a model is valid
-func isValidModel(model string) bool {
-	validModels := []string{
-		// Production models
-		"gemma2-9b-it",
-		"llama-3.1-8b-instant",
-		"llama-3.3-70b-versatile",
-		"meta-llama/llama-guard-4-12b",
-		// Preview models
-		"deepseek-r1-distill-llama-70b",
-		"meta-llama/llama-4-maverick-17b-128e-instruct",
-		"meta-llama/llama-4-scout-17b-16e-instruct",
-		"moonshotai/kimi-k2-instruct",
-		"qwen/qwen3-32b",
-		// OpenAI models on Groq
-		"openai/gpt-oss-120b",
-	}
-
-	for _, validModel := range validModels {
-		if model == validModel {
-			return true
-		}
+	// Unset rounds already come from the defaults layer; 0 means no
+	// revisions and is kept
+
+	if config.Review.Agent == "" {
+		config.Review.Agent = defaults.Review.Agent
 	}
-	return false
-}
\ No newline at end of file
+
+	if config.Acceptance.Agent == "" {
+		config.Acceptance.Agent = defaults.Acceptance.Agent
+	}
+	if config.Acceptance.Timeout == "" {
+		config.Acceptance.Timeout = defaults.Acceptance.Timeout
+	}
+
+	if config.Cache.TTL == "" {
+		config.Cache.TTL = defaults.Cache.TTL
+	}
+	if config.Cache.MaxSizeMB == 0 {
+		config.Cache.MaxSizeMB = defaults.Cache.MaxSizeMB
+	}
+
+	if config.Provider.Name == "" {
+		config.Provider = defaults.Provider
+	}
+	if config.Provider.BaseURL == "" && config.Provider.IsGroq() {
+		config.Provider.BaseURL = defaults.Provider.BaseURL
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"go-code/pkg/models"
+)
+
+// migration upgrades a raw config map from version-1 to version. It returns
+// a description of every change it made.
+type migration struct {
+	version     int
+	description string
+	apply       func(values map[string]interface{}) []string
+}
+
+// migrations is the ordered chain that brings any config up to
+// models.ConfigVersion. Append new steps; never edit released ones.
+var migrations = []migration{
+	{1, "replace retired models", remapRetiredModels},
+}
+
+// retiredModels maps models that were removed from the API to their replacements
+var retiredModels = map[string]string{
+	"llama-3.1-70b-versatile":               "llama-3.3-70b-versatile",
+	"llama3-70b-8192":                       "llama-3.3-70b-versatile",
+	"llama3-8b-8192":                        "llama-3.1-8b-instant",
+	"llama3-groq-70b-8192-tool-use-preview": "llama-3.3-70b-versatile",
+	"llama3-groq-8b-8192-tool-use-preview":  "llama-3.1-8b-instant",
+	"mixtral-8x7b-32768":                    "llama-3.3-70b-versatile",
+	"gemma-7b-it":                           "gemma2-9b-it",
+	"qwen2.5-coder-32b-instruct":            "moonshotai/kimi-k2-instruct",
+	"qwen-2.5-coder-32b":                    "moonshotai/kimi-k2-instruct",
+}
+
+// migrateUserFile upgrades an outdated user config in memory, backing up the
+// file first. It reports whether the file needs to be saved.
+func (m *Manager) migrateUserFile(values map[string]interface{}) (bool, error) {
+	from := configVersion(values)
+	if from >= models.ConfigVersion {
+		return false, nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", m.configPath, from)
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := os.WriteFile(backup, data, 0600); err != nil {
+		return false, fmt.Errorf("failed to back up config file before migrating: %w", err)
+	}
+
+	changes := migrateValues(values)
+	for _, change := range changes {
+		fmt.Fprintf(os.Stderr, "🔧 Migrated config: %s\n", change)
+	}
+	if len(changes) > 0 {
+		fmt.Fprintf(os.Stderr, "   Previous file saved to %s\n", backup)
+	}
+	return true, nil
+}
+
+// migrateValues runs every migration newer than the map's version on it and
+// stamps it with the current version
+func migrateValues(values map[string]interface{}) []string {
+	lowerKeys(values, "")
+
+	from := configVersion(values)
+	var changes []string
+	for _, step := range migrations {
+		if step.version <= from {
+			continue
+		}
+		for _, change := range step.apply(values) {
+			changes = append(changes, fmt.Sprintf("v%d %s: %s", step.version, step.description, change))
+		}
+	}
+
+	values["version"] = models.ConfigVersion
+	return changes
+}
+
+// remapRetiredModels replaces retired models in default_model and every
+// agent's model
+func remapRetiredModels(values map[string]interface{}) []string {
+	var changes []string
+	replace := func(key string) {
+		model, ok := getPath(values, key).(string)
+		if !ok {
+			return
+		}
+		if replacement, retired := retiredModels[model]; retired {
+			setPath(values, key, replacement)
+			changes = append(changes, fmt.Sprintf("%s %s → %s", key, model, replacement))
+		}
+	}
+
+	replace("default_model")
+	if prefs, ok := values["agent_preferences"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(prefs))
+		for name := range prefs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			replace("agent_preferences." + name + ".model")
+		}
+	}
+	return changes
+}
+
+// configVersion reads the version of a raw config map; files written before
+// versioning have none and count as version 0
+func configVersion(values map[string]interface{}) int {
+	switch v := values["version"].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// lowerKeys lower-cases the field names of a nested map below prefix, so
+// migrations find them however they were written. Map keys, e.g. profile
+// names or MCP env variables, are kept as written.
+func lowerKeys(values map[string]interface{}, prefix string) {
+	for key, value := range values {
+		lower := fieldKey(prefix, key)
+		if nested, ok := value.(map[string]interface{}); ok {
+			lowerKeys(nested, joinKey(prefix, lower))
+		}
+		if lower != key {
+			delete(values, key)
+			values[lower] = value
+		}
+	}
+}
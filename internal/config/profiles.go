@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go-code/pkg/models"
+)
+
+// SourceProfilePrefix marks settings overridden by the active profile
+const SourceProfilePrefix = "profile:"
+
+// ProfileInfo describes an available profile
+type ProfileInfo struct {
+	Name    string
+	Profile models.Profile
+	Builtin bool
+}
+
+// Profiles returns the built-in and user profiles, sorted by name. User
+// profiles replace built-in ones of the same name.
+func (m *Manager) Profiles() []ProfileInfo {
+	var profiles []ProfileInfo
+	for name, profile := range m.allProfiles() {
+		_, user := m.fileConfig.Profiles[name]
+		profiles = append(profiles, ProfileInfo{Name: name, Profile: profile, Builtin: !user})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// ActiveProfile returns the name of the profile in effect, if any
+func (m *Manager) ActiveProfile() string {
+	return m.config.Profile
+}
+
+// CreateProfile saves a user profile
+func (m *Manager) CreateProfile(name string, profile models.Profile) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	for agentType, agentConfig := range profile.AgentPreferences {
+		if err := m.ValidateAgentConfig(agentType, agentConfig); err != nil {
+			return err
+		}
+	}
+
+	return m.updateFile(func(config *models.Config) {
+		if config.Profiles == nil {
+			config.Profiles = make(map[string]models.Profile)
+		}
+		config.Profiles[name] = profile
+	})
+}
+
+// UseProfile makes a profile the default for every run. An empty name goes
+// back to the plain configuration.
+func (m *Manager) UseProfile(name string) error {
+	if name != "" {
+		if _, ok := m.allProfiles()[name]; !ok {
+			return fmt.Errorf("unknown profile '%s' (see 'go-code config profile list')", name)
+		}
+	}
+	return m.updateFile(func(config *models.Config) {
+		config.Profile = name
+	})
+}
+
+// DeleteProfile removes a user profile
+func (m *Manager) DeleteProfile(name string) error {
+	if _, ok := m.fileConfig.Profiles[name]; !ok {
+		if _, builtin := models.BuiltinProfiles()[name]; builtin {
+			return fmt.Errorf("'%s' is a built-in profile and can't be deleted", name)
+		}
+		return fmt.Errorf("unknown profile '%s'", name)
+	}
+
+	return m.updateFile(func(config *models.Config) {
+		delete(config.Profiles, name)
+		if config.Profile == name {
+			config.Profile = ""
+		}
+	})
+}
+
+// allProfiles merges the user profiles over the built-in ones. Profiles can
+// only be defined in the user file.
+func (m *Manager) allProfiles() map[string]models.Profile {
+	profiles := models.BuiltinProfiles()
+	for name, profile := range m.fileConfig.Profiles {
+		profiles[name] = profile
+	}
+	return profiles
+}
+
+// applyProfile merges the named profile into the merged layers below the
+// environment and CLI flags. setBy is where the name came from.
+func (m *Manager) applyProfile(values map[string]interface{}, name, setBy string) error {
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := m.allProfiles()[name]
+	if !ok {
+		return fmt.Errorf("unknown profile '%s' (set by %s; see 'go-code config profile list')", name, setBy)
+	}
+
+	layer := make(map[string]interface{})
+	set := func(key string, value interface{}) {
+		setPath(layer, key, value)
+	}
+
+	if profile.Provider.Name != "" {
+		set("provider.name", profile.Provider.Name)
+	}
+	if profile.Provider.BaseURL != "" {
+		set("provider.base_url", profile.Provider.BaseURL)
+	}
+	if profile.Provider.APIKeyEnv != "" {
+		set("provider.api_key_env", profile.Provider.APIKeyEnv)
+	}
+
+	if profile.Model != "" {
+		set("default_model", profile.Model)
+		agentPreferences, _ := values["agent_preferences"].(map[string]interface{})
+		for agentType := range agentPreferences {
+			set("agent_preferences."+agentType+".model", profile.Model)
+		}
+	}
+	if profile.DefaultModel != "" {
+		set("default_model", profile.DefaultModel)
+	}
+
+	for agentType, override := range profile.AgentPreferences {
+		prefix := "agent_preferences." + string(agentType)
+		if override.Model != "" {
+			set(prefix+".model", override.Model)
+		}
+		if override.Temperature != 0 {
+			set(prefix+".temperature", override.Temperature)
+		}
+		if override.MaxTokens != 0 {
+			set(prefix+".max_tokens", override.MaxTokens)
+		}
+	}
+
+	mergeLayer(values, layer, "", SourceProfilePrefix+name, m.sources)
+	return nil
+}
+
+// updateFile changes the user file, saves it and reloads every layer
+func (m *Manager) updateFile(change func(config *models.Config)) error {
+	change(m.fileConfig)
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.Load()
+}
+
+// ProviderAPIKey returns the key to send to the configured provider. The
+// Groq key is never sent to another provider.
+func (m *Manager) ProviderAPIKey() string {
+	if m.config.Provider.IsGroq() {
+		return m.config.GroqAPIKey
+	}
+	if m.config.Provider.APIKeyEnv != "" {
+		return os.Getenv(m.config.Provider.APIKeyEnv)
+	}
+	return ""
+}
+
+// validateProfileName checks a profile name is usable as a dotted key
+func validateProfileName(name string) error {
+	if name == "" || name == "none" {
+		return fmt.Errorf("invalid profile name '%s'", name)
+	}
+	if strings.ContainsAny(name, ". \t") {
+		return fmt.Errorf("profile name '%s' can't contain dots or spaces", name)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Sources describing where an effective setting came from
+const (
+	SourceDefault    = "default"
+	SourceFile       = "file"
+	SourceKeyCommand = "key_command"
+)
+
+// apiKeyEnvVar is the conventional variable checked when GOCODE_GROQ_API_KEY
+// isn't set
+const apiKeyEnvVar = "GROQ_API_KEY"
+
+// keyCommandTimeout bounds how long a key helper may run
+const keyCommandTimeout = 10 * time.Second
+
+// Source returns where the effective value of a dotted setting came from:
+// "default", "file", "project:<path>", "env:<NAME>", "flag" or "key_command"
+func (m *Manager) Source(key string) string {
+	if source, ok := m.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// resolveAPIKey applies the GROQ_API_KEY fallback or the key command on top
+// of the key from the config file. A key set through GOCODE_GROQ_API_KEY or
+// a CLI flag always wins.
+func (m *Manager) resolveAPIKey() error {
+	source := m.Source("groq_api_key")
+	if strings.HasPrefix(source, SourceEnvPrefix) || source == SourceFlag {
+		return nil
+	}
+
+	if value := strings.TrimSpace(os.Getenv(apiKeyEnvVar)); value != "" {
+		m.config.GroqAPIKey = value
+		m.sources["groq_api_key"] = SourceEnvPrefix + apiKeyEnvVar
+		return nil
+	}
+
+	if m.config.KeyCommand != "" {
+		key, err := runKeyCommand(m.config.KeyCommand)
+		if err != nil {
+			return fmt.Errorf("key_command failed: %w", err)
+		}
+		m.config.GroqAPIKey = key
+		m.sources["groq_api_key"] = SourceKeyCommand
+	}
+
+	return nil
+}
+
+// runKeyCommand runs a helper such as "pass show groq" and returns the first
+// line of its output
+func runKeyCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Stdin = os.Stdin // Helpers like pass may prompt for a passphrase
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	key := strings.TrimSpace(strings.SplitN(stdout.String(), "\n", 2)[0])
+	if key == "" {
+		return "", fmt.Errorf("command printed no key")
+	}
+	return key, nil
+}
+
+// defaultConfigDir returns go-code's own configuration directory
+func defaultConfigDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-code")
+}
+
+// ownsConfigDir reports whether the config file lives in go-code's own
+// directory rather than at a path given with --config
+func (m *Manager) ownsConfigDir() bool {
+	return filepath.Clean(filepath.Dir(m.configPath)) == filepath.Clean(defaultConfigDir())
+}
+
+// enforcePermissions warns about a config directory or file that other users
+// can read. go-code's own directory and file are tightened; a file given
+// with --config only gets a warning, and its directory is left alone.
+func (m *Manager) enforcePermissions() {
+	// Windows ACLs don't map onto Unix permission bits
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	type permissionCheck struct {
+		path string
+		mode os.FileMode
+	}
+	checks := []permissionCheck{{m.configPath, 0600}}
+	if m.ownsConfigDir() {
+		checks = append([]permissionCheck{{filepath.Dir(m.configPath), 0700}}, checks...)
+	}
+
+	for _, check := range checks {
+		info, err := os.Stat(check.path)
+		if err != nil || info.Mode().Perm()&0077 == 0 {
+			continue
+		}
+
+		if !m.ownsConfigDir() {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %s has permissions %04o and may expose your API key; consider 'chmod %o %s'\n",
+				check.path, info.Mode().Perm(), check.mode, check.path)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %s has permissions %04o and may expose your API key; changing to %04o\n",
+			check.path, info.Mode().Perm(), check.mode)
+		if err := os.Chmod(check.path, check.mode); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: failed to change permissions of %s: %v\n", check.path, err)
+		}
+	}
+}
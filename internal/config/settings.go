@@ -0,0 +1,350 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// maxTemperature is the highest sampling temperature the API accepts
+const maxTemperature = 2.0
+
+// mcpServerName matches the names of mcp_servers entries. Agents see a tool
+// as <server>__<tool>, so names can't contain a double underscore.
+var mcpServerName = regexp.MustCompile(`^[A-Za-z0-9-]+(_[A-Za-z0-9-]+)*$`)
+
+// Set changes a dotted setting in the user config file, e.g.
+// "agent_preferences.backend.temperature" to "0.2". The value is checked
+// against the setting's type and the result is validated before saving.
+func (m *Manager) Set(key, raw string) error {
+	key = NormalizeKey(key)
+
+	value, err := parseSetting(key, raw)
+	if err != nil {
+		return err
+	}
+
+	values, err := toMap(m.fileConfig)
+	if err != nil {
+		return err
+	}
+	setPath(values, key, value)
+
+	updated, err := decodeConfig(values)
+	if err != nil {
+		return err
+	}
+	m.mergeWithDefaults(updated)
+
+	if err := m.validateSettings(updated); err != nil {
+		return err
+	}
+
+	m.fileConfig = updated
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.Load()
+}
+
+// Reset replaces the user config file with the defaults, keeping the API key
+// and key command unless keepCredentials is false. The previous file is kept
+// next to it with a .bak suffix.
+func (m *Manager) Reset(keepCredentials bool) error {
+	if data, err := os.ReadFile(m.configPath); err == nil {
+		if err := os.WriteFile(m.configPath+".bak", data, 0600); err != nil {
+			return fmt.Errorf("failed to back up config file: %w", err)
+		}
+	}
+
+	defaults := models.DefaultConfig()
+	if keepCredentials {
+		defaults.GroqAPIKey = m.fileConfig.GroqAPIKey
+		defaults.KeyCommand = m.fileConfig.KeyCommand
+	}
+
+	m.fileConfig = defaults
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.Load()
+}
+
+// ReplaceFile validates new content for the user config file, in the file's
+// own format, and saves it only if it is valid
+func (m *Manager) ReplaceFile(data []byte) error {
+	tmp, err := os.CreateTemp("", "go-code-config-*"+fileExt(m.configPath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	values, err := readConfigFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	merged, err := toMap(models.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	mergeLayer(merged, values, "", SourceFile, make(map[string]string))
+
+	updated, err := decodeConfig(merged)
+	if err != nil {
+		return err
+	}
+	m.mergeWithDefaults(updated)
+
+	if err := m.validateSettings(updated); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(m.configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return m.Load()
+}
+
+// ValidateSettings checks every setting except the API key
+func (m *Manager) ValidateSettings() error {
+	return m.validateSettings(m.config)
+}
+
+// validateSettings checks models, agents and numeric ranges in config
+func (m *Manager) validateSettings(config *models.Config) error {
+	if config.DefaultModel == "" {
+		return fmt.Errorf("default_model is required")
+	}
+	if err := m.ValidateModel(config.DefaultModel); err != nil {
+		return fmt.Errorf("default_model: %w", err)
+	}
+
+	for agentType, agentConfig := range config.AgentPreferences {
+		if err := m.ValidateAgentConfig(agentType, agentConfig); err != nil {
+			return err
+		}
+	}
+
+	if config.Review.Rounds < 0 {
+		return fmt.Errorf("review.rounds must not be negative")
+	}
+	if !models.IsValidAgentType(config.Review.Agent) {
+		return fmt.Errorf("review.agent: unknown agent '%s'", config.Review.Agent)
+	}
+	if config.Acceptance.Rounds < 0 {
+		return fmt.Errorf("acceptance.rounds must not be negative")
+	}
+	if !models.IsValidAgentType(config.Acceptance.Agent) {
+		return fmt.Errorf("acceptance.agent: unknown agent '%s'", config.Acceptance.Agent)
+	}
+	if _, err := time.ParseDuration(config.Acceptance.Timeout); err != nil {
+		return fmt.Errorf("acceptance.timeout: %w", err)
+	}
+
+	if config.Budget.MaxTokens < 0 {
+		return fmt.Errorf("budget.max_tokens must not be negative")
+	}
+	if config.Budget.MaxCost < 0 {
+		return fmt.Errorf("budget.max_cost must not be negative")
+	}
+
+	if ttl, err := time.ParseDuration(config.Cache.TTL); err != nil || ttl < 0 {
+		return fmt.Errorf("cache.ttl must be a duration such as 24h or 168h, got %q", config.Cache.TTL)
+	}
+	if config.Cache.MaxSizeMB < 0 {
+		return fmt.Errorf("cache.max_size_mb must not be negative")
+	}
+
+	if config.Profile != "" {
+		_, builtin := models.BuiltinProfiles()[config.Profile]
+		if _, user := config.Profiles[config.Profile]; !builtin && !user {
+			return fmt.Errorf("profile: unknown profile '%s'", config.Profile)
+		}
+	}
+
+	for name, server := range config.MCPServers {
+		if err := validateMCPServer(name, server); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateMCPServer checks an entry of mcp_servers
+func validateMCPServer(name string, server models.MCPServerConfig) error {
+	prefix := "mcp_servers." + name
+	if !mcpServerName.MatchString(name) {
+		return fmt.Errorf("%s: server names may only contain letters, digits, '-' and single '_'", prefix)
+	}
+	if strings.TrimSpace(server.Command) == "" {
+		return fmt.Errorf("%s.command is required", prefix)
+	}
+	for tool, agentTypes := range server.Tools {
+		for _, agentType := range agentTypes {
+			if !models.IsValidAgentType(agentType) {
+				return fmt.Errorf("%s.tools.%s: unknown agent '%s'", prefix, tool, agentType)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateAgentConfig checks a single agent's preferences
+func (m *Manager) ValidateAgentConfig(agentType models.AgentType, agentConfig models.AgentConfig) error {
+	prefix := "agent_preferences." + string(agentType)
+
+	if !models.IsValidAgentType(agentType) {
+		return fmt.Errorf("%s: unknown agent '%s'", prefix, agentType)
+	}
+	if agentConfig.Model != "" {
+		if err := m.ValidateModel(agentConfig.Model); err != nil {
+			return fmt.Errorf("%s.model: %w", prefix, err)
+		}
+	}
+	if agentConfig.Temperature < 0 || agentConfig.Temperature > maxTemperature {
+		return fmt.Errorf("%s.temperature must be between 0 and %.0f, got %g", prefix, maxTemperature, agentConfig.Temperature)
+	}
+	if agentConfig.MaxTokens < 0 {
+		return fmt.Errorf("%s.max_tokens must not be negative", prefix)
+	}
+	if model, ok := m.lookupModel(agentConfig.Model); ok && model.MaxCompletionTokens > 0 && agentConfig.MaxTokens > model.MaxCompletionTokens {
+		return fmt.Errorf("%s.max_tokens %d exceeds the %d output tokens %s supports",
+			prefix, agentConfig.MaxTokens, model.MaxCompletionTokens, model.ID)
+	}
+	return nil
+}
+
+// parseSetting converts a raw string into the type stored at a dotted key
+func parseSetting(key, raw string) (interface{}, error) {
+	t, err := settingType(key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects true or false, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a whole number, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a number, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			var list []string
+			if err := json.Unmarshal([]byte(raw), &list); err != nil {
+				return nil, fmt.Errorf("%s expects a list of strings: %w", key, err)
+			}
+			return list, nil
+		}
+		list := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case reflect.Map, reflect.Struct:
+		return nil, fmt.Errorf("%s is a group of settings; set its individual keys instead", key)
+	}
+
+	return nil, fmt.Errorf("%s can't be set from the command line", key)
+}
+
+// settingType resolves the Go type stored at a dotted key of models.Config
+func settingType(key string) (reflect.Type, error) {
+	t := reflect.TypeOf(models.Config{})
+	parts := strings.Split(key, ".")
+
+	for i, part := range parts {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonField(t, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown setting %q", strings.Join(parts[:i+1], "."))
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	return t, nil
+}
+
+// NormalizeKey lower-cases the struct field names of a dotted key, keeping
+// map keys such as profile names and MCP env variables as written
+func NormalizeKey(key string) string {
+	var normalized string
+	for _, part := range strings.Split(strings.TrimSpace(key), ".") {
+		normalized = joinKey(normalized, fieldKey(normalized, part))
+	}
+	return normalized
+}
+
+// fieldKey returns how key is stored below the dotted prefix. Struct field
+// names are case-insensitive and stored lower-cased; map keys are data and
+// kept as written.
+func fieldKey(prefix, key string) string {
+	if prefix != "" {
+		if t, err := settingType(prefix); err == nil && t.Kind() == reflect.Map {
+			return key
+		}
+	}
+	return strings.ToLower(key)
+}
+
+// jsonField finds a struct field by its JSON name
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fileExt returns a path's extension, defaulting to .json
+func fileExt(path string) string {
+	for _, ext := range []string{".json", ".yaml", ".yml", ".toml"} {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return ext
+		}
+	}
+	return ".json"
+}
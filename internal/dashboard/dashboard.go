@@ -0,0 +1,500 @@
+// Package dashboard is a full-screen terminal view of a running build: the
+// task list, the selected task's streamed output, the files written so far
+// and a token and cost meter, with keys to pause, skip and retry tasks.
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-code/internal/events"
+	"go-code/internal/orchestrator"
+	"go-code/internal/ui"
+	"go-code/pkg/models"
+	"golang.org/x/term"
+)
+
+// refreshInterval is how often the screen is redrawn
+const refreshInterval = 100 * time.Millisecond
+
+// Dashboard follows a build through its events and draws it full screen
+type Dashboard struct {
+	mu       sync.Mutex
+	title    string
+	controls *orchestrator.Controls
+	in       *os.File
+	out      io.Writer
+
+	tasks    []*taskView
+	byID     map[string]*taskView
+	selected int
+	follow   bool
+	files    []string
+	seen     map[string]bool
+	usage    models.UsageSummary
+	status   string
+	started  time.Time
+	done     bool
+}
+
+// taskView is the dashboard's state for one planned task
+type taskView struct {
+	id          string
+	agent       models.AgentType
+	description string
+	status      string
+	started     time.Time
+	finished    time.Time
+	output      strings.Builder
+	err         string
+}
+
+// Interactive reports whether stdin and stdout are both terminals, which the
+// dashboard needs to read keys and draw
+func Interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// New creates a dashboard for a build titled title, steering it with controls
+func New(title string, controls *orchestrator.Controls) *Dashboard {
+	return &Dashboard{
+		title:    title,
+		controls: controls,
+		in:       os.Stdin,
+		out:      os.Stdout,
+		byID:     make(map[string]*taskView),
+		seen:     make(map[string]bool),
+		follow:   true,
+		status:   "Planning",
+		started:  time.Now(),
+	}
+}
+
+// Handle updates the dashboard from a build event. Subscribe the dashboard
+// to the orchestrator's events before running the build.
+func (d *Dashboard) Handle(event events.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if event.Summary != nil {
+		d.usage = *event.Summary
+	}
+
+	task := d.byID[event.TaskID]
+	switch event.Type {
+	case events.PlanCreated:
+		for _, planned := range event.Tasks {
+			view := &taskView{id: planned.ID, agent: planned.Agent, description: planned.Description, status: "pending"}
+			d.tasks = append(d.tasks, view)
+			d.byID[planned.ID] = view
+		}
+		d.status = "Running tasks"
+	case events.TaskStarted:
+		if task != nil {
+			task.status, task.started, task.finished, task.err = "running", event.Time, time.Time{}, ""
+			task.output.Reset()
+			d.selectTask(task)
+		}
+	case events.TaskOutput:
+		if task != nil {
+			task.output.WriteString(event.Delta)
+		}
+	case events.TaskCompleted:
+		if task != nil {
+			task.status, task.finished = "completed", event.Time
+		}
+		d.addFiles(event.Files)
+	case events.TaskSkipped:
+		if task != nil {
+			task.status, task.finished = "skipped", event.Time
+		}
+	case events.TaskFailed:
+		if task != nil {
+			task.status, task.finished, task.err = "failed", event.Time, event.Error
+		}
+	case events.FileWritten:
+		d.addFiles([]string{event.Path})
+	case events.Warning:
+		d.status = "Warning: " + event.Message
+	case events.Error:
+		if event.Path == "" {
+			d.status = "Error: " + event.Error
+		}
+	case events.Summary:
+		d.status = "Build finished"
+	}
+}
+
+// selectTask follows the running task unless another one was picked by hand
+func (d *Dashboard) selectTask(task *taskView) {
+	if !d.follow {
+		return
+	}
+	for i, view := range d.tasks {
+		if view == task {
+			d.selected = i
+			return
+		}
+	}
+}
+
+// addFiles records written files once each
+func (d *Dashboard) addFiles(files []string) {
+	for _, file := range files {
+		if !d.seen[file] {
+			d.seen[file] = true
+			d.files = append(d.files, file)
+		}
+	}
+}
+
+// Run takes over the terminal and runs build while drawing the dashboard.
+// When the build ends it waits for q, so the results can be browsed, and
+// returns the build's error.
+func (d *Dashboard) Run(build func() error) error {
+	fd := int(d.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return build()
+	}
+	restore := ui.Silence()
+	fmt.Fprint(d.out, "\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Fprint(d.out, "\033[?25h\033[?1049l")
+		term.Restore(fd, state)
+		restore()
+	}()
+
+	keys := make(chan byte)
+	go d.readKeys(keys)
+
+	result := make(chan error, 1)
+	go func() { result <- build() }()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	var buildErr error
+	running := true
+	for {
+		d.render()
+		select {
+		case buildErr = <-result:
+			running = false
+			d.finish(buildErr)
+		case key, ok := <-keys:
+			if !ok || d.handleKey(key, running) {
+				if !running {
+					return buildErr
+				}
+				// Quitting mid-build stops it; exit once it has wound down
+				d.controls.Stop()
+				d.setStatus("Stopping")
+				d.render()
+				return <-result
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// finish shows the outcome of the build
+func (d *Dashboard) finish(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.done = true
+	if err != nil {
+		d.status = "Build failed: " + err.Error()
+	} else {
+		d.status = "Build finished"
+	}
+}
+
+// setStatus replaces the status line
+func (d *Dashboard) setStatus(status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status = status
+}
+
+// readKeys sends key presses, turning arrow keys into k and j
+func (d *Dashboard) readKeys(keys chan<- byte) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := d.in.Read(buf)
+		if err != nil {
+			return
+		}
+		input := string(buf[:n])
+		switch input {
+		case "\033[A":
+			keys <- 'k'
+		case "\033[B":
+			keys <- 'j'
+		default:
+			for i := 0; i < n; i++ {
+				keys <- buf[i]
+			}
+		}
+	}
+}
+
+// handleKey acts on a key press and reports whether to quit
+func (d *Dashboard) handleKey(key byte, running bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var selected *taskView
+	if d.selected < len(d.tasks) {
+		selected = d.tasks[d.selected]
+	}
+
+	switch key {
+	case 'q', 3: // q or Ctrl-C
+		return true
+	case 'k', 'j':
+		if key == 'k' && d.selected > 0 {
+			d.selected--
+		}
+		if key == 'j' && d.selected < len(d.tasks)-1 {
+			d.selected++
+		}
+		// Moving onto the running task resumes following the build
+		d.follow = d.selected < len(d.tasks) && d.tasks[d.selected].status == "running"
+	case 'p':
+		if running {
+			if d.controls.TogglePause() {
+				d.status = "Paused - press p to resume"
+			} else {
+				d.status = "Running tasks"
+			}
+		}
+	case 's':
+		if running && selected != nil && (selected.status == "pending" || selected.status == "running") {
+			d.controls.Skip(selected.id)
+			if selected.status == "pending" {
+				selected.status = "skip"
+			}
+		}
+	case 'r':
+		if running && selected != nil && selected.status != "pending" {
+			d.controls.Retry(selected.id)
+			if selected.status != "running" {
+				selected.status = "pending"
+			}
+		}
+	}
+	return false
+}
+
+// render draws the whole screen
+func (d *Dashboard) render() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width < 40 || height < 10 {
+		width, height = 80, 24
+	}
+
+	leftWidth := width * 2 / 5
+	if leftWidth < 36 {
+		leftWidth = 36
+	}
+	rightWidth := width - leftWidth - 3
+	bodyHeight := height - 5
+
+	left := d.leftPane(leftWidth, bodyHeight)
+	right := d.outputPane(rightWidth, bodyHeight)
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+
+	header := " go-code build: " + d.title
+	clock := formatElapsed(time.Since(d.started)) + " "
+	if d.controls.Paused() {
+		clock = "PAUSED  " + clock
+	}
+	writeLine(&b, bold(pad(header, width-len(clock))+clock))
+	writeLine(&b, strings.Repeat("─", width))
+
+	for i := 0; i < bodyHeight; i++ {
+		writeLine(&b, left[i]+" │ "+right[i])
+	}
+
+	writeLine(&b, strings.Repeat("─", width))
+	writeLine(&b, pad(d.meter(), width))
+	help := " ↑/↓ select  p pause  s skip  r retry  q quit"
+	if d.done {
+		help = " ↑/↓ select  q exit"
+	}
+	b.WriteString(pad(help+"   "+d.status, width))
+	b.WriteString("\033[J")
+
+	fmt.Fprint(d.out, b.String())
+}
+
+// leftPane lists the tasks and, below them, the files written
+func (d *Dashboard) leftPane(width, height int) []string {
+	lines := []string{bold(pad(" TASKS", width))}
+	if len(d.tasks) == 0 {
+		lines = append(lines, pad("  waiting for the plan…", width))
+	}
+
+	taskRows := height/2 - 1
+	first := 0
+	if d.selected >= taskRows {
+		first = d.selected - taskRows + 1
+	}
+	for i := first; i < len(d.tasks) && i < first+taskRows; i++ {
+		task := d.tasks[i]
+		marker := " "
+		if i == d.selected {
+			marker = "›"
+		}
+		elapsed := ""
+		if !task.started.IsZero() {
+			end := task.finished
+			if end.IsZero() {
+				end = time.Now()
+			}
+			elapsed = formatElapsed(end.Sub(task.started))
+		}
+		row := fmt.Sprintf("%s%s %-8s %s", marker, statusIcon(task.status), task.agent, task.description)
+		row = pad(row, width-len(elapsed)-1) + " " + elapsed
+		if i == d.selected {
+			row = reverse(row)
+		}
+		lines = append(lines, row)
+	}
+
+	lines = append(lines, pad("", width), bold(pad(fmt.Sprintf(" FILES (%d)", len(d.files)), width)))
+	fileRows := height - len(lines)
+	files := d.files
+	if len(files) > fileRows {
+		files = files[len(files)-fileRows:]
+	}
+	for _, file := range files {
+		lines = append(lines, pad("  "+file, width))
+	}
+
+	for len(lines) < height {
+		lines = append(lines, pad("", width))
+	}
+	return lines[:height]
+}
+
+// outputPane shows the tail of the selected task's output
+func (d *Dashboard) outputPane(width, height int) []string {
+	title := " OUTPUT"
+	var content string
+	if d.selected < len(d.tasks) {
+		task := d.tasks[d.selected]
+		title = fmt.Sprintf(" OUTPUT %s (%s)", task.id, task.agent)
+		content = task.output.String()
+		if task.err != "" {
+			content += "\n\nError: " + task.err
+		}
+	}
+
+	var wrapped []string
+	for _, line := range strings.Split(content, "\n") {
+		wrapped = append(wrapped, wrap(line, width)...)
+	}
+	rows := height - 1
+	if len(wrapped) > rows {
+		wrapped = wrapped[len(wrapped)-rows:]
+	}
+
+	lines := []string{bold(pad(title, width))}
+	for _, line := range wrapped {
+		lines = append(lines, pad(line, width))
+	}
+	for len(lines) < height {
+		lines = append(lines, pad("", width))
+	}
+	return lines[:height]
+}
+
+// meter shows the tokens and cost used so far against the budget
+func (d *Dashboard) meter() string {
+	total := d.usage.Total
+	meter := fmt.Sprintf(" Tokens %d (%d prompt, %d completion) • %d requests • %s",
+		total.TotalTokens(), total.PromptTokens, total.CompletionTokens, total.Requests, ui.FormatCost(total.Cost))
+
+	var limits []string
+	if budget := d.usage.Budget; budget.MaxTokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d%% of %d tokens", total.TotalTokens()*100/budget.MaxTokens, budget.MaxTokens))
+	}
+	if budget := d.usage.Budget; budget.MaxCost > 0 {
+		limits = append(limits, fmt.Sprintf("%.0f%% of %s", total.Cost*100/budget.MaxCost, ui.FormatCost(budget.MaxCost)))
+	}
+	if len(limits) > 0 {
+		meter += " • budget: " + strings.Join(limits, ", ")
+	}
+	return meter
+}
+
+// statusIcon is the one-character marker for a task status
+func statusIcon(status string) string {
+	switch status {
+	case "running":
+		return "▶"
+	case "completed":
+		return "✓"
+	case "failed":
+		return "✗"
+	case "skipped", "skip":
+		return "↷"
+	default:
+		return "·"
+	}
+}
+
+// formatElapsed renders a duration as m:ss
+func formatElapsed(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// writeLine writes a screen line and clears whatever was left of the old one
+func writeLine(b *strings.Builder, line string) {
+	b.WriteString(line)
+	b.WriteString("\033[K\r\n")
+}
+
+// pad truncates or pads s with spaces to exactly width runes
+func pad(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(strings.ReplaceAll(s, "\t", "    "))
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// wrap breaks a line into pieces of at most width runes
+func wrap(line string, width int) []string {
+	runes := []rune(strings.ReplaceAll(line, "\t", "    "))
+	if len(runes) == 0 || width <= 0 {
+		return []string{""}
+	}
+	var lines []string
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(lines, string(runes))
+}
+
+// bold and reverse style a line for the terminal
+func bold(s string) string    { return "\033[1m" + s + "\033[0m" }
+func reverse(s string) string { return "\033[7m" + s + "\033[0m" }
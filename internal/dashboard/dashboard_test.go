@@ -0,0 +1,104 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/events"
+	"go-code/internal/orchestrator"
+	"go-code/pkg/models"
+)
+
+func TestDashboardRender(t *testing.T) {
+	var out bytes.Buffer
+	d := New("a todo app", orchestrator.NewControls())
+	d.out = &out
+
+	start := time.Now().Add(-5 * time.Second)
+	events := []events.Event{
+		{Type: events.PlanCreated, Tasks: []events.PlannedTask{
+			{ID: "task_1", Agent: models.BackendAgent, Description: "Create the todo API"},
+			{ID: "task_2", Agent: models.FrontendAgent, Description: "Build the todo page"},
+		}},
+		{Type: events.TaskStarted, Time: start, TaskID: "task_1"},
+		{Type: events.TaskOutput, TaskID: "task_1", Delta: "Here is the "},
+		{Type: events.TaskOutput, TaskID: "task_1", Delta: "router:\nmodule.exports = router;"},
+		{Type: events.FileWritten, Path: "routes/todos.js"},
+		{Type: events.TaskCompleted, Time: start.Add(3 * time.Second), TaskID: "task_1", Files: []string{"routes/todos.js"},
+			Summary: &models.UsageSummary{
+				Total:  models.Usage{Requests: 2, PromptTokens: 900, CompletionTokens: 100, Cost: 0.25},
+				Budget: models.Budget{MaxCost: 1},
+			}},
+		{Type: events.TaskStarted, TaskID: "task_2"},
+	}
+	for _, event := range events {
+		d.Handle(event)
+	}
+
+	// The dashboard follows the running task, so select the finished one
+	d.handleKey('k', true)
+	d.render()
+	screen := out.String()
+
+	for _, want := range []string{
+		"go-code build: a todo app",
+		"✓ backend  Create the todo API",
+		"▶ frontend Build the todo page",
+		"0:03",
+		"FILES (1)",
+		"routes/todos.js",
+		"OUTPUT task_1 (backend)",
+		"Here is the router:",
+		"module.exports = router;",
+		"Tokens 1000 (900 prompt, 100 completion) • 2 requests • $0.25",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen is missing %q", want)
+		}
+	}
+	// The budget may be cut off at the default width of 80 columns
+	if meter := d.meter(); !strings.HasSuffix(meter, "budget: 25% of $1.00") {
+		t.Errorf("meter = %q, want the cost budget", meter)
+	}
+}
+
+func TestDashboardKeys(t *testing.T) {
+	d := New("build", orchestrator.NewControls())
+	d.Handle(events.Event{Type: events.PlanCreated, Tasks: []events.PlannedTask{
+		{ID: "task_1", Agent: models.BackendAgent, Description: "one"},
+		{ID: "task_2", Agent: models.BackendAgent, Description: "two"},
+	}})
+
+	d.handleKey('j', true)
+	d.handleKey('s', true)
+	if got := d.tasks[1].status; got != "skip" {
+		t.Errorf("task_2 status after s = %q, want skip", got)
+	}
+
+	d.handleKey('p', true)
+	if !d.controls.Paused() {
+		t.Error("p did not pause the build")
+	}
+	d.handleKey('p', true)
+	if d.controls.Paused() {
+		t.Error("second p did not resume the build")
+	}
+
+	if !d.handleKey('q', true) || !d.handleKey(3, false) {
+		t.Error("q and Ctrl-C should quit")
+	}
+}
+
+func TestPadAndWrap(t *testing.T) {
+	if got := pad("abcdef", 4); got != "abc…" {
+		t.Errorf("pad truncated = %q", got)
+	}
+	if got := pad("ab", 4); got != "ab  " {
+		t.Errorf("pad padded = %q", got)
+	}
+	if got := wrap("abcdefg", 3); strings.Join(got, "|") != "abc|def|g" {
+		t.Errorf("wrap = %q", got)
+	}
+}
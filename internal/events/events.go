@@ -0,0 +1,203 @@
+// Package events carries what happens during a build from the orchestrator
+// to whoever is interested: the terminal UI, the dashboard, the JSON output,
+// the run-state file and tests. The orchestrator only publishes; rendering
+// and persistence are left to the subscribers.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// Type identifies what an event reports
+type Type string
+
+// Build event types
+const (
+	// BuildStarted opens a build; Message is the build description
+	BuildStarted Type = "build_started"
+	// StageStarted and StageCompleted mark progress through the build's
+	// stages: Stage is step Step of Total
+	StageStarted   Type = "stage_started"
+	StageCompleted Type = "stage_completed"
+	// PlanCreated lists the tasks parsed from the planner's response
+	PlanCreated   Type = "plan"
+	TaskStarted   Type = "task_started"
+	TaskOutput    Type = "task_output"
+	TaskCompleted Type = "task_completed"
+	TaskFailed    Type = "task_failed"
+	TaskSkipped   Type = "task_skipped"
+	FileWritten   Type = "file_written"
+	// TokensUsed reports one model response's usage and the run's total
+	TokensUsed     Type = "tokens_used"
+	BudgetExceeded Type = "budget_exceeded"
+	// AgentResponse carries an agent's answer outside of a task, such as the
+	// security triage of the scan findings
+	AgentResponse Type = "agent_response"
+	Warning       Type = "warning"
+	Error         Type = "error"
+	// Summary closes a successful build
+	Summary Type = "summary"
+)
+
+// Event is something that happened during a build. Only the fields that
+// matter for its type are set.
+type Event struct {
+	Type     Type                 `json:"type"`
+	Time     time.Time            `json:"time"`
+	RunID    string               `json:"run_id,omitempty"`
+	TaskID   string               `json:"task_id,omitempty"`
+	Agent    models.AgentType     `json:"agent,omitempty"`
+	Task     string               `json:"task,omitempty"`
+	Message  string               `json:"message,omitempty"`
+	Stage    string               `json:"stage,omitempty"`
+	Step     int                  `json:"step,omitempty"`
+	Total    int                  `json:"total,omitempty"`
+	Path     string               `json:"path,omitempty"`
+	Delta    string               `json:"delta,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Tasks    []PlannedTask        `json:"tasks,omitempty"`
+	Files    []string             `json:"files,omitempty"`
+	Review   *Review              `json:"review,omitempty"`
+	Tests    *models.TestResult   `json:"tests,omitempty"`
+	Findings []models.Finding     `json:"findings,omitempty"`
+	Report   string               `json:"report,omitempty"`
+	Response *models.Response     `json:"response,omitempty"`
+	Usage    *models.Usage        `json:"usage,omitempty"`
+	Summary  *models.UsageSummary `json:"summary,omitempty"`
+	Duration float64              `json:"duration_seconds,omitempty"`
+}
+
+// PlannedTask is a task as listed in a plan event
+type PlannedTask struct {
+	ID          string           `json:"id"`
+	Agent       models.AgentType `json:"agent"`
+	Description string           `json:"description"`
+	Acceptance  []string         `json:"acceptance,omitempty"`
+}
+
+// Review is the outcome of a task's review loop
+type Review struct {
+	Rounds   int              `json:"rounds"`
+	Passed   bool             `json:"passed"`
+	Findings []models.Finding `json:"findings,omitempty"`
+}
+
+// Subscriber receives published events
+type Subscriber interface {
+	Handle(event Event)
+}
+
+// HandlerFunc lets an ordinary function subscribe to a bus
+type HandlerFunc func(event Event)
+
+// Handle calls f(event)
+func (f HandlerFunc) Handle(event Event) {
+	f(event)
+}
+
+// Bus delivers events to its subscribers, one event at a time and in the
+// order they were published. Subscribers run on the publishing goroutine, so
+// they must not block, publish or subscribe.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []*subscription
+}
+
+// subscription wraps a subscriber so it can be removed even when the
+// subscriber itself isn't comparable, like a HandlerFunc
+type subscription struct {
+	Subscriber
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe delivers every event published from now on to s, until the
+// returned function is called
+func (b *Bus) Subscribe(s Subscriber) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := &subscription{s}
+	b.subscribers = append(b.subscribers, sub)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, other := range b.subscribers {
+			if other == sub {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish stamps the event with the current time, unless it has one, and
+// hands it to every subscriber
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.subscribers {
+		s.Handle(event)
+	}
+}
+
+// Recorder keeps every event it receives, for tests and embedders that want
+// to inspect a build afterwards
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Handle keeps the event
+func (r *Recorder) Handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the events received so far, optionally only those of the
+// given types
+func (r *Recorder) Events(types ...Type) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []Event
+	for _, event := range r.events {
+		if len(types) == 0 || containsType(types, event.Type) {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+// Types returns the type of every event received so far, in order
+func (r *Recorder) Types() []Type {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]Type, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+// containsType reports whether t is one of types
+func containsType(types []Type, t Type) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
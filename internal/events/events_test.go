@@ -0,0 +1,132 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+func TestBusDeliversInOrder(t *testing.T) {
+	bus := NewBus()
+	first, second := &Recorder{}, &Recorder{}
+	bus.Subscribe(first)
+	unsubscribe := bus.Subscribe(second)
+
+	bus.Publish(Event{Type: BuildStarted})
+	bus.Publish(Event{Type: PlanCreated})
+	unsubscribe()
+	bus.Publish(Event{Type: Summary})
+
+	if got := first.Types(); len(got) != 3 || got[0] != BuildStarted || got[2] != Summary {
+		t.Errorf("first subscriber got %v", got)
+	}
+	if got := second.Types(); len(got) != 2 {
+		t.Errorf("unsubscribed subscriber got %v, want the first two events", got)
+	}
+	if first.Events()[0].Time.IsZero() {
+		t.Error("Publish did not stamp the event time")
+	}
+
+	var calls int
+	unsubscribe = bus.Subscribe(HandlerFunc(func(Event) { calls++ }))
+	bus.Publish(Event{Type: Warning})
+	unsubscribe()
+	bus.Publish(Event{Type: Warning})
+	if calls != 1 {
+		t.Errorf("HandlerFunc called %d times, want 1", calls)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var lines bytes.Buffer
+	ndjson := NewJSONLogger(&lines, false)
+	ndjson.Handle(Event{Type: TaskStarted, TaskID: "task_1"})
+	ndjson.Handle(Event{Type: TaskCompleted, TaskID: "task_1"})
+	if err := ndjson.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	var types []Type
+	scanner := bufio.NewScanner(&lines)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line is not an event: %v", err)
+		}
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 || types[1] != TaskCompleted {
+		t.Errorf("ndjson events = %v", types)
+	}
+
+	var array bytes.Buffer
+	logger := NewJSONLogger(&array, true)
+	logger.Handle(Event{Type: Summary})
+	if array.Len() != 0 {
+		t.Error("array logger wrote before Flush")
+	}
+	logger.Flush()
+	var events []Event
+	if err := json.Unmarshal(array.Bytes(), &events); err != nil || len(events) != 1 {
+		t.Errorf("array = %s (%v)", array.String(), err)
+	}
+
+	var empty bytes.Buffer
+	NewJSONLogger(&empty, true).Flush()
+	if got := empty.String(); got != "[]\n" {
+		t.Errorf("empty array = %q", got)
+	}
+}
+
+func TestStatePersister(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "state.json")
+	p := NewStatePersister(path)
+	start := time.Now()
+
+	for _, event := range []Event{
+		{Type: BuildStarted, Time: start, RunID: "run1", Message: "a todo app"},
+		{Type: PlanCreated, Tasks: []PlannedTask{
+			{ID: "task_1", Agent: models.BackendAgent, Description: "API"},
+			{ID: "task_2", Agent: models.FrontendAgent, Description: "Page"},
+		}},
+		{Type: TaskStarted, TaskID: "task_1"},
+		{Type: TaskOutput, TaskID: "task_1", Delta: "ignored"},
+		{Type: FileWritten, Path: "server.js"},
+		{Type: FileWritten, Path: "server.js"},
+		{Type: TaskCompleted, TaskID: "task_1", Files: []string{"server.js"}, Usage: &models.Usage{Requests: 1}},
+		{Type: TaskFailed, TaskID: "task_2", Error: "timeout"},
+		{Type: Error, Path: "bad.js", Error: "permission denied"},
+	} {
+		p.Handle(event)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("saving state: %v", err)
+	}
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if state.RunID != "run1" || state.Description != "a todo app" || state.Status != RunRunning || !state.StartedAt.Equal(start) {
+		t.Errorf("state = %+v", state)
+	}
+	if len(state.Files) != 1 {
+		t.Errorf("files = %v, want server.js once", state.Files)
+	}
+	if task := state.Tasks[0]; task.Status != "completed" || len(task.Files) != 1 || task.Usage == nil {
+		t.Errorf("task_1 = %+v", task)
+	}
+	if task := state.Tasks[1]; task.Status != "failed" || task.Error != "timeout" {
+		t.Errorf("task_2 = %+v", task)
+	}
+
+	p.Handle(Event{Type: Error, Error: "build stopped"})
+	if state := p.State(); state.Status != RunFailed || state.Error != "build stopped" {
+		t.Errorf("after a build error, status = %s (%s)", state.Status, state.Error)
+	}
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// JSONLogger writes events as JSON, either one object per line as they
+// arrive or as a single array when flushed
+type JSONLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	array  bool
+	events []Event
+	err    error
+}
+
+// NewJSONLogger creates a logger writing NDJSON to w. With array set the
+// events are kept and written as one indented array by Flush instead.
+func NewJSONLogger(w io.Writer, array bool) *JSONLogger {
+	return &JSONLogger{w: w, array: array}
+}
+
+// Handle writes or keeps the event
+func (l *JSONLogger) Handle(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.array {
+		l.events = append(l.events, event)
+		return
+	}
+	data, err := json.Marshal(event)
+	if err == nil {
+		_, err = l.w.Write(append(data, '\n'))
+	}
+	if err != nil && l.err == nil {
+		l.err = err
+	}
+}
+
+// Flush writes the kept events as one array and returns the first error the
+// logger ran into
+func (l *JSONLogger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.array {
+		events := l.events
+		if events == nil {
+			events = []Event{}
+		}
+		data, err := json.MarshalIndent(events, "", "  ")
+		if err == nil {
+			_, err = l.w.Write(append(data, '\n'))
+		}
+		if err != nil && l.err == nil {
+			l.err = err
+		}
+		l.events = nil
+	}
+	return l.err
+}
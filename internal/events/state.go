@@ -0,0 +1,201 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// Run statuses kept in RunState.Status
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+// RunState is a snapshot of a build as told by its events
+type RunState struct {
+	RunID       string               `json:"run_id"`
+	Description string               `json:"description"`
+	Status      string               `json:"status"`
+	Error       string               `json:"error,omitempty"`
+	StartedAt   time.Time            `json:"started_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Tasks       []TaskState          `json:"tasks"`
+	Files       []string             `json:"files"`
+	Usage       *models.UsageSummary `json:"usage,omitempty"`
+}
+
+// TaskState is the state of one planned task
+type TaskState struct {
+	ID          string             `json:"id"`
+	Agent       models.AgentType   `json:"agent"`
+	Description string             `json:"description"`
+	Status      string             `json:"status"`
+	Error       string             `json:"error,omitempty"`
+	Files       []string           `json:"files,omitempty"`
+	Usage       *models.Usage      `json:"usage,omitempty"`
+	Acceptance  []string           `json:"acceptance,omitempty"`
+	Tests       *models.TestResult `json:"tests,omitempty"`
+}
+
+// StatePersister keeps a RunState up to date in a JSON file, so a crashed or
+// detached build can still be inspected
+type StatePersister struct {
+	mu    sync.Mutex
+	path  string
+	state RunState
+	err   error
+}
+
+// NewStatePersister creates a persister that writes the run state to path
+func NewStatePersister(path string) *StatePersister {
+	return &StatePersister{path: path, state: RunState{Tasks: []TaskState{}, Files: []string{}}}
+}
+
+// Handle applies the event to the run state and saves it
+func (p *StatePersister) Handle(event Event) {
+	// Streamed output is not part of the state and arrives too often to save
+	if event.Type == TaskOutput {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.apply(event)
+	if err := p.save(); err != nil && p.err == nil {
+		p.err = err
+	}
+}
+
+// State returns a copy of the current run state
+func (p *StatePersister) State() RunState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state
+	state.Tasks = append([]TaskState(nil), p.state.Tasks...)
+	state.Files = append([]string(nil), p.state.Files...)
+	return state
+}
+
+// Err returns the first error saving the state ran into
+func (p *StatePersister) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// apply updates the state from an event
+func (p *StatePersister) apply(event Event) {
+	state := &p.state
+	state.UpdatedAt = event.Time
+	if event.RunID != "" {
+		state.RunID = event.RunID
+	}
+	if event.Summary != nil {
+		summary := *event.Summary
+		state.Usage = &summary
+	}
+
+	task := p.task(event.TaskID)
+	switch event.Type {
+	case BuildStarted:
+		state.Description = event.Message
+		state.Status = RunRunning
+		state.StartedAt = event.Time
+	case PlanCreated:
+		for _, planned := range event.Tasks {
+			state.Tasks = append(state.Tasks, TaskState{ID: planned.ID, Agent: planned.Agent, Description: planned.Description, Status: "pending", Acceptance: planned.Acceptance})
+		}
+	case TaskStarted:
+		if task != nil {
+			task.Status, task.Error = "running", ""
+		}
+	case TaskCompleted:
+		if task != nil {
+			task.Status, task.Files, task.Usage, task.Tests = "completed", event.Files, event.Usage, event.Tests
+		}
+	case TaskFailed:
+		if task != nil {
+			task.Status, task.Error, task.Tests = "failed", event.Error, event.Tests
+		}
+	case TaskSkipped:
+		if task != nil {
+			task.Status = "skipped"
+		}
+	case FileWritten:
+		p.addFile(event.Path)
+	case Error:
+		// Errors about a single file don't end the build
+		if event.Path == "" {
+			state.Status, state.Error = RunFailed, event.Error
+		}
+	case Summary:
+		state.Status = RunCompleted
+	}
+}
+
+// task finds a planned task by ID
+func (p *StatePersister) task(id string) *TaskState {
+	if id == "" {
+		return nil
+	}
+	for i := range p.state.Tasks {
+		if p.state.Tasks[i].ID == id {
+			return &p.state.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// addFile records a written file once
+func (p *StatePersister) addFile(path string) {
+	for _, file := range p.state.Files {
+		if file == path {
+			return
+		}
+	}
+	p.state.Files = append(p.state.Files, path)
+}
+
+// save writes the state through a temporary file, so readers never see a
+// partial one
+func (p *StatePersister) save() error {
+	data, err := json.MarshalIndent(p.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("failed to create run state directory: %w", err)
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+	return nil
+}
+
+// LoadState reads a run state saved by a StatePersister
+func LoadState(path string) (*RunState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+
+	var state RunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse run state: %w", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,232 @@
+package filewriter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const manifestFile = "manifest.json"
+
+// RunManifest describes a single build run and every file it touched
+type RunManifest struct {
+	RunID       string         `json:"run_id"`
+	Description string         `json:"description"`
+	ProjectRoot string         `json:"project_root"`
+	StartedAt   time.Time      `json:"started_at"`
+	UndoneAt    *time.Time     `json:"undone_at,omitempty"`
+	Entries     []JournalEntry `json:"entries"`
+}
+
+// JournalEntry records the state of a file before the run first touched it
+type JournalEntry struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Backup  string      `json:"backup,omitempty"`
+}
+
+// Journal keeps the previous version of each file written during a run
+type Journal struct {
+	mu       sync.Mutex
+	dir      string
+	manifest RunManifest
+	recorded map[string]bool
+}
+
+// DefaultJournalDir returns the directory where run journals are stored
+func DefaultJournalDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-code", "runs")
+}
+
+// NewRunID generates a sortable, unique identifier for a run
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// NewJournal creates the journal directory for a run and writes its manifest
+func NewJournal(baseDir, runID, projectRoot, description string) (*Journal, error) {
+	dir := filepath.Join(baseDir, runID)
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	journal := &Journal{
+		dir: dir,
+		manifest: RunManifest{
+			RunID:       runID,
+			Description: description,
+			ProjectRoot: projectRoot,
+			StartedAt:   time.Now(),
+		},
+		recorded: make(map[string]bool),
+	}
+
+	if err := journal.save(); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// RunID returns the identifier of the run this journal belongs to
+func (j *Journal) RunID() string {
+	return j.manifest.RunID
+}
+
+// Record backs up the current version of a file the first time the run touches it
+func (j *Journal) Record(relativePath string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	relativePath = filepath.Clean(relativePath)
+	if j.recorded[relativePath] {
+		return nil
+	}
+
+	fullPath := filepath.Join(j.manifest.ProjectRoot, relativePath)
+	entry := JournalEntry{Path: relativePath}
+
+	info, err := os.Stat(fullPath)
+	switch {
+	case os.IsNotExist(err):
+		// Nothing to back up, undo will remove the file
+	case err != nil:
+		return err
+	default:
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return err
+		}
+		entry.Existed = true
+		entry.Mode = info.Mode().Perm()
+		entry.Backup = fmt.Sprintf("%04d", len(j.manifest.Entries))
+		if err := writeFileAtomic(filepath.Join(j.dir, "files", entry.Backup), data, 0600); err != nil {
+			return err
+		}
+	}
+
+	j.manifest.Entries = append(j.manifest.Entries, entry)
+	j.recorded[relativePath] = true
+
+	// Persist after every entry so a crash mid-run can still be undone
+	return j.save()
+}
+
+// save writes the manifest to the journal directory
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(j.dir, manifestFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// LoadRun reads the manifest of a previous run
+func LoadRun(baseDir, runID string) (*RunManifest, error) {
+	data, err := os.ReadFile(filepath.Join(baseDir, runID, manifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %s not found", runID)
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var manifest RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ListRuns returns all recorded runs, most recent first
+func ListRuns(baseDir string) ([]*RunManifest, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal directory: %w", err)
+	}
+
+	var runs []*RunManifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := LoadRun(baseDir, entry.Name())
+		if err != nil {
+			continue // Not a journal, or a damaged one
+		}
+		runs = append(runs, manifest)
+	}
+
+	sort.Slice(runs, func(i, k int) bool {
+		return runs[i].StartedAt.After(runs[k].StartedAt)
+	})
+	return runs, nil
+}
+
+// Undo restores every file touched by a run to its state before the run.
+// It returns the relative paths that were restored or removed.
+func Undo(baseDir, runID string) ([]string, error) {
+	manifest, err := LoadRun(baseDir, runID)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.UndoneAt != nil {
+		return nil, fmt.Errorf("run %s was already undone at %s", runID, manifest.UndoneAt.Format(time.RFC3339))
+	}
+
+	dir := filepath.Join(baseDir, runID)
+	var changed []string
+
+	// Walk backwards so the tree is unwound in the reverse order it was built
+	for i := len(manifest.Entries) - 1; i >= 0; i-- {
+		entry := manifest.Entries[i]
+		fullPath := filepath.Join(manifest.ProjectRoot, entry.Path)
+
+		if !entry.Existed {
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				return changed, fmt.Errorf("failed to remove %s: %w", fullPath, err)
+			}
+			changed = append(changed, entry.Path)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "files", entry.Backup))
+		if err != nil {
+			return changed, fmt.Errorf("failed to read backup of %s: %w", entry.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return changed, fmt.Errorf("failed to create directory for %s: %w", fullPath, err)
+		}
+		if err := writeFileAtomic(fullPath, data, entry.Mode); err != nil {
+			return changed, fmt.Errorf("failed to restore %s: %w", fullPath, err)
+		}
+		changed = append(changed, entry.Path)
+	}
+
+	now := time.Now()
+	manifest.UndoneAt = &now
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return changed, fmt.Errorf("failed to marshal journal: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, manifestFile), data, 0600); err != nil {
+		return changed, fmt.Errorf("failed to update journal: %w", err)
+	}
+
+	return changed, nil
+}
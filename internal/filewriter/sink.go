@@ -0,0 +1,150 @@
+package filewriter
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DirectorySink writes files into a directory on disk
+type DirectorySink struct {
+	root string
+}
+
+// NewDirectorySink creates a sink writing below root
+func NewDirectorySink(root string) *DirectorySink {
+	return &DirectorySink{root: root}
+}
+
+// Root returns the directory files are written into
+func (d *DirectorySink) Root() string {
+	return d.root
+}
+
+// WriteFile writes data to root/relativePath, creating directories as needed.
+// An existing file keeps its mode.
+func (d *DirectorySink) WriteFile(relativePath string, data []byte) error {
+	fullPath := filepath.Join(d.root, filepath.FromSlash(relativePath))
+
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil && info.Mode().IsRegular() {
+		perm = info.Mode().Perm()
+	}
+	if err := writeFileAtomic(fullPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
+	}
+	return nil
+}
+
+// ReadFile reads root/relativePath
+func (d *DirectorySink) ReadFile(relativePath string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.root, filepath.FromSlash(relativePath)))
+}
+
+// MemorySink keeps files in memory
+type MemorySink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	paths []string
+}
+
+// NewMemorySink creates an empty in-memory sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: make(map[string][]byte)}
+}
+
+// WriteFile stores a copy of data under relativePath
+func (m *MemorySink) WriteFile(relativePath string, data []byte) error {
+	key := cleanPath(relativePath)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.files[key]; !exists {
+		m.paths = append(m.paths, key)
+	}
+	m.files[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// ReadFile returns the content stored under relativePath
+func (m *MemorySink) ReadFile(relativePath string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[cleanPath(relativePath)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", relativePath, os.ErrNotExist)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Paths returns the stored paths in the order they were first written
+func (m *MemorySink) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.paths...)
+}
+
+// Files returns a copy of every stored file by path
+func (m *MemorySink) Files() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make(map[string][]byte, len(m.files))
+	for key, data := range m.files {
+		files[key] = append([]byte(nil), data...)
+	}
+	return files
+}
+
+// TarballSink collects files in memory and writes them to w as a gzipped
+// tarball on Close, so each file appears once with its final content
+type TarballSink struct {
+	*MemorySink
+	w io.Writer
+}
+
+// NewTarballSink creates a sink that writes a .tar.gz to w when closed
+func NewTarballSink(w io.Writer) *TarballSink {
+	return &TarballSink{MemorySink: NewMemorySink(), w: w}
+}
+
+// Close writes the tarball. It does not close the underlying writer.
+func (t *TarballSink) Close() error {
+	gz := gzip.NewWriter(t.w)
+	tw := tar.NewWriter(gz)
+
+	files := t.Files()
+	now := time.Now()
+	for _, name := range t.Paths() {
+		data := files[name]
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tarball: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write tarball: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write tarball: %w", err)
+	}
+	return gz.Close()
+}
+
+// cleanPath normalizes a relative path to the slash-separated form used as
+// a key by the in-memory sinks
+func cleanPath(relativePath string) string {
+	return path.Clean(filepath.ToSlash(relativePath))
+}
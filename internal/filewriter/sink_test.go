@@ -0,0 +1,87 @@
+package filewriter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectorySink(t *testing.T) {
+	root := t.TempDir()
+	sink := NewDirectorySink(root)
+
+	if err := sink.WriteFile("src/app.js", []byte("console.log(1)")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "src", "app.js"))
+	if err != nil || string(data) != "console.log(1)" {
+		t.Errorf("file on disk = %q, %v", data, err)
+	}
+	if data, err := sink.ReadFile("src/app.js"); err != nil || string(data) != "console.log(1)" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	sink := NewMemorySink()
+	sink.WriteFile("b.txt", []byte("one"))
+	sink.WriteFile("./a.txt", []byte("two"))
+	sink.WriteFile("b.txt", []byte("three"))
+
+	if got := sink.Paths(); len(got) != 2 || got[0] != "b.txt" || got[1] != "a.txt" {
+		t.Errorf("Paths = %v, want [b.txt a.txt]", got)
+	}
+	if data, err := sink.ReadFile("b.txt"); err != nil || string(data) != "three" {
+		t.Errorf("ReadFile(b.txt) = %q, %v", data, err)
+	}
+	if _, err := sink.ReadFile("missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile(missing.txt) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestTarballSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewTarballSink(&buf)
+	sink.WriteFile("README.md", []byte("# draft"))
+	sink.WriteFile("src/index.js", []byte("module.exports = {};"))
+	sink.WriteFile("README.md", []byte("# final"))
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files := readTarball(t, &buf)
+	if len(files) != 2 || files["README.md"] != "# final" || files["src/index.js"] != "module.exports = {};" {
+		t.Errorf("tarball = %v", files)
+	}
+}
+
+// readTarball returns the files of a gzipped tarball by name
+func readTarball(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+}
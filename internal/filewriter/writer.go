@@ -6,38 +6,125 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"go-code/pkg/models"
 )
 
-// FileWriter handles writing files to the filesystem
+// FileWriter handles writing generated files to a sink
 type FileWriter struct {
+	sink        models.FileSink
 	projectRoot string
+	journal     *Journal
+	written     []string
+	seen        map[string]bool
 }
 
-// New creates a new FileWriter
+// New creates a new FileWriter writing into the projectRoot directory
 func New(projectRoot string) *FileWriter {
-	return &FileWriter{
-		projectRoot: projectRoot,
+	return NewWithSink(NewDirectorySink(projectRoot))
+}
+
+// NewWithSink creates a FileWriter that writes into sink
+func NewWithSink(sink models.FileSink) *FileWriter {
+	fw := &FileWriter{
+		sink: sink,
+		seen: make(map[string]bool),
 	}
+	if dir, ok := sink.(*DirectorySink); ok {
+		fw.projectRoot = dir.Root()
+	}
+	return fw
 }
 
-// WriteFile writes content to a file with proper directory structure
+// SetJournal attaches a journal that records the previous version of every
+// file before it is overwritten
+func (fw *FileWriter) SetJournal(journal *Journal) {
+	fw.journal = journal
+}
+
+// ProjectRoot returns the directory files are written into, or "" when the
+// sink is not a directory
+func (fw *FileWriter) ProjectRoot() string {
+	return fw.projectRoot
+}
+
+// Sink returns the sink files are written into
+func (fw *FileWriter) Sink() models.FileSink {
+	return fw.sink
+}
+
+// ReadFile returns the current content of a file in the sink
+func (fw *FileWriter) ReadFile(relativePath string) ([]byte, error) {
+	return fw.sink.ReadFile(relativePath)
+}
+
+// WrittenFiles returns the relative paths of every file written so far, in
+// the order they were first written
+func (fw *FileWriter) WrittenFiles() []string {
+	return append([]string(nil), fw.written...)
+}
+
+// WriteFile writes content to a file in the sink
 func (fw *FileWriter) WriteFile(relativePath, content string) error {
-	fullPath := filepath.Join(fw.projectRoot, relativePath)
+	// Keep the previous version so the run can be undone
+	if fw.journal != nil {
+		if err := fw.journal.Record(relativePath); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", relativePath, err)
+		}
+	}
 	
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	if err := fw.sink.WriteFile(relativePath, []byte(content)); err != nil {
+		return err
 	}
 	
-	// Write file
-	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
+	if !fw.seen[relativePath] {
+		fw.seen[relativePath] = true
+		fw.written = append(fw.written, relativePath)
 	}
 	
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file in the target directory and
+// renames it into place, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temp file on any failure before the rename
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
 // WritePackageJSON creates a package.json file
 func (fw *FileWriter) WritePackageJSON(projectName string, dependencies map[string]string) error {
 	content := fmt.Sprintf(`{
@@ -187,17 +274,28 @@ func (fw *FileWriter) inferFilename(code string, index int) string {
 	return fmt.Sprintf("generated/generated%d.js", index)
 }
 
-// CreateProjectStructure creates a basic project structure
-func (fw *FileWriter) CreateProjectStructure() error {
-	dirs := []string{
-		"src",
-		"models",
-		"routes",
-		"controllers",
-		"middleware",
-		"config",
-		"utils",
-		"tests",
+// defaultDirs start a project whose stack is unknown
+var defaultDirs = []string{
+	"src",
+	"models",
+	"routes",
+	"controllers",
+	"middleware",
+	"config",
+	"utils",
+	"tests",
+}
+
+// CreateProjectStructure creates dirs, or a basic project structure if dirs
+// is empty. Sinks that are not directories have no empty directories, so
+// there is nothing to create.
+func (fw *FileWriter) CreateProjectStructure(dirs []string) error {
+	if fw.projectRoot == "" {
+		return nil
+	}
+	
+	if len(dirs) == 0 {
+		dirs = defaultDirs
 	}
 	
 	for _, dir := range dirs {
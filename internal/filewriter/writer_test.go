@@ -0,0 +1,177 @@
+package filewriter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     map[string]string
+	}{
+		{
+			name:     "filename comment",
+			response: "Here is the server:\n\n```javascript\n// filename: src/server.js\nconst express = require('express');\n```\n\nRun it with node.",
+			want:     map[string]string{"src/server.js": "// filename: src/server.js\nconst express = require('express');"},
+		},
+		{
+			name:     "html comment",
+			response: "```html\n<!-- public/index.html -->\n<h1>Hello</h1>\n```",
+			want:     map[string]string{"public/index.html": "<!-- public/index.html -->\n<h1>Hello</h1>"},
+		},
+		{
+			name:     "hash comment",
+			response: "```python\n# filename: app/main.py\nprint('hi')\n```",
+			want:     map[string]string{"app/main.py": "# filename: app/main.py\nprint('hi')"},
+		},
+		{
+			name: "several blocks",
+			response: "First the routes.\n\n```js\n// routes/users.js\nmodule.exports = router;\n```\n\n" +
+				"Then the model.\n\n```js\n// models/User.js\nmodule.exports = User;\n```",
+			want: map[string]string{
+				"routes/users.js": "// routes/users.js\nmodule.exports = router;",
+				"models/User.js":  "// models/User.js\nmodule.exports = User;",
+			},
+		},
+		{
+			name:     "inferred package.json",
+			response: "```json\n{\n  \"name\": \"app\",\n  \"dependencies\": {}\n}\n```",
+			want:     map[string]string{"package.json": "{\n  \"name\": \"app\",\n  \"dependencies\": {}\n}"},
+		},
+		{
+			name:     "inferred schema",
+			response: "```sql\nCREATE TABLE users (id SERIAL PRIMARY KEY);\n```",
+			want:     map[string]string{"database/schema.sql": "CREATE TABLE users (id SERIAL PRIMARY KEY);"},
+		},
+		{
+			name:     "inferred express app",
+			response: "```js\nconst express = require('express');\nconst app = express();\napp.listen(3000);\n```",
+			want:     map[string]string{"app.js": "const express = require('express');\nconst app = express();\napp.listen(3000);"},
+		},
+		{
+			name:     "unnamed fallback",
+			response: "```\necho hello\n```",
+			want:     map[string]string{"generated/generated0.js": "echo hello"},
+		},
+		{
+			name:     "empty block skipped",
+			response: "```js\n\n```\n\n```\n// a.js\nx\n```",
+			want:     map[string]string{"a.js": "// a.js\nx"},
+		},
+		{
+			name:     "no blocks",
+			response: "Nothing to write here, just advice.",
+			want:     map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(t.TempDir()).ExtractCodeBlocks(tt.response)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d blocks %v, want %d", len(got), got, len(tt.want))
+			}
+			for filename, code := range tt.want {
+				if got[filename] != code {
+					t.Errorf("%s = %q, want %q", filename, got[filename], code)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	root := t.TempDir()
+	fw := New(root)
+
+	for _, path := range []string{"src/app.js", "README.md", "src/app.js"} {
+		if err := fw.WriteFile(path, "content of "+path); err != nil {
+			t.Fatalf("WriteFile(%s): %v", path, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "src", "app.js"))
+	if err != nil {
+		t.Fatalf("reading written file: %v", err)
+	}
+	if string(data) != "content of src/app.js" {
+		t.Errorf("file content = %q", data)
+	}
+
+	written := fw.WrittenFiles()
+	if len(written) != 2 || written[0] != "src/app.js" || written[1] != "README.md" {
+		t.Errorf("WrittenFiles() = %v, want [src/app.js README.md]", written)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	root := t.TempDir()
+	journalDir := t.TempDir()
+
+	script := filepath.Join(root, "run.sh")
+	secret := filepath.Join(root, "secret.env")
+	if err := os.WriteFile(script, []byte("echo old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("KEY=old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, err := NewJournal(journalDir, "run-1", root, "test run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw := New(root)
+	fw.SetJournal(journal)
+	for path, content := range map[string]string{
+		"run.sh":     "echo new",
+		"secret.env": "KEY=new",
+		"src/app.js": "console.log(1)",
+	} {
+		if err := fw.WriteFile(path, content); err != nil {
+			t.Fatalf("WriteFile(%s): %v", path, err)
+		}
+	}
+
+	// Overwriting keeps the existing modes
+	checkFile(t, script, "echo new", 0755)
+	checkFile(t, secret, "KEY=new", 0600)
+
+	changed, err := Undo(journalDir, "run-1")
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if len(changed) != 3 {
+		t.Errorf("Undo changed %v, want 3 files", changed)
+	}
+	checkFile(t, script, "echo old", 0755)
+	checkFile(t, secret, "KEY=old", 0600)
+	if _, err := os.Stat(filepath.Join(root, "src", "app.js")); !os.IsNotExist(err) {
+		t.Errorf("new file still exists after undo: %v", err)
+	}
+
+	if _, err := Undo(journalDir, "run-1"); err == nil {
+		t.Error("a run was undone twice")
+	}
+}
+
+// checkFile fails the test unless path has content and, where modes are
+// supported, mode
+func checkFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != content {
+		t.Errorf("%s = %q, %v, want %q", filepath.Base(path), data, err, content)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != mode {
+		t.Errorf("%s mode = %v, want %v", filepath.Base(path), info.Mode().Perm(), mode)
+	}
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GeneratedDir is where builds outside of a repository get a new one
+const GeneratedDir = "generated-project"
+
+// Repo drives the local git binary for a single working tree
+type Repo struct {
+	root string
+}
+
+// Available reports whether a git binary is on the PATH
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Open returns the repository containing dir
+func Open(dir string) (*Repo, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository: %w", dir, err)
+	}
+	return &Repo{root: out}, nil
+}
+
+// Init creates a new repository in dir, creating the directory if needed
+func Init(dir string) (*Repo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if _, err := run(dir, "init", "--quiet"); err != nil {
+		return nil, fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	return Open(dir)
+}
+
+// OpenForBuild returns the repository a build in dir should commit to.
+// Outside of a repository a new one is initialized in dir/GeneratedDir. A
+// worktree with uncommitted changes is refused unless force is set.
+func OpenForBuild(dir string, force bool) (*Repo, error) {
+	repo, err := Open(dir)
+	if err != nil {
+		return Init(filepath.Join(dir, GeneratedDir))
+	}
+
+	dirty, err := repo.IsDirty()
+	if err != nil {
+		return nil, err
+	}
+	if dirty && !force {
+		return nil, fmt.Errorf("worktree %s has uncommitted changes; commit or stash them, or use --force", repo.Root())
+	}
+	return repo, nil
+}
+
+// Root returns the top-level directory of the working tree
+func (r *Repo) Root() string {
+	return r.root
+}
+
+// IsDirty reports whether the working tree has uncommitted or untracked changes
+func (r *Repo) IsDirty() (bool, error) {
+	out, err := run(r.root, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// CurrentBranch returns the name of the checked out branch
+func (r *Repo) CurrentBranch() (string, error) {
+	return run(r.root, "symbolic-ref", "--short", "HEAD")
+}
+
+// CreateBranch creates a branch at HEAD and checks it out
+func (r *Repo) CreateBranch(name string) error {
+	if _, err := run(r.root, "checkout", "--quiet", "-b", name); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// Commit stages the given paths and commits them. It returns the new commit
+// hash, or an empty string when the paths had no changes to commit.
+func (r *Repo) Commit(message string, paths ...string) (string, error) {
+	if len(paths) == 0 {
+		return "", nil
+	}
+
+	addArgs := append([]string{"add", "--"}, paths...)
+	if _, err := run(r.root, addArgs...); err != nil {
+		return "", fmt.Errorf("failed to stage files: %w", err)
+	}
+
+	// Nothing staged means the task rewrote files with identical content
+	if _, err := run(r.root, "diff", "--cached", "--quiet"); err == nil {
+		return "", nil
+	}
+
+	commitArgs := append(r.identityArgs(), "commit", "--quiet", "-m", message)
+	if _, err := run(r.root, commitArgs...); err != nil {
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return run(r.root, "rev-parse", "--short", "HEAD")
+}
+
+// identityArgs supplies a fallback author when the user has none configured,
+// so commits don't fail on fresh machines and CI runners
+func (r *Repo) identityArgs() []string {
+	if email, err := run(r.root, "config", "user.email"); err == nil && email != "" {
+		return nil
+	}
+	return []string{"-c", "user.name=go-code", "-c", "user.email=go-code@localhost"}
+}
+
+// run executes git in dir and returns its trimmed standard output
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
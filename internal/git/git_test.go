@@ -0,0 +1,132 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tempRepo initializes a repository with one commit in a temp directory
+func tempRepo(t *testing.T) *Repo {
+	t.Helper()
+	if !Available() {
+		t.Skip("git is not installed")
+	}
+	// Keep the user's git configuration out of the tests
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	writeFile(t, repo.Root(), "README.md", "# test")
+	if _, err := repo.Commit("initial", filepath.Join(repo.Root(), "README.md")); err != nil {
+		t.Fatalf("initial commit: %v", err)
+	}
+	return repo
+}
+
+// writeFile writes content to root/name
+func writeFile(t *testing.T, root, name, content string) string {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBranchAndCommit(t *testing.T) {
+	repo := tempRepo(t)
+
+	if err := repo.CreateBranch("go-code/run-1"); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	if branch, err := repo.CurrentBranch(); err != nil || branch != "go-code/run-1" {
+		t.Errorf("CurrentBranch = %q, %v", branch, err)
+	}
+	if err := repo.CreateBranch("go-code/run-1"); err == nil {
+		t.Error("an existing branch was created again")
+	}
+
+	// One commit per task, with only that task's files
+	first := writeFile(t, repo.Root(), "api/server.js", "listen()")
+	second := writeFile(t, repo.Root(), "web/index.html", "<ul></ul>")
+	hash, err := repo.Commit("go-code(backend): Create the API\n\nTask: Create the API", first)
+	if err != nil || hash == "" {
+		t.Fatalf("Commit = %q, %v", hash, err)
+	}
+	if dirty, _ := repo.IsDirty(); !dirty {
+		t.Error("the other task's file was committed too")
+	}
+	if _, err := repo.Commit("go-code(frontend): Build the page", second); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	subjects, err := run(repo.Root(), "log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "go-code(frontend): Build the page\ngo-code(backend): Create the API\ninitial"
+	if subjects != want {
+		t.Errorf("log = %q, want %q", subjects, want)
+	}
+	if files, _ := run(repo.Root(), "show", "--name-only", "--format=", hash); files != "api/server.js" {
+		t.Errorf("first commit has %q, want api/server.js", files)
+	}
+
+	// Rewriting a file with the same content commits nothing
+	writeFile(t, repo.Root(), "api/server.js", "listen()")
+	if hash, err := repo.Commit("unchanged", first); err != nil || hash != "" {
+		t.Errorf("Commit without changes = %q, %v", hash, err)
+	}
+	if hash, err := repo.Commit("no paths"); err != nil || hash != "" {
+		t.Errorf("Commit without paths = %q, %v", hash, err)
+	}
+}
+
+func TestOpenForBuild(t *testing.T) {
+	repo := tempRepo(t)
+	sub := filepath.Join(repo.Root(), "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := OpenForBuild(sub, false)
+	if err != nil {
+		t.Fatalf("OpenForBuild of a clean worktree: %v", err)
+	}
+	if resolved, _ := filepath.EvalSymlinks(repo.Root()); opened.Root() != repo.Root() && opened.Root() != resolved {
+		t.Errorf("Root = %s, want %s", opened.Root(), repo.Root())
+	}
+
+	writeFile(t, repo.Root(), "notes.txt", "uncommitted")
+	if _, err := OpenForBuild(sub, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("OpenForBuild of a dirty worktree = %v, want a refusal", err)
+	}
+	if _, err := OpenForBuild(sub, true); err != nil {
+		t.Errorf("OpenForBuild with force: %v", err)
+	}
+
+	// Outside of a repository the generated project gets its own
+	outside := t.TempDir()
+	if _, err := Open(outside); err == nil {
+		t.Skip("the temp directory is inside a git repository")
+	}
+	created, err := OpenForBuild(outside, false)
+	if err != nil {
+		t.Fatalf("OpenForBuild outside a repository: %v", err)
+	}
+	if filepath.Base(created.Root()) != GeneratedDir {
+		t.Errorf("Root = %s, want a new repository in %s", created.Root(), GeneratedDir)
+	}
+	if _, err := os.Stat(filepath.Join(outside, GeneratedDir, ".git")); err != nil {
+		t.Errorf("no repository in %s: %v", GeneratedDir, err)
+	}
+}
@@ -0,0 +1,259 @@
+// Package llmtest provides an in-process fake of an OpenAI-compatible chat
+// completions API with scriptable responses, for tests that exercise agents,
+// builds and the API client without a real key or network.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+)
+
+// Reply is a scripted response to one chat completion request
+type Reply struct {
+	Content          string
+	ToolCalls        []api.ToolCall // tools the model calls; not streamed
+	Model            string         // defaults to the requested model
+	PromptTokens     int            // defaults to an estimate from the request
+	CompletionTokens int            // defaults to an estimate from Content
+	Status           int            // non-200 statuses return Error as an API error
+	Error            string
+	Header           map[string]string
+}
+
+// Handler computes a reply from a request
+type Handler func(req api.ChatRequest) Reply
+
+// Server is a fake OpenAI-compatible API. Replies come from the queue filled
+// by Enqueue, then from the Handler, in that order. Requests with
+// "stream": true are answered as server-sent events.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	queue    []Reply
+	handler  Handler
+	requests []api.ChatRequest
+	models   []string
+}
+
+// NewServer starts a fake server that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{models: []string{"llama-3.3-70b-versatile", "moonshotai/kimi-k2-instruct", "llama-3.1-8b-instant"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.chatCompletions)
+	mux.HandleFunc("/v1/models", s.listModels)
+	s.Server = httptest.NewServer(mux)
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// BaseURL returns the API root to configure a client or provider with
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// Client returns an API client pointed at the server
+func (s *Server) Client() *api.GroqClient {
+	return api.NewClient("test-key", s.BaseURL())
+}
+
+// Enqueue adds replies that are returned, in order, before the handler is used
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, replies...)
+}
+
+// Handle sets the handler used once the queue is empty
+func (s *Server) Handle(handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Requests returns every chat request received so far
+func (s *Server) Requests() []api.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]api.ChatRequest(nil), s.requests...)
+}
+
+// next records req and picks its reply
+func (s *Server) next(req api.ChatRequest) Reply {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.queue) > 0 {
+		reply := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		return reply
+	}
+	handler := s.handler
+	s.mu.Unlock()
+
+	if handler != nil {
+		return handler(req)
+	}
+	return Reply{Status: http.StatusInternalServerError, Error: "llmtest: no scripted reply for request"}
+}
+
+// chatCompletions serves /v1/chat/completions
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req api.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	reply := s.next(req)
+	for name, value := range reply.Header {
+		w.Header().Set(name, value)
+	}
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		writeError(w, reply.Status, reply.Error)
+		return
+	}
+
+	model := reply.Model
+	if model == "" {
+		model = req.Model
+	}
+	usage := api.Usage{
+		PromptTokens:     reply.PromptTokens,
+		CompletionTokens: reply.CompletionTokens,
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = api.EstimateTokens(req.Messages)
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = len(reply.Content)/4 + 1
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	id := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	if req.Stream {
+		writeStream(w, id, model, reply.Content, usage)
+		return
+	}
+
+	finishReason := "stop"
+	if len(reply.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.ChatResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []api.Choice{{
+			Message:      api.Message{Role: "assistant", Content: reply.Content, ToolCalls: reply.ToolCalls},
+			FinishReason: finishReason,
+		}},
+		Usage: usage,
+	})
+}
+
+// writeStream sends content as server-sent events, a few words per chunk,
+// with usage in the final chunk the way Groq reports it
+func writeStream(w http.ResponseWriter, id, model, content string, usage api.Usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]interface{}) {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for _, piece := range splitChunks(content) {
+		send(map[string]interface{}{
+			"id": id, "object": "chat.completion.chunk", "model": model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"content": piece}}},
+		})
+	}
+	send(map[string]interface{}{
+		"id": id, "object": "chat.completion.chunk", "model": model,
+		"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{}, "finish_reason": "stop"}},
+		"x_groq":  map[string]interface{}{"usage": usage},
+	})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// splitChunks breaks content into pieces of a few words, keeping whitespace
+func splitChunks(content string) []string {
+	var chunks []string
+	for len(content) > 0 {
+		end := 0
+		for words := 0; end < len(content) && words < 3; words++ {
+			next := strings.IndexAny(content[end:], " \n")
+			if next < 0 {
+				end = len(content)
+				break
+			}
+			end += next + 1
+		}
+		chunks = append(chunks, content[:end])
+		content = content[end:]
+	}
+	return chunks
+}
+
+// listModels serves /v1/models
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	var data []api.ModelInfo
+	for _, id := range s.models {
+		data = append(data, api.ModelInfo{ID: id, Object: "model", Active: true, ContextWindow: 131072})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+}
+
+// writeError writes an OpenAI-style error body
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "invalid_request_error", "code": http.StatusText(status)},
+	})
+}
+
+// SystemPrompt returns the system message of a request, if any
+func SystemPrompt(req api.ChatRequest) string {
+	for _, message := range req.Messages {
+		if message.Role == "system" {
+			return message.Content
+		}
+	}
+	return ""
+}
+
+// UserMessage returns the last user message of a request
+func UserMessage(req api.ChatRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by requests on a connection the server has closed
+var ErrClosed = errors.New("mcp: connection closed")
+
+// closeTimeout is how long Close waits for a launched server to exit once
+// its input is closed before killing it
+const closeTimeout = 5 * time.Second
+
+// Client is a connection to an MCP server. Its methods may be called from
+// several goroutines.
+type Client struct {
+	conn  *conn
+	input io.Closer
+	cmd   *exec.Cmd
+	// stderr keeps the end of a launched server's error output
+	stderr *tail
+
+	// Server is how the server introduced itself in Initialize
+	Server Implementation
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *message
+	done    chan struct{}
+	err     error
+}
+
+// NewClient creates a client reading the server's messages from r and
+// writing requests to w. Closing the client closes w.
+func NewClient(r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		conn:    newConn(r, w),
+		input:   w,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Launch starts command as a subprocess speaking MCP over its stdin and
+// stdout, with env added to go-code's environment
+func Launch(command string, args []string, env map[string]string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd.Env = append(cmd.Env, name+"="+env[name])
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tail{max: 4096}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+
+	c := NewClient(stdout, stdin)
+	c.cmd = cmd
+	c.stderr = stderr
+	return c, nil
+}
+
+// Initialize introduces the client and checks the server speaks a protocol
+// revision this package supports. It must be the first request.
+func (c *Client) Initialize(ctx context.Context) error {
+	var result initializeResult
+	params := initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: "go-code", Version: serverVersion},
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	if negotiateVersion(result.ProtocolVersion) != result.ProtocolVersion {
+		return fmt.Errorf("server speaks unsupported protocol revision %q", result.ProtocolVersion)
+	}
+	c.Server = result.ServerInfo
+	return c.conn.notify("notifications/initialized", struct{}{})
+}
+
+// ListTools returns every tool the server offers, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	var params interface{}
+	for {
+		var result struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		params = map[string]string{"cursor": result.NextCursor}
+	}
+}
+
+// CallTool runs a tool with arguments, a JSON object. A tool that fails
+// returns a result with IsError set rather than an error.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	params := map[string]interface{}{"name": name}
+	if len(arguments) > 0 {
+		params["arguments"] = arguments
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Stderr returns the end of a launched server's error output, which often
+// explains why it failed
+func (c *Client) Stderr() string {
+	if c.stderr == nil {
+		return ""
+	}
+	return c.stderr.String()
+}
+
+// Close ends the connection. A launched server is given closeTimeout to exit
+// once its input is closed, then killed.
+func (c *Client) Close() error {
+	err := c.input.Close()
+	if c.cmd == nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+	case <-time.After(closeTimeout):
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return err
+}
+
+// call sends a request and decodes its result into result. When ctx is done
+// first the server is told the request was cancelled.
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	var data json.RawMessage
+	if params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, c.err)
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	answer := make(chan *message, 1)
+	c.pending[string(id)] = answer
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, string(id))
+		c.mu.Unlock()
+	}()
+
+	if err := c.conn.write(&message{ID: id, Method: method, Params: data}); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case msg := <-answer:
+		return c.decode(method, msg, result)
+	case <-c.done:
+		select {
+		case msg := <-answer:
+			return c.decode(method, msg, result)
+		default:
+		}
+		return fmt.Errorf("%s: %w", method, c.err)
+	case <-ctx.Done():
+		c.conn.notify("notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+// decode returns the error of a response or decodes its result into result
+func (c *Client) decode(method string, msg *message, result interface{}) error {
+	if msg.Error != nil {
+		return fmt.Errorf("%s: %w", method, msg.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("%s: invalid result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// readLoop hands responses to the requests waiting for them and answers the
+// server's own requests until the connection ends
+func (c *Client) readLoop() {
+	for {
+		msg, err := c.conn.read()
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = ErrClosed
+			}
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+
+		switch {
+		case msg.Method == "":
+			c.mu.Lock()
+			answer, ok := c.pending[string(msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case answer <- msg:
+				default: // a duplicate response
+				}
+			}
+		case len(msg.ID) > 0:
+			// Answered on another goroutine so a server blocked writing
+			// to us can't hold up its own answer
+			if msg.Method == "ping" {
+				go c.conn.respond(msg.ID, struct{}{}, nil)
+			} else {
+				go c.conn.respond(msg.ID, nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)})
+			}
+		}
+	}
+}
+
+// tail is a writer that keeps the last max bytes written to it
+type tail struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = append(t.data, p...)
+	if len(t.data) > t.max {
+		t.data = t.data[len(t.data)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(string(t.data))
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go-code/internal/api"
+	"go-code/internal/llmtest"
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
+)
+
+// connect starts a go-code MCP server for a team answering with handler and
+// returns an initialized client connected to it
+func connect(t *testing.T, handler llmtest.Handler) *Client {
+	t.Helper()
+	fake := llmtest.NewServer(t)
+	fake.Handle(handler)
+	team, err := gocode.New(gocode.WithClient(fake.Client()), gocode.WithDataDir(t.TempDir()), gocode.WithSecurityScan(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		NewServer(team, t.TempDir()).Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+
+	c := NewClient(outR, inW)
+	t.Cleanup(func() { c.Close() })
+	if err := c.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := connect(t, scriptedTeam)
+	ctx := context.Background()
+
+	if c.Server.Name != "go-code" {
+		t.Errorf("server = %+v", c.Server)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); !strings.Contains(got, "ask_backend") || !strings.Contains(got, "build") {
+		t.Errorf("tools = %s", got)
+	}
+
+	result, err := c.CallTool(ctx, "ask_backend", []byte(`{"message": "Which database?"}`))
+	if err != nil || result.IsError || result.Text() != "Echo: Which database?" {
+		t.Errorf("ask_backend = %+v, %v", result, err)
+	}
+
+	_, err = c.CallTool(ctx, "ask_nobody", []byte(`{"message": "hi"}`))
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("unknown tool error = %v", err)
+	}
+}
+
+func TestClientClosedConnection(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := NewClient(outR, inW)
+	defer c.Close()
+
+	// The server reads the request and hangs up without answering
+	go func() {
+		newConn(inR, io.Discard).read()
+		outW.Close()
+	}()
+
+	if _, err := c.ListTools(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("ListTools = %v, want ErrClosed", err)
+	}
+}
+
+// askConfig allows the backend agent to ask go-code's security agent
+func askConfig() *models.Config {
+	config := models.DefaultConfig()
+	config.MCPServers = map[string]models.MCPServerConfig{
+		"gocode": {Command: "go-code", Args: []string{"mcp"}, Tools: map[string][]models.AgentType{
+			"ask_security": {models.BackendAgent},
+		}},
+	}
+	return config
+}
+
+func TestToolboxAllowListAndPermission(t *testing.T) {
+	config := askConfig()
+	var asked []string
+	allow := false
+	box := NewToolbox(config, func(agentType models.AgentType, server, tool, arguments string) bool {
+		asked = append(asked, string(agentType)+" "+server+"/"+tool+" "+arguments)
+		return allow
+	})
+	if err := box.Add(context.Background(), "gocode", connect(t, scriptedTeam)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if tools := box.ToolsFor(models.PlannerAgent); tools != nil {
+		t.Errorf("planner got tools %+v, want none", tools.Tools())
+	}
+	tools := box.ToolsFor(models.BackendAgent)
+	if tools == nil || len(tools.Tools()) != 1 || tools.Tools()[0].Function.Name != "gocode__ask_security" {
+		t.Fatalf("backend tools = %+v", tools)
+	}
+
+	ctx := context.Background()
+	arguments := `{"message": "Is eval safe?"}`
+	if _, err := tools.Call(ctx, "gocode__ask_security", arguments); err == nil || !strings.Contains(err.Error(), "did not allow") {
+		t.Errorf("refused call = %v", err)
+	}
+	if len(asked) != 1 || asked[0] != "backend gocode/ask_security "+arguments {
+		t.Errorf("asked = %q", asked)
+	}
+
+	allow = true
+	if text, err := tools.Call(ctx, "gocode__ask_security", arguments); err != nil || text != "Echo: Is eval safe?" {
+		t.Errorf("allowed call = %q, %v", text, err)
+	}
+
+	// A session permission skips the prompt
+	config.SessionPermissions[models.MCPPermissionKey("gocode", "ask_security")] = true
+	if _, err := tools.Call(ctx, "gocode__ask_security", arguments); err != nil || len(asked) != 2 {
+		t.Errorf("call with session permission = %v after %d prompts", err, len(asked))
+	}
+
+	if _, err := tools.Call(ctx, "gocode__build", `{}`); err == nil {
+		t.Error("a tool outside the allow-list could be called")
+	}
+}
+
+func TestAgentCallsServerTool(t *testing.T) {
+	config := askConfig()
+	config.RequireCommandPermission = false
+	box := NewToolbox(config, nil)
+	if err := box.Add(context.Background(), "gocode", connect(t, scriptedTeam)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// The backend agent asks security through the tool, then answers with
+	// what it was told
+	fake := llmtest.NewServer(t)
+	fake.Handle(func(req api.ChatRequest) llmtest.Reply {
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == "tool" {
+			return llmtest.Reply{Content: "Security says: " + last.Content}
+		}
+		if len(req.Tools) == 0 {
+			return llmtest.Reply{Content: "no tools"}
+		}
+		return llmtest.Reply{ToolCalls: []api.ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: api.ToolCallFunction{Name: req.Tools[0].Function.Name, Arguments: `{"message": "Is eval safe?"}`},
+		}}}
+	})
+	team, err := gocode.New(gocode.WithConfig(config), gocode.WithClient(fake.Client()), gocode.WithDataDir(t.TempDir()), gocode.WithTools(box))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	message := []gocode.Message{{Role: models.RoleUser, Content: "Should I use eval?"}}
+	response, err := team.Chat(ctx, models.BackendAgent, message)
+	if err != nil || response.Content != "Security says: Echo: Is eval safe?" {
+		t.Errorf("backend = %+v, %v", response, err)
+	}
+
+	response, err = team.Chat(ctx, models.FrontendAgent, message)
+	if err != nil || response.Content != "no tools" {
+		t.Errorf("frontend = %+v, %v", response, err)
+	}
+}
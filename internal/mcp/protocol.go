@@ -0,0 +1,203 @@
+// Package mcp speaks the Model Context Protocol: JSON-RPC 2.0 messages, one
+// per line, over a pair of streams such as a process's stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ProtocolVersion is the newest protocol revision spoken here
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the protocol revisions this package can speak
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// message is any JSON-RPC message: a request has a method and an ID, a
+// notification a method only, and a response an ID with a result or error
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Tool is a tool a server offers
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// Content is a piece of a tool result. Only text content is produced here.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// CallToolResult is the result of tools/call. Failures of the tool itself are
+// results with IsError set, not JSON-RPC errors.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text returns the text content of the result
+func (r *CallToolResult) Text() string {
+	var text string
+	for _, content := range r.Content {
+		if content.Type == "text" {
+			text += content.Text
+		}
+	}
+	return text
+}
+
+// textResult is a tool result of a single text
+func textResult(text string, isError bool) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: text}}, IsError: isError}
+}
+
+// Resource is a file a server exposes
+type Resource struct {
+	URI      string `json:"uri"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+}
+
+// ResourceContents is the content of a resource: Text for text files, Blob
+// (base64) for anything else
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// Implementation names a client or server
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// initializeParams are the params of initialize
+type initializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// initializeResult is the result of initialize
+type initializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// negotiateVersion picks the protocol revision to speak with a peer that
+// asked for requested
+func negotiateVersion(requested string) string {
+	for _, version := range supportedVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return ProtocolVersion
+}
+
+// conn reads and writes newline-delimited JSON-RPC messages. Writes are
+// safe from several goroutines; reads are not.
+type conn struct {
+	r  *bufio.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newConn creates a connection reading from r and writing to w
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: bufio.NewReader(r), w: w}
+}
+
+// read returns the next message. A line that isn't valid JSON is returned as
+// an error wrapping a JSON syntax error, so the caller can answer it and go
+// on; io.EOF means the peer closed the stream.
+func (c *conn) read() (*message, error) {
+	for {
+		line, err := c.r.ReadBytes('\n')
+		if len(line) == 0 || (len(line) == 1 && line[0] == '\n') {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var msg message
+		if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+			return nil, &Error{Code: CodeParseError, Message: fmt.Sprintf("parse error: %v", jsonErr)}
+		}
+		return &msg, nil
+	}
+}
+
+// write sends a message
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
+
+// notify sends a notification
+func (c *conn) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: data})
+}
+
+// respond answers the request with the given ID, with result or, if err is
+// set, an error
+func (c *conn) respond(id json.RawMessage, result interface{}, err *Error) error {
+	if err != nil {
+		return c.write(&message{ID: id, Error: err})
+	}
+
+	data, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		return c.write(&message{ID: id, Error: &Error{Code: CodeInternalError, Message: marshalErr.Error()}})
+	}
+	return c.write(&message{ID: id, Result: data})
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// CodeResourceNotFound is the error of resources/read for an unknown URI
+const CodeResourceNotFound = -32002
+
+// maxResourceSize limits the files served as resources
+const maxResourceSize = 1 << 20
+
+// skippedDirs are not listed as resources
+var skippedDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// resources lists the files below the server's directory
+func (s *Server) resources() ([]Resource, error) {
+	resources := []Resource{}
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == s.root && os.IsNotExist(err) {
+				return fs.SkipAll // Nothing built yet
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.root, path)
+		resources = append(resources, Resource{
+			URI:      fileURI(path),
+			Name:     filepath.ToSlash(rel),
+			MimeType: mimeType(path),
+			Size:     info.Size(),
+		})
+		return nil
+	})
+	return resources, err
+}
+
+// readResource returns the content of the file a URI names, which must be
+// below the server's directory
+func (s *Server) readResource(uri string) (*ResourceContents, *Error) {
+	notFound := &Error{Code: CodeResourceNotFound, Message: fmt.Sprintf("resource %q not found", uri)}
+
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return nil, notFound
+	}
+	path := filepath.Clean(filepath.FromSlash(parsed.Path))
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, notFound
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, notFound
+	}
+	if info.Size() > maxResourceSize {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("%s is larger than %d bytes", rel, maxResourceSize)}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+
+	contents := &ResourceContents{URI: uri, MimeType: mimeType(path)}
+	if utf8.Valid(data) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = base64.StdEncoding.EncodeToString(data)
+	}
+	return contents, nil
+}
+
+// fileURI returns the file:// URI of an absolute path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// mimeType guesses a file's MIME type from its extension, defaulting to
+// plain text since generated files are mostly source code
+func mimeType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "text/plain"
+}
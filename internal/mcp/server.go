@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"go-code/pkg/gocode"
+)
+
+// serverVersion is reported to clients in initialize
+const serverVersion = "dev"
+
+// Server exposes a team over MCP: every agent as an ask_<agent> tool, plan
+// and build tools, and the files of the project directory as resources
+type Server struct {
+	team *gocode.Team
+	root string
+	conn *conn
+
+	mu       sync.Mutex
+	inFlight map[string]context.CancelFunc
+	building bool
+}
+
+// NewServer creates a server for team. Builds write into root, and the files
+// below root are the server's resources.
+func NewServer(team *gocode.Team, root string) *Server {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &Server{team: team, root: root, inFlight: make(map[string]context.CancelFunc)}
+}
+
+// Serve answers the requests read from r on w until r ends or ctx is done.
+// Requests are handled concurrently, so a long build doesn't hold up the
+// others; notifications/cancelled stops one.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.conn = newConn(r, w)
+
+	// When r ends the requests in flight still get their answers
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	messages := make(chan *message)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := s.conn.read()
+			var rpcErr *Error
+			if errors.As(err, &rpcErr) {
+				s.conn.respond(json.RawMessage("null"), nil, rpcErr)
+				continue
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case msg := <-messages:
+			switch {
+			case msg.Method == "":
+				// A response; this server sends no requests
+			case len(msg.ID) == 0:
+				s.notification(msg)
+			default:
+				requestCtx := s.track(ctx, msg.ID)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer s.untrack(msg.ID)
+					result, err := s.handle(requestCtx, msg)
+					s.conn.respond(msg.ID, result, err)
+				}()
+			}
+		}
+	}
+}
+
+// track returns a context for the request with the given ID that
+// notifications/cancelled can cancel
+func (s *Server) track(ctx context.Context, id json.RawMessage) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.inFlight[string(id)] = cancel
+	s.mu.Unlock()
+	return ctx
+}
+
+// untrack forgets a finished request
+func (s *Server) untrack(id json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.inFlight[string(id)]; ok {
+		cancel()
+		delete(s.inFlight, string(id))
+	}
+}
+
+// notification handles a message that needs no answer
+func (s *Server) notification(msg *message) {
+	if msg.Method != "notifications/cancelled" {
+		return
+	}
+
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(msg.Params, &params) == nil {
+		s.mu.Lock()
+		if cancel, ok := s.inFlight[string(params.RequestID)]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// handle answers a request
+func (s *Server) handle(ctx context.Context, msg *message) (interface{}, *Error) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return initializeResult{
+			ProtocolVersion: negotiateVersion(params.ProtocolVersion),
+			Capabilities: map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{"listChanged": true},
+			},
+			ServerInfo:   Implementation{Name: "go-code", Version: serverVersion},
+			Instructions: "go-code's specialist agents. Ask one with an ask_<agent> tool, plan a project with plan, or generate it with build; generated files are resources.",
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.tools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+			Meta      struct {
+				ProgressToken json.RawMessage `json:"progressToken"`
+			} `json:"_meta"`
+		}
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, params.Name, params.Arguments, params.Meta.ProgressToken)
+	case "resources/list":
+		resources, err := s.resources()
+		if err != nil {
+			return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{"resources": resources}, nil
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		contents, err := s.readResource(params.URI)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"contents": []ResourceContents{*contents}}, nil
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// unmarshalParams decodes request params, answering invalid params on failure
+func unmarshalParams(params json.RawMessage, v interface{}) *Error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
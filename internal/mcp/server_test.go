@@ -0,0 +1,334 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+	"go-code/internal/llmtest"
+	"go-code/pkg/gocode"
+)
+
+// scriptedTeam answers as a planner with a two task plan and as the agents
+// doing the tasks; other agents echo the request
+func scriptedTeam(req api.ChatRequest) llmtest.Reply {
+	system := llmtest.SystemPrompt(req)
+	switch {
+	case strings.HasPrefix(system, "You are the Planner Agent"):
+		return llmtest.Reply{Content: "1. [BACKEND] Create the todo API\n2. [FRONTEND] Build the todo page"}
+	case strings.Contains(llmtest.UserMessage(req), "Create the todo API"):
+		return llmtest.Reply{Content: "```js\n// filename: routes/todos.js\nmodule.exports = {};\n```"}
+	case strings.Contains(llmtest.UserMessage(req), "Build the todo page"):
+		return llmtest.Reply{Content: "```html\n<!-- public/index.html -->\n<ul id=\"todos\"></ul>\n```"}
+	}
+	return llmtest.Reply{Content: "Echo: " + llmtest.UserMessage(req)}
+}
+
+// client is a scripted MCP client talking to a server over pipes
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Scanner
+	nextID int
+	// notifications holds the notifications received so far
+	notifications []message
+	done          chan error
+}
+
+// startServer runs a server for a team talking to a fake API answering with
+// handler, and returns a client connected to it and the project directory
+func startServer(t *testing.T, handler llmtest.Handler) (*client, string) {
+	t.Helper()
+	fake := llmtest.NewServer(t)
+	fake.Handle(handler)
+	team, err := gocode.New(gocode.WithClient(fake.Client()), gocode.WithDataDir(t.TempDir()), gocode.WithSecurityScan(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(t.TempDir(), "project")
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewScanner(outR), done: make(chan error, 1)}
+	c.out.Buffer(make([]byte, 1<<20), 1<<20)
+
+	go func() {
+		c.done <- NewServer(team, root).Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c, root
+}
+
+// send writes a raw line to the server
+func (c *client) send(line string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// notify sends a notification
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+	c.send(string(data))
+}
+
+// request sends a request and returns its ID without waiting for the answer
+func (c *client) request(method string, params interface{}) int {
+	c.t.Helper()
+	c.nextID++
+	data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	c.send(string(data))
+	return c.nextID
+}
+
+// next reads the next message from the server
+func (c *client) next() *message {
+	c.t.Helper()
+	if !c.out.Scan() {
+		c.t.Fatal("server closed the stream")
+	}
+	var msg message
+	if err := json.Unmarshal(c.out.Bytes(), &msg); err != nil {
+		c.t.Fatalf("server wrote invalid JSON %q: %v", c.out.Text(), err)
+	}
+	if msg.JSONRPC != "2.0" {
+		c.t.Fatalf("message without jsonrpc 2.0: %s", c.out.Text())
+	}
+	return &msg
+}
+
+// response reads messages until the response to id, keeping notifications
+func (c *client) response(id int) *message {
+	c.t.Helper()
+	for {
+		msg := c.next()
+		if msg.Method != "" {
+			c.notifications = append(c.notifications, *msg)
+			continue
+		}
+		if string(msg.ID) == jsonString(id) {
+			return msg
+		}
+	}
+}
+
+// call sends a request and decodes its result into result
+func (c *client) call(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+	msg := c.response(c.request(method, params))
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %v", method, msg.Error)
+	}
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+// callTool calls a tool and returns its result
+func (c *client) callTool(name string, arguments interface{}) *CallToolResult {
+	c.t.Helper()
+	var result CallToolResult
+	c.call("tools/call", map[string]interface{}{"name": name, "arguments": arguments}, &result)
+	return &result
+}
+
+func jsonString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestInitializeAndListTools(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	var init initializeResult
+	c.call("initialize", initializeParams{ProtocolVersion: "2024-11-05", ClientInfo: Implementation{Name: "test", Version: "1"}}, &init)
+	if init.ProtocolVersion != "2024-11-05" || init.ServerInfo.Name != "go-code" || init.Capabilities["tools"] == nil {
+		t.Errorf("initialize = %+v", init)
+	}
+	c.notify("notifications/initialized", nil)
+
+	var pong struct{}
+	c.call("ping", nil, &pong)
+
+	var list struct{ Tools []Tool }
+	c.call("tools/list", nil, &list)
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if !json.Valid(tool.InputSchema) {
+			t.Errorf("%s has an invalid input schema", tool.Name)
+		}
+	}
+	got := strings.Join(names, ",")
+	for _, want := range []string{"ask_planner", "ask_backend", "ask_security", "plan", "build"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("tools %s lack %s", got, want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	c.send(`{"jsonrpc": "2.0", "id": 1, "method": `)
+	if msg := c.next(); string(msg.ID) != "null" || msg.Error == nil || msg.Error.Code != CodeParseError {
+		t.Errorf("answer to malformed JSON = %+v", msg)
+	}
+
+	// The server carries on with the next message
+	var pong struct{}
+	c.call("ping", nil, &pong)
+}
+
+func TestAskAgent(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	result := c.callTool("ask_security", map[string]string{"message": "Is this safe?", "context": "eval(input)"})
+	if result.IsError || !strings.Contains(result.Text(), "Echo: Context: eval(input)") || !strings.Contains(result.Text(), "Is this safe?") {
+		t.Errorf("ask_security = %+v", result)
+	}
+}
+
+func TestPlanTool(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	result := c.callTool("plan", map[string]string{"description": "a todo app"})
+	if result.IsError || !strings.Contains(result.Text(), "1. [backend] Create the todo API") || !strings.Contains(result.Text(), "2. [frontend] Build the todo page") {
+		t.Errorf("plan = %q", result.Text())
+	}
+}
+
+func TestBuildToolAndResources(t *testing.T) {
+	c, root := startServer(t, scriptedTeam)
+
+	var result CallToolResult
+	c.call("tools/call", map[string]interface{}{
+		"name":      "build",
+		"arguments": map[string]string{"description": "a todo app"},
+		"_meta":     map[string]interface{}{"progressToken": "build-1"},
+	}, &result)
+	if result.IsError || !strings.Contains(result.Text(), "routes/todos.js") || !strings.Contains(result.Text(), "go-code undo") {
+		t.Errorf("build = %q", result.Text())
+	}
+
+	var progress, listChanged int
+	for _, notification := range c.notifications {
+		switch notification.Method {
+		case "notifications/progress":
+			var params struct {
+				ProgressToken string
+				Progress      int
+				Total         int
+			}
+			json.Unmarshal(notification.Params, &params)
+			if params.ProgressToken != "build-1" || params.Total == 0 {
+				t.Errorf("progress = %s", notification.Params)
+			}
+			progress++
+		case "notifications/resources/list_changed":
+			listChanged++
+		}
+	}
+	if progress < 3 || listChanged != 1 {
+		t.Errorf("got %d progress and %d list_changed notifications", progress, listChanged)
+	}
+
+	var list struct{ Resources []Resource }
+	c.call("resources/list", nil, &list)
+	var uri string
+	for _, resource := range list.Resources {
+		if resource.Name == "routes/todos.js" {
+			uri = resource.URI
+		}
+	}
+	if uri != fileURI(filepath.Join(root, "routes", "todos.js")) {
+		t.Fatalf("resources = %+v", list.Resources)
+	}
+
+	var read struct{ Contents []ResourceContents }
+	c.call("resources/read", map[string]string{"uri": uri}, &read)
+	if len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, "module.exports = {};") {
+		t.Errorf("read = %+v", read)
+	}
+
+	outside := fileURI(filepath.Join(root, "..", "secret.txt"))
+	if msg := c.response(c.request("resources/read", map[string]string{"uri": outside})); msg.Error == nil || msg.Error.Code != CodeResourceNotFound {
+		t.Errorf("read outside the project = %+v", msg)
+	}
+}
+
+func TestCancelBuild(t *testing.T) {
+	release := make(chan struct{})
+	c, _ := startServer(t, func(req api.ChatRequest) llmtest.Reply {
+		<-release
+		return llmtest.Reply{Status: 500, Error: "released"}
+	})
+	t.Cleanup(func() { close(release) })
+
+	id := c.request("tools/call", map[string]interface{}{
+		"name":      "build",
+		"arguments": map[string]string{"description": "a todo app"},
+		"_meta":     map[string]interface{}{"progressToken": 1},
+	})
+	if msg := c.next(); msg.Method != "notifications/progress" {
+		t.Fatalf("expected progress, got %+v", msg)
+	}
+
+	// A second build is refused while the first runs
+	second := c.callTool("build", map[string]string{"description": "a blog"})
+	if !second.IsError || !strings.Contains(second.Text(), "already running") {
+		t.Errorf("second build = %+v", second)
+	}
+
+	c.notify("notifications/cancelled", map[string]interface{}{"requestId": id, "reason": "user cancelled"})
+	msg := c.response(id)
+	var result CallToolResult
+	json.Unmarshal(msg.Result, &result)
+	if !result.IsError || !strings.Contains(result.Text(), "context canceled") {
+		t.Errorf("cancelled build = %+v", result)
+	}
+}
+
+func TestUnknownMethodAndTool(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	if msg := c.response(c.request("prompts/list", nil)); msg.Error == nil || msg.Error.Code != CodeMethodNotFound {
+		t.Errorf("prompts/list = %+v", msg)
+	}
+	msg := c.response(c.request("tools/call", map[string]interface{}{"name": "ask_nobody", "arguments": map[string]string{"message": "hi"}}))
+	if msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Errorf("unknown tool = %+v", msg)
+	}
+	msg = c.response(c.request("tools/call", map[string]interface{}{"name": "plan", "arguments": map[string]string{}}))
+	if msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Errorf("plan without a description = %+v", msg)
+	}
+}
+
+func TestServeEndsWithInput(t *testing.T) {
+	c, _ := startServer(t, scriptedTeam)
+
+	// A request sent just before the input ends is still answered
+	id := c.request("ping", nil)
+	c.in.Close()
+	if msg := c.response(id); msg.Error != nil {
+		t.Errorf("ping = %+v", msg)
+	}
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Errorf("Serve = %v, want nil at end of input", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return at end of input")
+	}
+}
@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-code/internal/api"
+	"go-code/pkg/models"
+)
+
+// toolSeparator joins a server's name and a tool's name into the name agents
+// see, e.g. postgres__query
+const toolSeparator = "__"
+
+// invalidToolChars are the characters the chat completions API doesn't
+// allow in function names
+var invalidToolChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ConfirmFunc asks the user whether an agent may call a server's tool with
+// the given arguments
+type ConfirmFunc func(agentType models.AgentType, server, tool, arguments string) bool
+
+// Toolbox holds connections to the configured MCP servers and gives each
+// agent the tools the configuration allows it
+type Toolbox struct {
+	config  *models.Config
+	confirm ConfirmFunc
+	clients map[string]*Client
+	tools   map[string][]Tool
+
+	// asking serializes permission prompts of agents working in parallel
+	asking sync.Mutex
+}
+
+// NewToolbox creates a toolbox without servers. When config requires
+// command permission, tool calls not granted in its session permissions go
+// through confirm; with a nil confirm they are refused.
+func NewToolbox(config *models.Config, confirm ConfirmFunc) *Toolbox {
+	return &Toolbox{
+		config:  config,
+		confirm: confirm,
+		clients: make(map[string]*Client),
+		tools:   make(map[string][]Tool),
+	}
+}
+
+// StartToolbox launches every server in config.MCPServers and discovers its
+// tools. Servers that fail to start are left out and reported in the error;
+// the toolbox holds the others either way.
+func StartToolbox(ctx context.Context, config *models.Config, confirm ConfirmFunc) (*Toolbox, error) {
+	box := NewToolbox(config, confirm)
+
+	names := make([]string, 0, len(config.MCPServers))
+	for name := range config.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		server := config.MCPServers[name]
+		client, err := Launch(server.Command, server.Args, server.Env)
+		if err == nil {
+			err = box.Add(ctx, name, client)
+			if err != nil {
+				if stderr := client.Stderr(); stderr != "" {
+					err = fmt.Errorf("%w\n%s", err, stderr)
+				}
+				client.Close()
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MCP server %s: %w", name, err))
+		}
+	}
+	return box, errors.Join(errs...)
+}
+
+// Add initializes a connection to the server called name and discovers its
+// tools. The toolbox closes the client when it is closed.
+func (b *Toolbox) Add(ctx context.Context, name string, client *Client) error {
+	if err := client.Initialize(ctx); err != nil {
+		return err
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	b.clients[name] = client
+	b.tools[name] = tools
+	return nil
+}
+
+// Close closes every server connection
+func (b *Toolbox) Close() error {
+	var errs []error
+	for _, client := range b.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ToolsFor returns the tools agentType may use, or nil if it may use none
+func (b *Toolbox) ToolsFor(agentType models.AgentType) api.Toolset {
+	set := &toolset{box: b, agentType: agentType, targets: make(map[string]target)}
+
+	servers := make([]string, 0, len(b.tools))
+	for name := range b.tools {
+		servers = append(servers, name)
+	}
+	sort.Strings(servers)
+
+	for _, server := range servers {
+		for _, tool := range b.tools[server] {
+			if !b.config.MCPServers[server].Allows(tool.Name, agentType) {
+				continue
+			}
+			name := server + toolSeparator + invalidToolChars.ReplaceAllString(tool.Name, "_")
+			if len(name) > 64 {
+				name = name[:64]
+			}
+			set.targets[name] = target{server: server, tool: tool.Name}
+			set.tools = append(set.tools, api.Tool{
+				Type: "function",
+				Function: api.ToolFunction{
+					Name:        name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			})
+		}
+	}
+
+	if len(set.tools) == 0 {
+		return nil
+	}
+	return set
+}
+
+// permitted reports whether agentType may call a server's tool, asking the
+// user when the configuration requires it
+func (b *Toolbox) permitted(agentType models.AgentType, server, tool, arguments string) bool {
+	if !b.config.RequireCommandPermission || b.config.SessionPermissions[models.MCPPermissionKey(server, tool)] {
+		return true
+	}
+	if b.confirm == nil {
+		return false
+	}
+
+	b.asking.Lock()
+	defer b.asking.Unlock()
+	return b.confirm(agentType, server, tool, arguments)
+}
+
+// target is the server tool behind a name offered to an agent
+type target struct {
+	server string
+	tool   string
+}
+
+// toolset is the part of a toolbox one agent may use
+type toolset struct {
+	box       *Toolbox
+	agentType models.AgentType
+	tools     []api.Tool
+	targets   map[string]target
+}
+
+func (s *toolset) Tools() []api.Tool {
+	return s.tools
+}
+
+// Call runs a tool on its server once the user allows it. A result the
+// server flags as an error is returned as an error.
+func (s *toolset) Call(ctx context.Context, name, arguments string) (string, error) {
+	target, ok := s.targets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("arguments of %s are not valid JSON", name)
+	}
+	if !s.box.permitted(s.agentType, target.server, target.tool, arguments) {
+		return "", fmt.Errorf("the user did not allow %s to be called", name)
+	}
+
+	result, err := s.box.clients[target.server].CallTool(ctx, target.tool, json.RawMessage(arguments))
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", errors.New(result.Text())
+	}
+	return result.Text(), nil
+}
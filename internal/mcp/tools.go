@@ -0,0 +1,220 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
+)
+
+// askPrefix starts the name of the tool asking an agent, e.g. ask_backend
+const askPrefix = "ask_"
+
+// askSchema is the input of the ask_<agent> tools
+const askSchema = `{
+	"type": "object",
+	"properties": {
+		"message": {"type": "string", "description": "The question or request for the agent"},
+		"context": {"type": "string", "description": "Code or background the agent should take into account"}
+	},
+	"required": ["message"]
+}`
+
+// descriptionSchema is the input of the plan and build tools
+const descriptionSchema = `{
+	"type": "object",
+	"properties": {
+		"description": {"type": "string", "description": "What to build, e.g. \"a todo app with a REST API\""}
+	},
+	"required": ["description"]
+}`
+
+// tools lists the server's tools: one per agent, then plan and build
+func (s *Server) tools() []Tool {
+	var tools []Tool
+	for _, agent := range s.team.Agents() {
+		tools = append(tools, Tool{
+			Name:        askPrefix + string(agent.Type()),
+			Description: fmt.Sprintf("Ask go-code's %s (%s) and get its answer", agent.Name(), agent.Role()),
+			InputSchema: json.RawMessage(askSchema),
+		})
+	}
+	return append(tools,
+		Tool{
+			Name:        "plan",
+			Description: "Have go-code's planner break a project down into tasks for its agents, without writing files",
+			InputSchema: json.RawMessage(descriptionSchema),
+		},
+		Tool{
+			Name: "build",
+			Description: fmt.Sprintf("Plan and generate a project with go-code's whole agent team. Files are written to %s "+
+				"and listed as resources; the run can be reverted with go-code undo.", s.root),
+			InputSchema: json.RawMessage(descriptionSchema),
+		},
+	)
+}
+
+// callTool runs a tool. Unknown tools and bad arguments are protocol errors;
+// a tool that fails returns a result with IsError set.
+func (s *Server) callTool(ctx context.Context, name string, arguments json.RawMessage, progressToken json.RawMessage) (*CallToolResult, *Error) {
+	if agentName, ok := strings.CutPrefix(name, askPrefix); ok {
+		agentType, found := s.agentType(agentName)
+		if !found {
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+		}
+		var args struct {
+			Message string `json:"message"`
+			Context string `json:"context"`
+		}
+		if err := unmarshalParams(arguments, &args); err != nil {
+			return nil, err
+		}
+		if args.Message == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "message is required"}
+		}
+		return s.ask(ctx, agentType, args.Message, args.Context), nil
+	}
+
+	var args struct {
+		Description string `json:"description"`
+	}
+	switch name {
+	case "plan", "build":
+		if err := unmarshalParams(arguments, &args); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(args.Description) == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "description is required"}
+		}
+	default:
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+	}
+
+	if name == "plan" {
+		return s.plan(ctx, args.Description), nil
+	}
+	return s.build(ctx, args.Description, progressToken), nil
+}
+
+// agentType returns the type of the team's agent called name
+func (s *Server) agentType(name string) (models.AgentType, bool) {
+	for _, agent := range s.team.Agents() {
+		if string(agent.Type()) == name {
+			return agent.Type(), true
+		}
+	}
+	return "", false
+}
+
+// ask sends a message, with optional context, to one agent
+func (s *Server) ask(ctx context.Context, agentType models.AgentType, message, taskContext string) *CallToolResult {
+	if taskContext != "" {
+		message = fmt.Sprintf("Context: %s\n\nUser Request: %s", taskContext, message)
+	}
+
+	response, err := s.team.Chat(ctx, agentType, []gocode.Message{{Role: models.RoleUser, Content: message}})
+	if err != nil {
+		return textResult(fmt.Sprintf("%s agent failed: %v", agentType, err), true)
+	}
+	return textResult(response.Content, false)
+}
+
+// plan asks the planner for the tasks of a project
+func (s *Server) plan(ctx context.Context, description string) *CallToolResult {
+	tasks, err := s.team.Plan(ctx, description)
+	if err != nil {
+		return textResult(fmt.Sprintf("Planning failed: %v", err), true)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan for %q:\n", description)
+	for i, task := range tasks {
+		fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, task.Agent, task.Description)
+		if len(task.Acceptance) > 0 {
+			fmt.Fprintf(&b, "   Acceptance: %s\n", strings.Join(task.Acceptance, "; "))
+		}
+	}
+	return textResult(b.String(), false)
+}
+
+// build generates a project into the server's directory, reporting each
+// stage as progress when the client asked for it. Builds run one at a time
+// because they share the directory.
+func (s *Server) build(ctx context.Context, description string, progressToken json.RawMessage) *CallToolResult {
+	s.mu.Lock()
+	if s.building {
+		s.mu.Unlock()
+		return textResult("Another build is already running; try again when it has finished", true)
+	}
+	s.building = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.building = false
+		s.mu.Unlock()
+	}()
+
+	opts := []gocode.BuildOption{gocode.BuildSink(gocode.NewDirectorySink(s.root))}
+	if len(progressToken) > 0 {
+		opts = append(opts, gocode.BuildEventHandler(gocode.HandlerFunc(func(event gocode.Event) {
+			if event.Type == gocode.EventStageStarted {
+				s.conn.notify("notifications/progress", map[string]interface{}{
+					"progressToken": progressToken,
+					"progress":      event.Step,
+					"total":         event.Total,
+					"message":       event.Stage,
+				})
+			}
+		})))
+	}
+
+	result, err := s.team.Build(ctx, description, opts...)
+	if len(result.Files) > 0 {
+		s.conn.notify("notifications/resources/list_changed", struct{}{})
+	}
+	if err != nil {
+		return textResult(fmt.Sprintf("Build failed: %v\n\n%s", err, buildReport(description, result)), true)
+	}
+	return textResult(buildReport(description, result), false)
+}
+
+// buildReport summarizes a build for the client
+func buildReport(description string, result *gocode.BuildResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Build of %q, run %s (%s). Revert it with: go-code undo %s\n",
+		description, result.RunID, result.Duration.Round(time.Second), result.RunID)
+
+	if len(result.Tasks) > 0 {
+		b.WriteString("\nTasks:\n")
+		for _, task := range result.Tasks {
+			fmt.Fprintf(&b, "- %s [%s] %s: %s\n", task.ID, task.Agent, task.Description, task.Status)
+			if tests := task.Tests; tests != nil {
+				fmt.Fprintf(&b, "  acceptance tests %s", tests.Status)
+				if tests.Reason != "" {
+					fmt.Fprintf(&b, " (%s)", tests.Reason)
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+	if len(result.Files) > 0 {
+		b.WriteString("\nFiles:\n")
+		for _, file := range result.Files {
+			fmt.Fprintf(&b, "- %s\n", file)
+		}
+	}
+	if len(result.Findings) > 0 {
+		b.WriteString("\nSecurity findings:\n")
+		for _, finding := range result.Findings {
+			fmt.Fprintf(&b, "- %s [%s] %s:%d %s\n", finding.Rule, finding.Severity, finding.File, finding.Line, finding.Message)
+		}
+	}
+
+	total := result.Usage.Total
+	fmt.Fprintf(&b, "\nTokens: %d in %d requests", total.TotalTokens(), total.Requests)
+	return b.String()
+}
@@ -0,0 +1,307 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go-code/internal/runner"
+	"go-code/internal/stack"
+	"go-code/pkg/models"
+)
+
+// defaultTestTimeout bounds a test run when acceptance.timeout is unusable
+const defaultTestTimeout = 5 * time.Minute
+
+// defaultTestCommands run the tests of a language whose manifest doesn't say
+// how
+var defaultTestCommands = map[string]string{
+	stack.Go:         "go test ./...",
+	stack.JavaScript: "npx jest",
+	stack.TypeScript: "npx jest",
+	stack.Python:     "python -m pytest",
+	stack.Rust:       "cargo test",
+}
+
+// testConventions tell the test writer where tests go in each language
+var testConventions = map[string]string{
+	stack.Go:         "Go tests using the standard testing package, in _test.go files next to the code they test",
+	stack.JavaScript: "jest tests in files named *.test.js",
+	stack.TypeScript: "jest tests in files named *.test.ts",
+	stack.Python:     "pytest tests in tests/test_*.py",
+	stack.Rust:       "integration tests in tests/*.rs",
+}
+
+// jsTestFile and pythonTestFile match the base names of test files
+var (
+	jsTestFile     = regexp.MustCompile(`\.(test|spec)\.[cm]?[jt]sx?$`)
+	pythonTestFile = regexp.MustCompile(`^(test_.*|.*_test)\.py$|^conftest\.py$`)
+)
+
+// SetRunner sets the runner acceptance tests run through. The default runner
+// can't ask for permission, so with require_command_permission only
+// commands with a session permission run.
+func (o *Orchestrator) SetRunner(r *runner.Runner) {
+	o.runner = r
+}
+
+// testTask has tests written for a completed task's acceptance criteria,
+// runs them and lets the task's agent fix its code while they fail, up to
+// the configured rounds. The outcome is kept in task.Tests; it returns the
+// task's files with the tests added.
+func (o *Orchestrator) testTask(task *Task, agent models.Agent, context string, written []string) []string {
+	result := &models.TestResult{Criteria: task.Acceptance}
+	task.Tests = result
+	notRun := func(reason string) []string {
+		result.Status, result.Reason = models.TestsNotRun, reason
+		o.warn(fmt.Sprintf("Acceptance tests for %s not run: %s", task.ID, reason))
+		return written
+	}
+
+	root := o.fileWriter.ProjectRoot()
+	if root == "" {
+		return notRun("the build's files are not written to a directory")
+	}
+	profile := o.testProfile(root)
+	command := testCommand(profile)
+	if command == "" {
+		return notRun("no test command is known for the project's stack")
+	}
+	result.Command = command
+	// Don't spend tokens on tests that can't run
+	if err := o.runner.Check(command); err != nil {
+		return notRun(err.Error())
+	}
+
+	tester, err := o.registry.GetAgent(o.config.Acceptance.Agent)
+	if err != nil {
+		return notRun(fmt.Sprintf("agent %s not available: %v", o.config.Acceptance.Agent, err))
+	}
+	response, err := o.process(tester, "", o.testPrompt(task, profile, command, written))
+	if err != nil {
+		return notRun(fmt.Sprintf("writing the tests failed: %v", err))
+	}
+	o.track(task, response)
+	tests := o.writeTestFiles(response.Content, profile.Primary(), written)
+	if len(tests) == 0 {
+		return notRun("no test files were written")
+	}
+	for _, path := range tests {
+		result.Files = append(result.Files, filepath.ToSlash(path))
+	}
+
+	files := mergePaths(written, tests)
+	timeout, err := time.ParseDuration(o.config.Acceptance.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = defaultTestTimeout
+	}
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+		if !o.runTests(result, root, timeout) {
+			return files
+		}
+		if result.Status == models.TestsPassed {
+			return files
+		}
+
+		// Out of fix rounds or budget, the task fails with its test output
+		if attempt > o.config.Acceptance.Rounds || o.usage.CheckBudget() != nil {
+			return files
+		}
+
+		fix, err := o.process(agent, context, o.fixPrompt(task, result, files))
+		if err != nil {
+			o.warn(fmt.Sprintf("Fixing the failing tests failed: %v", err))
+			return files
+		}
+		o.track(task, fix)
+		task.Result = fix
+		files = mergePaths(files, o.writeGeneratedFiles(fix.Content))
+	}
+}
+
+// writeTestFiles writes the test files of the test writer's response. Files
+// the task wrote, or that aren't test files by the stack's conventions, are
+// refused, so the tests can't change the code they check.
+func (o *Orchestrator) writeTestFiles(content, language string, written []string) []string {
+	taskFiles := make(map[string]bool, len(written))
+	for _, name := range written {
+		taskFiles[slashPath(name)] = true
+	}
+
+	blocks := o.fileWriter.ExtractCodeBlocks(content)
+	var refused []string
+	for name := range blocks {
+		if taskFiles[slashPath(name)] || !isTestFile(language, name) {
+			refused = append(refused, name)
+			delete(blocks, name)
+		}
+	}
+	if len(refused) > 0 {
+		sort.Strings(refused)
+		o.warn(fmt.Sprintf("Ignored files from the acceptance test writer that aren't new test files: %s", strings.Join(refused, ", ")))
+	}
+	return o.writeCodeBlocks(blocks)
+}
+
+// isTestFile reports whether name follows the test file conventions of
+// language, or of any known language when the stack is unknown
+func isTestFile(language, name string) bool {
+	name = slashPath(name)
+	base := path.Base(name)
+	switch language {
+	case stack.Go:
+		return strings.HasSuffix(base, "_test.go") || inDir(name, "testdata")
+	case stack.JavaScript, stack.TypeScript:
+		return jsTestFile.MatchString(base) || inDir(name, "__tests__")
+	case stack.Python:
+		return pythonTestFile.MatchString(base) || (inDir(name, "tests") && strings.HasSuffix(base, ".py"))
+	case stack.Rust:
+		return inDir(name, "tests") && strings.HasSuffix(base, ".rs")
+	}
+
+	for _, known := range []string{stack.Go, stack.JavaScript, stack.Python, stack.Rust} {
+		if isTestFile(known, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inDir reports whether a slash-separated path lies below a directory
+// called dir
+func inDir(name, dir string) bool {
+	parts := strings.Split(path.Dir(name), "/")
+	for _, part := range parts {
+		if part == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// slashPath normalizes a relative path for comparison
+func slashPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// runTests runs the test command once and records how it ended. It returns
+// false when the tests could not be run at all.
+func (o *Orchestrator) runTests(result *models.TestResult, root string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(o.ctx, timeout)
+	defer cancel()
+
+	run, err := o.runner.Run(ctx, root, result.Command)
+	switch {
+	case err != nil && errors.Is(err, context.DeadlineExceeded) && o.ctx.Err() == nil:
+		result.Status, result.ExitCode = models.TestsFailed, -1
+		result.Output, result.Reason = run.Output, fmt.Sprintf("%s timed out after %s", result.Command, timeout)
+		return true
+	case err != nil:
+		result.Status, result.Reason = models.TestsNotRun, err.Error()
+		o.warn(fmt.Sprintf("Acceptance tests not run: %v", err))
+		return false
+	case run.Passed():
+		result.Status, result.ExitCode, result.Output, result.Reason = models.TestsPassed, 0, "", ""
+	default:
+		result.Status, result.ExitCode, result.Output = models.TestsFailed, run.ExitCode, run.Output
+		result.Reason = fmt.Sprintf("%s exited with status %d", result.Command, run.ExitCode)
+	}
+	return true
+}
+
+// testProfile returns the stack of the generated project, or of the project
+// the build runs in when the generated files don't tell
+func (o *Orchestrator) testProfile(root string) *stack.Profile {
+	if profile, err := stack.Detect(root); err == nil && profile.Primary() != "" {
+		return profile
+	}
+	if o.stack != nil {
+		return o.stack
+	}
+	return &stack.Profile{}
+}
+
+// testCommand returns the command that runs a stack's tests
+func testCommand(profile *stack.Profile) string {
+	if profile.Commands.Test != "" {
+		return profile.Commands.Test
+	}
+	return defaultTestCommands[profile.Primary()]
+}
+
+// testPrompt asks for tests of the task's acceptance criteria
+func (o *Orchestrator) testPrompt(task *Task, profile *stack.Profile, command string, files []string) string {
+	conventions := testConventions[profile.Primary()]
+	if conventions == "" {
+		conventions = "tests in the project's usual test framework"
+	}
+	if profile.TestRunner != "" {
+		conventions += fmt.Sprintf(" (the project's test runner is %s)", profile.TestRunner)
+	}
+
+	return fmt.Sprintf(`Write automated acceptance tests for this task.
+
+Task: %s
+
+Acceptance criteria:
+%s
+
+Stack: %s
+Write %s. They are run with `+"`%s`"+` from the project root, so each criterion must be checked by a test that fails when it isn't met.
+
+%s
+Test the code through its public behaviour and don't change it; only new test files are kept. Return only the test files as code blocks with their filenames.`,
+		task.Description, bulletList(task.Acceptance), profileSummary(profile), conventions, command, o.fileSnapshot(files))
+}
+
+// fixPrompt asks the task's agent to make the failing tests pass
+func (o *Orchestrator) fixPrompt(task *Task, result *models.TestResult, files []string) string {
+	return fmt.Sprintf(`The acceptance tests for your previous output fail.
+
+Task: %s
+
+Acceptance criteria:
+%s
+
+%s:
+`+"```"+`
+%s
+`+"```"+`
+
+%s
+Fix the code so the tests pass. Only change a test if it contradicts the acceptance criteria. Return the complete updated files as code blocks with their filenames.`,
+		task.Description, bulletList(task.Acceptance), result.Reason, tailText(result.Output, maxReviewFileChars), o.fileSnapshot(files))
+}
+
+// profileSummary describes a stack for a prompt
+func profileSummary(profile *stack.Profile) string {
+	if summary := profile.Summary(); summary != "" {
+		return summary
+	}
+	return "unknown"
+}
+
+// tailText shortens text to its last maxLen bytes, where test runners
+// report failures
+func tailText(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	return "..." + text[len(text)-maxLen:]
+}
+
+// bulletList renders items as a Markdown list
+func bulletList(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,253 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go-code/internal/events"
+	"go-code/pkg/models"
+)
+
+// errBuildStopped is returned by ExecuteBuild when Controls.Stop is called
+var errBuildStopped = errors.New("build stopped")
+
+// Controls lets an interactive front end steer a running build: pause it
+// before the next task, skip a task, run a task again or stop the build.
+// Its methods are safe to call from any goroutine.
+type Controls struct {
+	mu      sync.Mutex
+	paused  bool
+	resume  chan struct{}
+	stopped bool
+	skip    map[string]bool
+	retries []string
+
+	// The task that is running and how to interrupt it
+	running   string
+	cancel    context.CancelFunc
+	interrupt string
+}
+
+// Ways a running task can be interrupted
+const (
+	interruptSkip  = "skip"
+	interruptRetry = "retry"
+)
+
+// NewControls creates controls for an unpaused build
+func NewControls() *Controls {
+	return &Controls{skip: make(map[string]bool)}
+}
+
+// TogglePause pauses or resumes the build and reports whether it is now
+// paused. A paused build finishes the running task and waits before the next.
+func (c *Controls) TogglePause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+		return false
+	}
+	c.paused = true
+	c.resume = make(chan struct{})
+	return true
+}
+
+// Paused reports whether the build is paused
+func (c *Controls) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Skip interrupts the task if it is running, or skips it when it comes up
+func (c *Controls) Skip(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if taskID == c.running && c.cancel != nil {
+		c.interrupt = interruptSkip
+		c.cancel()
+		return
+	}
+	c.skip[taskID] = true
+}
+
+// Retry runs a task again after the running one, restarts it if it is the
+// running task, or cancels an earlier Skip of a task that hasn't run yet
+func (c *Controls) Retry(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A task marked to be skipped hasn't run yet; unmarking it is enough
+	if c.skip[taskID] {
+		delete(c.skip, taskID)
+		return
+	}
+	if taskID == c.running && c.cancel != nil {
+		c.interrupt = interruptRetry
+		c.cancel()
+		return
+	}
+	c.retries = append(c.retries, taskID)
+}
+
+// Stop interrupts the running task and ends the build
+func (c *Controls) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+	}
+}
+
+// isStopped reports whether Stop was called
+func (c *Controls) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+// wait blocks while the build is paused and reports a stopped build
+func (c *Controls) wait() error {
+	c.mu.Lock()
+	resume, paused := c.resume, c.paused
+	c.mu.Unlock()
+
+	if paused {
+		<-resume
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return errBuildStopped
+	}
+	return nil
+}
+
+// start marks taskID as running and returns the context to run it with
+func (c *Controls) start(parent context.Context, taskID string) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running, c.cancel, c.interrupt = taskID, cancel, ""
+	if c.stopped {
+		cancel()
+	}
+	return ctx
+}
+
+// finish clears the running task and returns how it was interrupted, if at all
+func (c *Controls) finish() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+	interrupt := c.interrupt
+	c.running, c.cancel, c.interrupt = "", nil, ""
+	return interrupt
+}
+
+// takeSkip reports whether taskID was marked to be skipped
+func (c *Controls) takeSkip(taskID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	skipped := c.skip[taskID]
+	delete(c.skip, taskID)
+	return skipped
+}
+
+// takeRetries returns the tasks queued to run again
+func (c *Controls) takeRetries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	retries := c.retries
+	c.retries = nil
+	return retries
+}
+
+// EnableControls lets controls steer the build while it runs
+func (o *Orchestrator) EnableControls(controls *Controls) {
+	o.controls = controls
+}
+
+// EnableStreaming makes agents that support it stream their output as
+// task_output events while a task runs
+func (o *Orchestrator) EnableStreaming() {
+	o.stream = true
+}
+
+// runTask runs a task's agent and returns how the controls interrupted it,
+// if they did
+func (o *Orchestrator) runTask(agent models.Agent, taskContext string, task *Task) (*models.Response, string, error) {
+	ctx := o.ctx
+	if o.controls != nil {
+		ctx = o.controls.start(ctx, task.ID)
+	}
+
+	var onDelta func(string)
+	if o.stream {
+		onDelta = func(delta string) {
+			o.publish(events.Event{Type: events.TaskOutput, TaskID: task.ID, Agent: task.AgentType, Delta: delta})
+		}
+	}
+
+	var response *models.Response
+	var err error
+	if streaming, ok := agent.(models.StreamingAgent); ok {
+		response, err = streaming.ProcessStream(ctx, taskContext, task.Description, onDelta)
+	} else {
+		response, err = agent.Process(taskContext, task.Description)
+	}
+
+	interrupt := ""
+	if o.controls != nil {
+		interrupt = o.controls.finish()
+	}
+	return response, interrupt, err
+}
+
+// skipTask marks a task as skipped
+func (o *Orchestrator) skipTask(task *Task) {
+	task.Status = "skipped"
+	o.publish(events.Event{Type: events.TaskSkipped, TaskID: task.ID, Agent: task.AgentType, Task: task.Description})
+}
+
+// queueRetries inserts the tasks queued by Controls.Retry at position step
+// of order, so they run next
+func (o *Orchestrator) queueRetries(tasks []Task, order []int, step int) []int {
+	retries := o.controls.takeRetries()
+	for n := len(retries) - 1; n >= 0; n-- {
+		for i := range tasks {
+			if tasks[i].ID == retries[n] && tasks[i].Status != "running" {
+				tasks[i].Status = "pending"
+				order = insertIndex(order, step, i)
+				break
+			}
+		}
+	}
+	return order
+}
+
+// insertIndex inserts index i into order at position at
+func insertIndex(order []int, at, i int) []int {
+	order = append(order, 0)
+	copy(order[at+1:], order[at:])
+	order[at] = i
+	return order
+}
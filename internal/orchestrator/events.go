@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"time"
+
+	"go-code/internal/events"
+	"go-code/pkg/models"
+)
+
+// runStateFile is the name of the run state kept next to the run's journal
+const runStateFile = "state.json"
+
+// Events returns the bus the orchestrator publishes the build's progress on.
+// Subscribe to it before calling ExecuteBuild.
+func (o *Orchestrator) Events() *events.Bus {
+	return o.bus
+}
+
+// RunStatePath returns where the state of this orchestrator's run is kept
+func (o *Orchestrator) RunStatePath() string {
+	return filepath.Join(o.journalDir, o.runID, runStateFile)
+}
+
+// publish sends an event about this run to the bus
+func (o *Orchestrator) publish(event events.Event) {
+	if event.RunID == "" {
+		event.RunID = o.runID
+	}
+	o.bus.Publish(event)
+}
+
+// warn publishes a problem that doesn't stop the build
+func (o *Orchestrator) warn(message string) {
+	o.publish(events.Event{Type: events.Warning, Message: message})
+}
+
+// stage publishes the start or end of step of total build stages
+func (o *Orchestrator) stage(eventType events.Type, name string, step, total int) {
+	o.publish(events.Event{Type: eventType, Stage: name, Step: step, Total: total})
+}
+
+// record adds a response to the usage ledger and reports the tokens used
+func (o *Orchestrator) record(label string, task *Task, response *models.Response) models.Usage {
+	used := o.usage.Record(label, response)
+	summary := o.usage.Summary()
+	event := events.Event{Type: events.TokensUsed, Task: label, Usage: &used, Summary: &summary}
+	if task != nil {
+		event.TaskID, event.Agent = task.ID, task.AgentType
+	}
+	o.publish(event)
+	return used
+}
+
+// emitPlan reports the parsed plan
+func (o *Orchestrator) emitPlan(tasks []Task) {
+	planned := make([]events.PlannedTask, len(tasks))
+	for i, task := range tasks {
+		planned[i] = events.PlannedTask{ID: task.ID, Agent: task.AgentType, Description: task.Description, Acceptance: task.Acceptance}
+	}
+	summary := o.usage.Summary()
+	o.publish(events.Event{Type: events.PlanCreated, Tasks: planned, Summary: &summary})
+}
+
+// emitTaskStarted reports a task handed to its agent
+func (o *Orchestrator) emitTaskStarted(task *Task) {
+	o.publish(events.Event{Type: events.TaskStarted, TaskID: task.ID, Agent: task.AgentType, Task: task.Description})
+}
+
+// emitTaskFailed reports a task that could not be completed, with its
+// acceptance tests if they failed
+func (o *Orchestrator) emitTaskFailed(task *Task, err error) {
+	o.publish(events.Event{Type: events.TaskFailed, TaskID: task.ID, Agent: task.AgentType, Task: task.Description, Error: err.Error(), Tests: task.Tests})
+}
+
+// emitTaskCompleted reports a finished task with its usage, the run's usage so
+// far, its review and acceptance test outcomes and the files it wrote
+func (o *Orchestrator) emitTaskCompleted(task *Task, written []string) {
+	files := make([]string, 0, len(written))
+	for _, path := range written {
+		files = append(files, filepath.ToSlash(path))
+	}
+	usage := task.Usage
+	summary := o.usage.Summary()
+	event := events.Event{
+		Type:    events.TaskCompleted,
+		TaskID:  task.ID,
+		Agent:   task.AgentType,
+		Task:    task.Description,
+		Files:   files,
+		Usage:   &usage,
+		Summary: &summary,
+		Tests:   task.Tests,
+	}
+	if task.ReviewRounds > 0 {
+		event.Review = &events.Review{Rounds: task.ReviewRounds, Passed: task.ReviewPassed, Findings: task.Findings}
+	}
+	o.publish(event)
+}
+
+// emitSummary reports the outcome of the whole build. scanned is false when
+// the security scan was skipped.
+func (o *Orchestrator) emitSummary(projectPath string, totalStages int, startTime time.Time, findings []models.Finding, scanned bool) {
+	summary := o.usage.Summary()
+	event := events.Event{
+		Type:     events.Summary,
+		Path:     projectPath,
+		Total:    totalStages,
+		Files:    o.fileWriter.WrittenFiles(),
+		Summary:  &summary,
+		Duration: time.Since(startTime).Seconds(),
+	}
+	if scanned {
+		event.Findings, event.Report = findings, o.SARIFPath()
+	}
+	o.publish(event)
+}
@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,8 +10,12 @@ import (
 	"time"
 
 	"go-code/internal/agents"
+	"go-code/internal/events"
 	"go-code/internal/filewriter"
-	"go-code/internal/ui"
+	"go-code/internal/git"
+	"go-code/internal/runner"
+	"go-code/internal/stack"
+	"go-code/internal/usage"
 	"go-code/pkg/models"
 )
 
@@ -19,21 +24,80 @@ type Orchestrator struct {
 	registry   *agents.Registry
 	config     *models.Config
 	fileWriter *filewriter.FileWriter
+	runID      string
+	repo       *git.Repo
+	skipScan   bool
+	sarifPath  string
+	usage      *usage.Tracker
+	controls   *Controls
+	stream     bool
+	bus        *events.Bus
+	journalDir string
+	ctx        context.Context
+	tasks      []Task
+	findings   []models.Finding
+	stack      *stack.Profile
+	runner     *runner.Runner
+}
+
+// DefaultOutputDir returns where builds write their files unless given a
+// sink: the generated-project directory below the working directory
+func DefaultOutputDir() string {
+	cwd, _ := os.Getwd()
+	return filepath.Join(cwd, git.GeneratedDir)
 }
 
 // New creates a new orchestrator
 func New(registry *agents.Registry, config *models.Config) *Orchestrator {
-	// Create project directory
-	cwd, _ := os.Getwd()
-	projectDir := filepath.Join(cwd, "generated-project")
-	
+	runID := filewriter.NewRunID()
 	return &Orchestrator{
 		registry:   registry,
 		config:     config,
-		fileWriter: filewriter.New(projectDir),
+		fileWriter: filewriter.New(DefaultOutputDir()),
+		runID:      runID,
+		usage:      usage.NewTracker(usage.DefaultLedgerPath(), "build", runID, config.Budget),
+		bus:        events.NewBus(),
+		journalDir: filewriter.DefaultJournalDir(),
+		ctx:        context.Background(),
+		runner:     runner.New(config, nil),
 	}
 }
 
+// SetFileSink makes the build write its files into sink instead of the
+// generated-project directory. Only directory sinks are journaled for undo.
+func (o *Orchestrator) SetFileSink(sink models.FileSink) {
+	o.fileWriter = filewriter.NewWithSink(sink)
+}
+
+// SetDataDir keeps the run journal, run state and usage ledger below dir
+// instead of ~/.go-code
+func (o *Orchestrator) SetDataDir(dir string) {
+	o.journalDir = filepath.Join(dir, "runs")
+	o.usage = usage.NewTracker(filepath.Join(dir, "usage.jsonl"), "build", o.runID, o.config.Budget)
+}
+
+// SetStack tells the planner which stack the project uses and lays out new
+// projects for its primary language
+func (o *Orchestrator) SetStack(profile *stack.Profile) {
+	o.stack = profile
+}
+
+// RunID returns the identifier used to journal and undo this orchestrator's run
+func (o *Orchestrator) RunID() string {
+	return o.runID
+}
+
+// EnableGit makes the build run on a new go-code/<run-id> branch of repo and
+// commit the output of every completed task
+func (o *Orchestrator) EnableGit(repo *git.Repo) {
+	o.repo = repo
+}
+
+// BranchName returns the branch the build commits to when git is enabled
+func (o *Orchestrator) BranchName() string {
+	return "go-code/" + o.runID
+}
+
 // Task represents a task that needs to be executed by an agent
 type Task struct {
 	ID          string
@@ -42,98 +106,257 @@ type Task struct {
 	Dependencies []string
 	Status      string
 	Result      *models.Response
+	Findings     []models.Finding
+	ReviewRounds int
+	ReviewPassed bool
+	Usage        models.Usage
+	// Acceptance are the criteria the planner gave for the task being done
+	Acceptance []string
+	// Tests is the outcome of the task's acceptance tests, if it has criteria
+	Tests *models.TestResult
 }
 
-// ExecuteBuild coordinates agents to build a complete feature
+// ExecuteBuild coordinates agents to build a complete feature. Its progress
+// is published on Events; a failed build ends with an error event.
 func (o *Orchestrator) ExecuteBuild(description string) error {
+	return o.ExecuteBuildContext(context.Background(), description)
+}
+
+// ExecuteBuildContext is ExecuteBuild with a context that stops the build
+// and cancels the running request when it is done
+func (o *Orchestrator) ExecuteBuildContext(ctx context.Context, description string) (err error) {
 	startTime := time.Now()
+	o.ctx = ctx
 	
-	// Clear screen for clean output
-	ui.ClearScreen()
-	fmt.Printf("🚀 Building: %s\n\n", description)
+	// Keep the run's state next to its journal while it runs
+	state := events.NewStatePersister(o.RunStatePath())
+	defer o.bus.Subscribe(state)()
+	defer func() {
+		if err != nil {
+			o.publish(events.Event{Type: events.Error, Error: err.Error()})
+		}
+	}()
+	o.publish(events.Event{Type: events.BuildStarted, Time: startTime, Message: description})
+	
+	// Journal every write so the run can be undone
+	if o.fileWriter.ProjectRoot() != "" {
+		journal, err := filewriter.NewJournal(o.journalDir, o.runID, o.fileWriter.ProjectRoot(), description)
+		if err != nil {
+			return fmt.Errorf("failed to start run journal: %w", err)
+		}
+		o.fileWriter.SetJournal(journal)
+	}
+	
+	if o.repo != nil {
+		if err := o.repo.CreateBranch(o.BranchName()); err != nil {
+			return err
+		}
+	}
 	
 	// Step 0: Create project structure
-	ui.DisplayProgress("Creating project structure", 0, 10, startTime)
-	if err := o.fileWriter.CreateProjectStructure(); err != nil {
+	o.stage(events.StageStarted, "Creating project structure", 0, 10)
+	var layout []string
+	if o.stack != nil {
+		layout = o.stack.Layout()
+	}
+	if err := o.fileWriter.CreateProjectStructure(layout); err != nil {
 		return fmt.Errorf("failed to create project structure: %w", err)
 	}
-	ui.DisplayStageComplete("Creating project structure", 1, 10, startTime)
+	o.stage(events.StageCompleted, "Creating project structure", 1, 10)
 
 	// Step 1: Get planner to create the plan
-	ui.DisplayProgress("Planning project", 1, 10, startTime)
-	planner, err := o.registry.GetAgent(models.PlannerAgent)
+	o.stage(events.StageStarted, "Planning project", 1, 10)
+	tasks, err := o.createPlan(description)
 	if err != nil {
-		return fmt.Errorf("failed to get planner agent: %w", err)
+		return err
 	}
-
-	planPrompt := fmt.Sprintf(`Create a detailed execution plan for: "%s"
-
-Please structure your response as a numbered list of tasks that can be executed by specialized agents.
-For each task, specify:
-1. The task description
-2. Which agent should handle it (backend, frontend, security, etc.)
-3. Any dependencies on other tasks
-
-Format your response like this:
-1. [AGENT_TYPE] Task description
-2. [AGENT_TYPE] Task description (depends on task 1)
-...
-
-Available agents: backend, frontend, security, planner
-Focus on creating actionable, specific tasks that agents can execute independently.
-When providing code, use proper code blocks with filenames where possible.`, description)
-
-	planResponse, err := planner.Process("", planPrompt)
-	if err != nil {
-		return fmt.Errorf("failed to create plan: %w", err)
+	if err := o.checkBudget(); err != nil {
+		return err
 	}
-	ui.DisplayStageComplete("Planning project", 2, 10, startTime)
+	o.stage(events.StageCompleted, "Planning project", 2, 10)
 
 	// Step 2: Parse the plan into executable tasks
-	tasks := o.parsePlan(planResponse.Content)
 	if len(tasks) == 0 {
 		return fmt.Errorf("no executable tasks found in plan")
 	}
+	o.tasks = tasks
+	o.emitPlan(tasks)
 
 	totalStages := len(tasks) + 2 // +2 for structure creation and planning
 
-	// Step 3: Execute tasks in order
-	for i, task := range tasks {
+	// Step 3: Execute tasks in order. With controls, tasks can be skipped or
+	// queued to run again, so the order is kept as a list of task indexes.
+	order := make([]int, len(tasks))
+	for i := range order {
+		order[i] = i
+	}
+	for step := 0; ; step++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if o.controls != nil {
+			if err := o.controls.wait(); err != nil {
+				return err
+			}
+			order = o.queueRetries(tasks, order, step)
+		}
+		if step >= len(order) {
+			break
+		}
+		
+		i := order[step]
+		task := &tasks[i]
 		stageName := fmt.Sprintf("%s: %s", task.AgentType, task.Description[:min(40, len(task.Description))])
-		ui.DisplayProgress(stageName, i+3, totalStages, startTime)
+		
+		if o.controls != nil && o.controls.takeSkip(task.ID) {
+			o.skipTask(task)
+			continue
+		}
+		o.stage(events.StageStarted, stageName, i+3, totalStages)
 		
 		agent, err := o.registry.GetAgent(task.AgentType)
 		if err != nil {
-			ui.DisplayWarning(fmt.Sprintf("Skipping task - agent %s not available: %v", task.AgentType, err))
+			task.Status = "failed"
+			o.emitTaskFailed(task, fmt.Errorf("agent %s not available: %w", task.AgentType, err))
 			continue
 		}
 		
 		// Create context from previous results
 		context := o.buildContext(tasks[:i])
 		
-		response, err := agent.Process(context, task.Description)
-		if err != nil {
-			ui.DisplayError(fmt.Errorf("task failed: %w", err))
+		task.Status = "running"
+		o.emitTaskStarted(task)
+		response, interrupt, err := o.runTask(agent, context, task)
+		if interrupt != "" && response != nil {
+			// The tokens were spent even though the result is dropped
+			o.track(task, response)
+		}
+		switch {
+		case interrupt == interruptSkip:
+			o.skipTask(task)
+			continue
+		case interrupt == interruptRetry:
+			task.Status = "pending"
+			order = insertIndex(order, step+1, i)
+			continue
+		case err != nil && o.controls != nil && o.controls.isStopped():
+			return errBuildStopped
+		case err != nil && ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			task.Status = "failed"
+			o.emitTaskFailed(task, err)
 			continue
 		}
 
 		task.Result = response
 		task.Status = "completed"
+		o.track(task, response)
 		
 		// Extract and write any code blocks to files
-		o.writeGeneratedFiles(response.Content)
+		written := o.writeGeneratedFiles(response.Content)
+		
+		// Let the reviewer critique the output and the agent revise it
+		if o.config.Review.Enabled && len(written) > 0 && task.AgentType != o.config.Review.Agent {
+			o.stage(events.StageStarted, stageName+" (review)", i+3, totalStages)
+			written = o.reviewTask(task, agent, context, written)
+		}
+		
+		// The task is done when tests for its acceptance criteria pass
+		if o.config.Acceptance.Enabled && len(task.Acceptance) > 0 && len(written) > 0 {
+			o.stage(events.StageStarted, stageName+" (tests)", i+3, totalStages)
+			written = o.testTask(task, agent, context, written)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		
+		if o.repo != nil {
+			paths := make([]string, len(written))
+			for n, path := range written {
+				paths[n] = filepath.Join(o.fileWriter.ProjectRoot(), filepath.FromSlash(path))
+			}
+			if _, err := o.repo.Commit(o.commitMessage(task), paths...); err != nil {
+				o.warn(fmt.Sprintf("Failed to commit task output: %v", err))
+			}
+		}
+		
+		if task.Tests != nil && task.Tests.Status == models.TestsFailed {
+			task.Status = "failed"
+			o.emitTaskFailed(task, fmt.Errorf("acceptance tests failed: %s", task.Tests.Reason))
+		} else {
+			o.stage(events.StageCompleted, stageName, i+3, totalStages)
+			o.emitTaskCompleted(task, written)
+		}
 		
-		ui.DisplayStageComplete(stageName, i+3, totalStages, startTime)
+		if err := o.checkBudget(); err != nil {
+			return err
+		}
+	}
+
+	// Scan everything that was written for common security problems
+	var scanFindings []models.Finding
+	if !o.skipScan {
+		o.stage(events.StageStarted, "Scanning generated files", totalStages, totalStages)
+		scanFindings, err = o.scanGeneratedFiles()
+		if err != nil {
+			o.warn(fmt.Sprintf("Security scan failed: %v", err))
+		}
+		o.stage(events.StageCompleted, "Scanning generated files", totalStages, totalStages)
 	}
 
 	// Final results
-	cwd, _ := os.Getwd()
-	projectPath := filepath.Join(cwd, "generated-project")
-	ui.DisplayFinalResults(totalStages, startTime, projectPath)
+	o.findings = scanFindings
+	o.emitSummary(o.fileWriter.ProjectRoot(), totalStages, startTime, scanFindings, !o.skipScan)
 
 	return nil
 }
 
+// Usage returns the tokens and cost of the build so far
+func (o *Orchestrator) Usage() models.UsageSummary {
+	return o.usage.Summary()
+}
+
+// Tasks returns the planned tasks with their status, result and usage
+func (o *Orchestrator) Tasks() []Task {
+	return append([]Task(nil), o.tasks...)
+}
+
+// WrittenFiles returns the relative paths of the files the build wrote
+func (o *Orchestrator) WrittenFiles() []string {
+	return o.fileWriter.WrittenFiles()
+}
+
+// ScanFindings returns what the security scan found
+func (o *Orchestrator) ScanFindings() []models.Finding {
+	return o.findings
+}
+
+// process sends a message to an agent, cancelled with the build's context
+// when the agent supports it
+func (o *Orchestrator) process(agent models.Agent, taskContext, message string) (*models.Response, error) {
+	if streaming, ok := agent.(models.StreamingAgent); ok {
+		return streaming.ProcessStream(o.ctx, taskContext, message, nil)
+	}
+	return agent.Process(taskContext, message)
+}
+
+// track records a task's response in the usage ledger and the task's totals
+func (o *Orchestrator) track(task *Task, response *models.Response) {
+	task.Usage.Add(o.record(task.Description, task, response))
+}
+
+// checkBudget stops the build once the configured token or spend budget is
+// used up. Files written so far stay journaled and can be undone.
+func (o *Orchestrator) checkBudget() error {
+	if err := o.usage.CheckBudget(); err != nil {
+		summary := o.usage.Summary()
+		o.publish(events.Event{Type: events.BudgetExceeded, Error: err.Error(), Summary: &summary})
+		return fmt.Errorf("build aborted: %w", err)
+	}
+	return nil
+}
+
 // min returns the smaller of two integers
 func min(a, b int) int {
 	if a < b {
@@ -142,6 +365,68 @@ func min(a, b int) int {
 	return b
 }
 
+// Plan asks the planner to break description into tasks without building
+// anything. The plan is published like a build's.
+func (o *Orchestrator) Plan(ctx context.Context, description string) ([]Task, error) {
+	o.ctx = ctx
+	tasks, err := o.createPlan(description)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no executable tasks found in plan")
+	}
+	o.tasks = tasks
+	o.emitPlan(tasks)
+	return tasks, nil
+}
+
+// createPlan asks the planner for a plan of description and parses it
+func (o *Orchestrator) createPlan(description string) ([]Task, error) {
+	planner, err := o.registry.GetAgent(models.PlannerAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get planner agent: %w", err)
+	}
+
+	planResponse, err := o.process(planner, "", planPrompt(description, o.stack))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+	o.record("Plan", nil, planResponse)
+	return o.parsePlan(planResponse.Content), nil
+}
+
+// planPrompt asks the planner for a numbered list of agent tasks, within the
+// detected stack if there is one
+func planPrompt(description string, profile *stack.Profile) string {
+	var stackNote string
+	if profile != nil && !profile.Empty() {
+		stackNote = fmt.Sprintf("\n\nThe project's detected stack is %s. Plan tasks in this stack rather than introducing new languages or frameworks.", profile.Summary())
+		if profile.Commands.Test != "" {
+			stackNote += fmt.Sprintf(" Tests run with `%s`.", profile.Commands.Test)
+		}
+	}
+	return fmt.Sprintf(`Create a detailed execution plan for: "%s"
+
+Please structure your response as a numbered list of tasks that can be executed by specialized agents.
+For each task, specify:
+1. The task description
+2. Which agent should handle it (backend, frontend, security, etc.)
+3. Any dependencies on other tasks
+4. For tasks that produce code, acceptance criteria that automated tests can check
+
+Format your response like this:
+1. [AGENT_TYPE] Task description
+   Acceptance: first testable criterion; second testable criterion
+2. [AGENT_TYPE] Task description (depends on task 1)
+   Acceptance: testable criterion
+...
+
+Available agents: backend, frontend, security, reviewer, planner
+Focus on creating actionable, specific tasks that agents can execute independently.
+When providing code, use proper code blocks with filenames where possible.%s`, description, stackNote)
+}
+
 // parsePlan extracts executable tasks from the planner's response
 func (o *Orchestrator) parsePlan(planContent string) []Task {
 	var tasks []Task
@@ -149,6 +434,9 @@ func (o *Orchestrator) parsePlan(planContent string) []Task {
 	// Regex to match task lines like "1. [BACKEND] Create API endpoints" or "1. **[BACKEND]** Create API endpoints"
 	taskRegex := regexp.MustCompile(`(?i)^\d+\.\s*\*?\*?\[(\w+)\]\*?\*?\s*(.+?)(?:\s*` + "```" + `|\s*$)`)
 	
+	// Criteria follow their task, e.g. "Acceptance: GET /todos returns 200; POST /todos without a title returns 400"
+	acceptanceRegex := regexp.MustCompile(`(?i)^(?:[-*]\s*)?\*{0,2}acceptance(?:\s+criteria)?\*{0,2}\s*:\*{0,2}\s*(.+)$`)
+	
 	lines := strings.Split(planContent, "\n")
 	taskID := 1
 	
@@ -158,6 +446,14 @@ func (o *Orchestrator) parsePlan(planContent string) []Task {
 			continue
 		}
 		
+		if matches := acceptanceRegex.FindStringSubmatch(line); matches != nil {
+			if len(tasks) > 0 {
+				last := &tasks[len(tasks)-1]
+				last.Acceptance = append(last.Acceptance, splitCriteria(matches[1])...)
+			}
+			continue
+		}
+		
 		matches := taskRegex.FindStringSubmatch(line)
 		if len(matches) >= 3 {
 			agentName := strings.ToLower(matches[1])
@@ -186,6 +482,18 @@ func (o *Orchestrator) parsePlan(planContent string) []Task {
 	return tasks
 }
 
+// splitCriteria splits a line of acceptance criteria at semicolons
+func splitCriteria(line string) []string {
+	var criteria []string
+	for _, criterion := range strings.Split(line, ";") {
+		criterion = strings.TrimSuffix(strings.TrimSpace(criterion), ".")
+		if criterion != "" {
+			criteria = append(criteria, criterion)
+		}
+	}
+	return criteria
+}
+
 // mapAgentName maps agent names from plan to agent types
 func (o *Orchestrator) mapAgentName(name string) models.AgentType {
 	switch strings.ToLower(name) {
@@ -197,6 +505,8 @@ func (o *Orchestrator) mapAgentName(name string) models.AgentType {
 		return models.SecurityAgent
 	case "planner":
 		return models.PlannerAgent
+	case "reviewer":
+		return models.ReviewerAgent
 	default:
 		return ""
 	}
@@ -230,9 +540,23 @@ func (o *Orchestrator) truncateText(text string, maxLen int) string {
 	return text[:maxLen] + "..."
 }
 
-// writeGeneratedFiles extracts code blocks and writes them to files
-func (o *Orchestrator) writeGeneratedFiles(content string) {
-	codeBlocks := o.fileWriter.ExtractCodeBlocks(content)
+// commitMessage describes a completed task for its git commit
+func (o *Orchestrator) commitMessage(task *Task) string {
+	return fmt.Sprintf("go-code(%s): %s\n\nRun: %s\nAgent: %s\nTask: %s\nTokens: %d\n",
+		task.AgentType, o.truncateText(task.Description, 60),
+		o.runID, task.AgentType, task.Description, task.Usage.TotalTokens())
+}
+
+// writeGeneratedFiles extracts code blocks and writes them to files,
+// returning the relative paths of the files that were written
+func (o *Orchestrator) writeGeneratedFiles(content string) []string {
+	return o.writeCodeBlocks(o.fileWriter.ExtractCodeBlocks(content))
+}
+
+// writeCodeBlocks writes code blocks keyed by filename, returning the
+// relative paths of the files that were written
+func (o *Orchestrator) writeCodeBlocks(codeBlocks map[string]string) []string {
+	var written []string
 	
 	for filename, code := range codeBlocks {
 		// Skip empty code blocks
@@ -241,8 +565,13 @@ func (o *Orchestrator) writeGeneratedFiles(content string) {
 		}
 		
 		if err := o.fileWriter.WriteFile(filename, code); err != nil {
-			fmt.Printf("⚠️  Failed to write %s: %v\n", filename, err)
+			o.publish(events.Event{Type: events.Error, Path: filename, Error: err.Error()})
+			continue
 		}
+		o.publish(events.Event{Type: events.FileWritten, Path: filename})
+		written = append(written, filename)
 	}
+	
+	return written
 }
 
@@ -0,0 +1,568 @@
+package orchestrator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/agents"
+	"go-code/internal/api"
+	"go-code/internal/events"
+	"go-code/internal/filewriter"
+	"go-code/internal/git"
+	"go-code/internal/llmtest"
+	"go-code/internal/stack"
+	"go-code/internal/usage"
+	"go-code/pkg/models"
+)
+
+func TestParsePlan(t *testing.T) {
+	plan := `Here is the plan:
+
+1. [BACKEND] Create REST API endpoints for todos
+2. **[FRONTEND]** Build the todo list page.
+3. [Security] Review input validation:
+4. [DEVOPS] Write a Dockerfile
+5. [REVIEWER] Review the code ` + "```" + `
+Some notes that are not tasks.
+- [BACKEND] bullet points are ignored`
+
+	tasks := (&Orchestrator{}).parsePlan(plan)
+
+	want := []struct {
+		agent       models.AgentType
+		description string
+	}{
+		{models.BackendAgent, "Create REST API endpoints for todos"},
+		{models.FrontendAgent, "Build the todo list page"},
+		{models.SecurityAgent, "Review input validation"},
+		{models.ReviewerAgent, "Review the code"},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("got %d tasks %+v, want %d", len(tasks), tasks, len(want))
+	}
+	for i, w := range want {
+		if tasks[i].AgentType != w.agent || tasks[i].Description != w.description {
+			t.Errorf("task %d = [%s] %q, want [%s] %q", i+1, tasks[i].AgentType, tasks[i].Description, w.agent, w.description)
+		}
+		if tasks[i].Status != "pending" {
+			t.Errorf("task %d status = %q, want pending", i+1, tasks[i].Status)
+		}
+	}
+	if tasks[3].ID != "task_4" {
+		t.Errorf("last task ID = %q, want task_4", tasks[3].ID)
+	}
+}
+
+func TestParseFindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"fenced json", "Findings:\n```json\n[{\"file\": \"app.js\", \"severity\": \"HIGH\", \"message\": \"no auth\"}]\n```", 1, false},
+		{"bare array", `[{"file": "a.js", "severity": "low", "message": "x"}, {"file": "b.js", "severity": "medium", "message": "y"}]`, 2, false},
+		{"no findings", "[]", 0, false},
+		{"no array", "Looks good to me!", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := parseFindings(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(findings) != tt.want {
+				t.Fatalf("got %d findings, want %d", len(findings), tt.want)
+			}
+			for _, f := range findings {
+				if f.Severity != models.Severity(strings.ToLower(string(f.Severity))) {
+					t.Errorf("severity %q was not lower-cased", f.Severity)
+				}
+			}
+		})
+	}
+}
+
+func TestReviewPrompt(t *testing.T) {
+	o := &Orchestrator{fileWriter: filewriter.NewWithSink(filewriter.NewMemorySink())}
+	o.fileWriter.WriteFile("todo.go", "package todo")
+
+	task := &Task{Description: "Create the todo API"}
+	prompt := o.reviewPrompt(task, []string{"todo.go"})
+	if !strings.Contains(prompt, "Acceptance criteria: "+genericCriteria) || !strings.Contains(prompt, "package todo") {
+		t.Errorf("prompt without criteria = %q", prompt)
+	}
+
+	task.Acceptance = []string{"GET /todos lists todos", "POST /todos rejects an empty title"}
+	prompt = o.reviewPrompt(task, []string{"todo.go"})
+	if !strings.Contains(prompt, "Acceptance criteria:\n- GET /todos lists todos\n- POST /todos rejects an empty title\n") {
+		t.Errorf("prompt does not list the task's criteria: %q", prompt)
+	}
+}
+
+// scriptedTeam answers the planner with a two-task plan and the other agents
+// with code blocks named after their role
+func scriptedTeam(req api.ChatRequest) llmtest.Reply {
+	system := llmtest.SystemPrompt(req)
+	switch {
+	case strings.HasPrefix(system, "You are the Planner Agent"):
+		return llmtest.Reply{Content: "1. [BACKEND] Create the todo API\n2. [FRONTEND] Build the todo page"}
+	case strings.HasPrefix(system, "You are the Backend Agent"):
+		return llmtest.Reply{Content: "```js\n// filename: routes/todos.js\nmodule.exports = {};\n```"}
+	case strings.HasPrefix(system, "You are the Frontend Agent"):
+		return llmtest.Reply{Content: "```html\n<!-- public/index.html -->\n<ul id=\"todos\"></ul>\n```"}
+	}
+	return llmtest.Reply{Status: 500, Error: "unexpected agent"}
+}
+
+// chdir changes into dir for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func TestExecuteBuild(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	work := t.TempDir()
+	chdir(t, work)
+
+	server := llmtest.NewServer(t)
+	server.Handle(scriptedTeam)
+
+	config := models.DefaultConfig()
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	probe := &events.Recorder{}
+	o.Events().Subscribe(probe)
+
+	if err := o.ExecuteBuild("a todo app"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("server saw %d requests, want plan + 2 tasks", len(requests))
+	}
+	if !strings.Contains(llmtest.UserMessage(requests[0]), `Create a detailed execution plan for: "a todo app"`) {
+		t.Errorf("first request was not the plan: %q", llmtest.UserMessage(requests[0]))
+	}
+	if !strings.Contains(llmtest.UserMessage(requests[2]), "Build the todo page") {
+		t.Errorf("frontend request = %q", llmtest.UserMessage(requests[2]))
+	}
+
+	for path, want := range map[string]string{
+		"routes/todos.js":   "module.exports = {};",
+		"public/index.html": `<ul id="todos"></ul>`,
+	} {
+		data, err := os.ReadFile(filepath.Join(work, "generated-project", path))
+		if err != nil {
+			t.Errorf("%s was not written: %v", path, err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s = %q, want it to contain %q", path, data, want)
+		}
+	}
+
+	summary := o.Usage()
+	if summary.Total.Requests != 3 || len(summary.ByTask) != 3 {
+		t.Errorf("usage = %d requests, %d tasks, want 3 and 3", summary.Total.Requests, len(summary.ByTask))
+	}
+	entries, err := usage.Read(usage.DefaultLedgerPath(), time.Time{})
+	if err != nil {
+		t.Fatalf("reading ledger: %v", err)
+	}
+	if len(entries) != 3 || entries[0].RunID != o.RunID() {
+		t.Errorf("ledger has %d entries for run %q, want 3 for %q", len(entries), firstRunID(entries), o.RunID())
+	}
+
+	var types []string
+	for _, event := range probe.Events(events.BuildStarted, events.PlanCreated, events.TaskStarted, events.TaskCompleted, events.FileWritten, events.Summary) {
+		types = append(types, string(event.Type))
+	}
+	want := "build_started,plan,task_started,file_written,task_completed,task_started,file_written,task_completed,summary"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	if used := probe.Events(events.TokensUsed); len(used) != 3 || used[2].Summary.Total.Requests != 3 {
+		t.Errorf("got %d tokens_used events, want one per request with a running total", len(used))
+	}
+
+	state, err := events.LoadState(o.RunStatePath())
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if state.Status != events.RunCompleted || state.Description != "a todo app" || len(state.Tasks) != 2 || len(state.Files) != 2 {
+		t.Errorf("run state = %+v", state)
+	}
+	for _, task := range state.Tasks {
+		if task.Status != "completed" {
+			t.Errorf("task %s status = %q, want completed", task.ID, task.Status)
+		}
+	}
+}
+
+func firstRunID(entries []usage.Entry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	return entries[0].RunID
+}
+
+func TestExecuteBuildCommits(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git is not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	work := t.TempDir()
+	chdir(t, work)
+
+	repo, err := git.Init(work)
+	if err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	server := llmtest.NewServer(t)
+	server.Handle(scriptedTeam)
+
+	config := models.DefaultConfig()
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	o.EnableGit(repo)
+
+	if err := o.ExecuteBuild("a todo app"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+
+	if branch, err := repo.CurrentBranch(); err != nil || branch != "go-code/"+o.RunID() {
+		t.Errorf("branch = %q, %v, want go-code/%s", branch, err, o.RunID())
+	}
+	log, err := exec.Command("git", "-C", work, "log", "--format=%s|%b", "--name-only").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	commits := strings.Split(strings.TrimSpace(string(log)), "go-code(")
+	if len(commits) != 3 {
+		t.Fatalf("git log = %s, want one commit per task", log)
+	}
+	for i, want := range []struct{ subject, file string }{
+		{"frontend): Build the todo page|Run: " + o.RunID(), "generated-project/public/index.html"},
+		{"backend): Create the todo API|Run: " + o.RunID(), "generated-project/routes/todos.js"},
+	} {
+		commit := commits[i+1]
+		if !strings.HasPrefix(commit, want.subject) || !strings.Contains(commit, "\n"+want.file) {
+			t.Errorf("commit %d = %q, want %q with %s", i+1, commit, want.subject, want.file)
+		}
+	}
+}
+
+func TestExecuteBuildBudget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	chdir(t, t.TempDir())
+
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		reply := scriptedTeam(req)
+		reply.PromptTokens, reply.CompletionTokens = 500, 500
+		return reply
+	})
+
+	config := models.DefaultConfig()
+	config.Budget.MaxTokens = 1500
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	probe := &events.Recorder{}
+	o.Events().Subscribe(probe)
+
+	err := o.ExecuteBuild("a todo app")
+	if err == nil || !strings.Contains(err.Error(), "build aborted") {
+		t.Fatalf("ExecuteBuild error = %v, want a budget abort", err)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want the build to stop after the first task", n)
+	}
+
+	types := probe.Types()
+	if n := len(types); n < 2 || types[n-2] != events.BudgetExceeded || types[n-1] != events.Error {
+		t.Errorf("events = %v, want budget_exceeded then error at the end", types)
+	}
+	state, err := events.LoadState(o.RunStatePath())
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if state.Status != events.RunFailed || !strings.Contains(state.Error, "build aborted") {
+		t.Errorf("run state = %s (%s), want failed with the budget error", state.Status, state.Error)
+	}
+}
+
+func TestExecuteBuildControls(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	chdir(t, t.TempDir())
+
+	server := llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		if strings.HasPrefix(llmtest.SystemPrompt(req), "You are the Planner Agent") {
+			return llmtest.Reply{Content: "1. [BACKEND] Create the API\n2. [FRONTEND] Build the page\n3. [BACKEND] Add tests"}
+		}
+		return llmtest.Reply{Content: "Done with: " + llmtest.UserMessage(req)}
+	})
+
+	config := models.DefaultConfig()
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	o.EnableStreaming()
+
+	controls := NewControls()
+	controls.Skip("task_2")
+	controls.Retry("task_1")
+	o.EnableControls(controls)
+
+	if err := o.ExecuteBuild("a todo app"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+
+	var messages []string
+	for _, req := range server.Requests()[1:] {
+		if !req.Stream {
+			t.Errorf("task request for %q was not streamed", llmtest.UserMessage(req))
+		}
+		messages = append(messages, llmtest.UserMessage(req))
+	}
+	want := []string{"Create the API", "Create the API", "Add tests"}
+	if len(messages) != len(want) {
+		t.Fatalf("task requests = %q, want %q", messages, want)
+	}
+	for i := range want {
+		if !strings.HasSuffix(messages[i], want[i]) {
+			t.Errorf("request %d = %q, want it to end with %q", i+1, messages[i], want[i])
+		}
+	}
+}
+
+func TestParsePlanAcceptance(t *testing.T) {
+	plan := `Acceptance: criteria before any task are ignored
+1. [BACKEND] Create the todo API
+   Acceptance: GET /todos returns 200; POST /todos without a title returns 400.
+   - **Acceptance criteria:** DELETE /todos/1 returns 204
+2. [FRONTEND] Build the todo page`
+
+	tasks := (&Orchestrator{}).parsePlan(plan)
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks %+v, want 2", len(tasks), tasks)
+	}
+	want := []string{"GET /todos returns 200", "POST /todos without a title returns 400", "DELETE /todos/1 returns 204"}
+	if strings.Join(tasks[0].Acceptance, "|") != strings.Join(want, "|") {
+		t.Errorf("acceptance = %q, want %q", tasks[0].Acceptance, want)
+	}
+	if tasks[1].Acceptance != nil {
+		t.Errorf("task 2 acceptance = %q, want none", tasks[1].Acceptance)
+	}
+}
+
+// acceptanceTeam plans a Go task with acceptance criteria. The backend
+// first writes a broken Add, the tests catch it and the fix passes them. The
+// test writer also tries to fix Add itself, which must be refused.
+func acceptanceTeam(req api.ChatRequest) llmtest.Reply {
+	message := llmtest.UserMessage(req)
+	switch {
+	case strings.HasPrefix(llmtest.SystemPrompt(req), "You are the Planner Agent"):
+		return llmtest.Reply{Content: "1. [BACKEND] Write an Add function\n   Acceptance: Add(2, 3) returns 5"}
+	case strings.Contains(message, "Write automated acceptance tests"):
+		return llmtest.Reply{Content: "```go\n// filename: calc/calc_test.go\npackage calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif got := Add(2, 3); got != 5 {\n\t\tt.Fatalf(\"Add(2, 3) = %d\", got)\n\t}\n}\n```\n\n" +
+			"```go\n// filename: calc/calc.go\npackage calc\n\nfunc Add(a, b int) int { return 5 }\n```\n\n```go\n// filename: calc/helpers.go\npackage calc\n\nfunc init() {}\n```"}
+	case strings.Contains(message, "acceptance tests for your previous output fail"):
+		return llmtest.Reply{Content: "```go\n// filename: calc/calc.go\npackage calc\n\nfunc Add(a, b int) int { return a + b }\n```"}
+	}
+	return llmtest.Reply{Content: "```\n// filename: go.mod\nmodule calc\n\ngo 1.21\n```\n\n```go\n// filename: calc/calc.go\npackage calc\n\nfunc Add(a, b int) int { return a - b }\n```"}
+}
+
+// goTestEnv reports whether the go command can run generated tests, and
+// keeps it on the local toolchain and the user's build cache, which a
+// temporary HOME would otherwise replace with an empty one
+func goTestEnv(t *testing.T) bool {
+	t.Helper()
+	cache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		return false
+	}
+	t.Setenv("GOCACHE", strings.TrimSpace(string(cache)))
+	t.Setenv("GOTOOLCHAIN", "local")
+	t.Setenv("GOFLAGS", "")
+	return true
+}
+
+func TestAcceptanceTests(t *testing.T) {
+	if !goTestEnv(t) {
+		t.Skip("needs the go command")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	work := t.TempDir()
+	chdir(t, work)
+
+	server := llmtest.NewServer(t)
+	server.Handle(acceptanceTeam)
+
+	config := models.DefaultConfig()
+	config.RequireCommandPermission = false
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	probe := &events.Recorder{}
+	o.Events().Subscribe(probe)
+
+	if err := o.ExecuteBuild("a calculator"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+
+	if n := len(server.Requests()); n != 4 {
+		t.Errorf("server saw %d requests, want plan, task, tests and one fix", n)
+	}
+	task := o.Tasks()[0]
+	tests := task.Tests
+	if task.Status != "completed" || tests == nil {
+		t.Fatalf("task = %s with tests %+v", task.Status, tests)
+	}
+	if tests.Status != models.TestsPassed || tests.Attempts != 2 || tests.Command != "go test ./..." || tests.Files[0] != "calc/calc_test.go" || tests.Criteria[0] != "Add(2, 3) returns 5" {
+		t.Errorf("tests = %+v", tests)
+	}
+	fix := llmtest.UserMessage(server.Requests()[3])
+	if !strings.Contains(fix, "go test ./... exited with status 1") || !strings.Contains(fix, "Add(2, 3) = -1") {
+		t.Errorf("fix request lacks the failing output:\n%s", fix)
+	}
+
+	completed := probe.Events(events.TaskCompleted)
+	if len(completed) != 1 || completed[0].Tests == nil || completed[0].Tests.Status != models.TestsPassed {
+		t.Errorf("task_completed events = %+v", completed)
+	}
+	if plan := probe.Events(events.PlanCreated); len(plan) != 1 || len(plan[0].Tasks[0].Acceptance) != 1 {
+		t.Errorf("plan events = %+v", plan)
+	}
+
+	// The test writer's own Add and non-test file were refused
+	if len(tests.Files) != 1 {
+		t.Errorf("test files = %q, want only calc/calc_test.go", tests.Files)
+	}
+	if _, err := os.Stat(filepath.Join(work, "generated-project", "calc", "helpers.go")); !os.IsNotExist(err) {
+		t.Errorf("a non-test file from the test writer was written: %v", err)
+	}
+	warned := false
+	for _, event := range probe.Events(events.Warning) {
+		warned = warned || strings.Contains(event.Message, "calc/calc.go, calc/helpers.go")
+	}
+	if !warned {
+		t.Error("refusing the test writer's files was not reported")
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		language string
+		name     string
+		want     bool
+	}{
+		{stack.Go, "calc/calc_test.go", true},
+		{stack.Go, "calc/testdata/input.json", true},
+		{stack.Go, "calc/calc.go", false},
+		{stack.Go, "calc_test.py", false},
+		{stack.JavaScript, "src/app.test.js", true},
+		{stack.JavaScript, "src/app.spec.mjs", true},
+		{stack.TypeScript, "src/app.test.tsx", true},
+		{stack.TypeScript, "src/__tests__/app.ts", true},
+		{stack.TypeScript, "src/app.ts", false},
+		{stack.JavaScript, "jest.config.js", false},
+		{stack.Python, "tests/test_api.py", true},
+		{stack.Python, "tests/__init__.py", true},
+		{stack.Python, "app/api_test.py", true},
+		{stack.Python, "conftest.py", true},
+		{stack.Python, "app/api.py", false},
+		{stack.Rust, "tests/api.rs", true},
+		{stack.Rust, "src/lib.rs", false},
+		{"", "./pkg/x_test.go", true},
+		{"", "src/app.test.ts", true},
+		{"", "main.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTestFile(tt.language, tt.name); got != tt.want {
+			t.Errorf("isTestFile(%q, %q) = %v, want %v", tt.language, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptanceTestsFailOrNotRun(t *testing.T) {
+	hasGo := goTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	chdir(t, t.TempDir())
+
+	server := llmtest.NewServer(t)
+	server.Handle(acceptanceTeam)
+
+	// go isn't allowed, so no tests are written or run
+	config := models.DefaultConfig()
+	config.AllowedCommands = []string{"npm"}
+	o := New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	if err := o.ExecuteBuild("a calculator"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+	task := o.Tasks()[0]
+	if task.Status != "completed" || task.Tests.Status != models.TestsNotRun || !strings.Contains(task.Tests.Reason, "config commands add go") {
+		t.Errorf("task = %s with tests %+v", task.Status, task.Tests)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want no test writing", n)
+	}
+
+	// Failing tests fail the task once the fix rounds are used up
+	if !hasGo {
+		return
+	}
+	server = llmtest.NewServer(t)
+	server.Handle(func(req api.ChatRequest) llmtest.Reply {
+		if strings.Contains(llmtest.UserMessage(req), "acceptance tests for your previous output fail") {
+			return llmtest.Reply{Content: "I could not fix it."}
+		}
+		return acceptanceTeam(req)
+	})
+	config = models.DefaultConfig()
+	config.RequireCommandPermission = false
+	o = New(agents.NewRegistry(server.Client(), config), config)
+	o.DisableSecurityScan()
+	probe := &events.Recorder{}
+	o.Events().Subscribe(probe)
+	if err := o.ExecuteBuild("a calculator"); err != nil {
+		t.Fatalf("ExecuteBuild: %v", err)
+	}
+	task = o.Tasks()[0]
+	if task.Status != "failed" || task.Tests.Status != models.TestsFailed || task.Tests.Attempts != 2 || task.Tests.ExitCode != 1 {
+		t.Errorf("task = %s with tests %+v", task.Status, task.Tests)
+	}
+	failed := probe.Events(events.TaskFailed)
+	if len(failed) != 1 || failed[0].Tests == nil || !strings.Contains(failed[0].Error, "acceptance tests failed") {
+		t.Errorf("task_failed events = %+v", failed)
+	}
+}
@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go-code/pkg/models"
+)
+
+// maxReviewFileChars caps how much of each file is sent to the reviewer
+const maxReviewFileChars = 6000
+
+// reviewTask runs the review loop for a completed coding task. The reviewer
+// checks the written files against the task, and the original agent revises
+// them until the reviewer has no findings or the configured rounds run out.
+// It returns the full set of files written for the task.
+func (o *Orchestrator) reviewTask(task *Task, agent models.Agent, context string, written []string) []string {
+	reviewer, err := o.registry.GetAgent(o.config.Review.Agent)
+	if err != nil {
+		o.warn(fmt.Sprintf("Skipping review - agent %s not available: %v", o.config.Review.Agent, err))
+		return written
+	}
+
+	files := written
+	for round := 1; ; round++ {
+		task.ReviewRounds = round
+
+		review, err := o.process(reviewer, "", o.reviewPrompt(task, files))
+		if err != nil {
+			o.warn(fmt.Sprintf("Review failed: %v", err))
+			return files
+		}
+		o.track(task, review)
+
+		findings, err := parseFindings(review.Content)
+		if err != nil {
+			o.warn(fmt.Sprintf("Could not parse review findings: %v", err))
+			return files
+		}
+		for i := range findings {
+			findings[i].Round = round
+		}
+		task.Findings = append(task.Findings, findings...)
+
+		if len(findings) == 0 {
+			task.ReviewPassed = true
+			return files
+		}
+
+		// Out of revision rounds or budget, leave the remaining findings for the report
+		if round > o.config.Review.Rounds || o.usage.CheckBudget() != nil {
+			return files
+		}
+
+		revision, err := o.process(agent, context, o.revisionPrompt(task, findings, files))
+		if err != nil {
+			o.warn(fmt.Sprintf("Revision failed: %v", err))
+			return files
+		}
+		o.track(task, revision)
+		task.Result = revision
+
+		files = mergePaths(files, o.writeGeneratedFiles(revision.Content))
+	}
+}
+
+// genericCriteria are reviewed for tasks the planner gave no acceptance
+// criteria
+const genericCriteria = "the files completely and correctly implement the task, handle errors, and are safe to run."
+
+// reviewPrompt asks the reviewer to check the task's files against its
+// acceptance criteria
+func (o *Orchestrator) reviewPrompt(task *Task, files []string) string {
+	criteria := " " + genericCriteria
+	if len(task.Acceptance) > 0 {
+		criteria = "\n" + bulletList(task.Acceptance) + "\nAlso check that " + genericCriteria
+	}
+
+	return fmt.Sprintf(`Review the files generated for this task.
+
+Task: %s
+Acceptance criteria:%s
+
+%s
+Respond ONLY with a JSON array of findings (severity, file, line, message, fix). Return [] if there are no problems.`,
+		task.Description, criteria, o.fileSnapshot(files))
+}
+
+// revisionPrompt asks the original agent to address the reviewer's findings
+func (o *Orchestrator) revisionPrompt(task *Task, findings []models.Finding, files []string) string {
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("- [%s] %s:%d %s (fix: %s)", f.Severity, f.File, f.Line, f.Message, f.Fix))
+	}
+
+	return fmt.Sprintf(`Your previous output for this task was reviewed.
+
+Task: %s
+
+Review findings:
+%s
+
+%s
+Revise the files to address every finding. Return the complete updated files as code blocks with their filenames.`,
+		task.Description, strings.Join(lines, "\n"), o.fileSnapshot(files))
+}
+
+// fileSnapshot renders the current content of files for a prompt
+func (o *Orchestrator) fileSnapshot(files []string) string {
+	var b strings.Builder
+	b.WriteString("Files:\n")
+
+	for _, path := range files {
+		data, err := o.fileWriter.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n```\n%s\n```\n", filepath.ToSlash(path), o.truncateText(string(data), maxReviewFileChars))
+	}
+
+	return b.String()
+}
+
+// parseFindings extracts the JSON findings array from a reviewer response
+func parseFindings(content string) ([]models.Finding, error) {
+	raw := content
+
+	// Prefer a fenced JSON block if the model wrapped its answer
+	if matches := regexp.MustCompile("(?s)```(?:json)?\\s*\\n(.*?)\\n```").FindStringSubmatch(content); len(matches) > 1 {
+		raw = matches[1]
+	}
+
+	start := strings.Index(raw, "[")
+	end := strings.LastIndex(raw, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no findings array in response")
+	}
+
+	var findings []models.Finding
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &findings); err != nil {
+		return nil, err
+	}
+
+	for i := range findings {
+		findings[i].Severity = models.Severity(strings.ToLower(string(findings[i].Severity)))
+	}
+
+	return findings, nil
+}
+
+// mergePaths appends the paths in extra that are not already in paths
+func mergePaths(paths, extra []string) []string {
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		seen[p] = true
+	}
+	for _, p := range extra {
+		if !seen[p] {
+			paths = append(paths, p)
+			seen[p] = true
+		}
+	}
+	return paths
+}
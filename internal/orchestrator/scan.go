@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go-code/internal/events"
+	"go-code/internal/scanner"
+	"go-code/pkg/models"
+)
+
+// DisableSecurityScan turns off the offline scan run after the build
+func (o *Orchestrator) DisableSecurityScan() {
+	o.skipScan = true
+}
+
+// SetSARIFPath overrides where the scan's SARIF report is written
+func (o *Orchestrator) SetSARIFPath(path string) {
+	o.sarifPath = path
+}
+
+// SARIFPath returns where the scan's SARIF report is written
+func (o *Orchestrator) SARIFPath() string {
+	if o.sarifPath != "" {
+		return o.sarifPath
+	}
+	return filepath.Join(o.journalDir, o.runID, "security.sarif")
+}
+
+// scanGeneratedFiles runs the static scanner over everything written during
+// the build, saves a SARIF report and asks the security agent to triage
+// whatever it found
+func (o *Orchestrator) scanGeneratedFiles() ([]models.Finding, error) {
+	findings, err := scanner.New().ScanSink(o.fileWriter.Sink(), o.fileWriter.WrittenFiles())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := scanner.WriteSARIFFile(o.SARIFPath(), findings); err != nil {
+		return findings, err
+	}
+
+	if len(findings) == 0 {
+		return findings, nil
+	}
+
+	security, err := o.registry.GetAgent(models.SecurityAgent)
+	if err != nil {
+		return findings, nil // Report the raw findings without triage
+	}
+
+	triage, err := o.process(security, "", triagePrompt(findings))
+	if err != nil {
+		o.warn(fmt.Sprintf("Security triage failed: %v", err))
+		return findings, nil
+	}
+	o.record("Security triage", nil, triage)
+	o.publish(events.Event{Type: events.AgentResponse, Agent: models.SecurityAgent, Task: "Security triage", Response: triage})
+
+	return findings, nil
+}
+
+// triagePrompt asks the security agent to review the scanner's findings
+func triagePrompt(findings []models.Finding) string {
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("- %s [%s] %s:%d %s", f.Rule, f.Severity, f.File, f.Line, f.Message))
+	}
+
+	return fmt.Sprintf(`A static security scan of the generated project reported these findings:
+
+%s
+
+Triage them: flag likely false positives, rank the real issues by risk, and give the concrete fix for each.`,
+		strings.Join(lines, "\n"))
+}
@@ -0,0 +1,134 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"go-code/internal/stack"
+)
+
+// ConventionsFile is the file, relative to the project directory, whose
+// contents are added to the conventions of every agent
+const ConventionsFile = ".go-code/conventions.md"
+
+// Limits that keep the facts a small part of every prompt
+const (
+	maxTreeDepth   = 3
+	maxTreeEntries = 150
+	maxReadmeBytes = 4000
+)
+
+// skippedDirs are left out of the directory tree
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// Gather collects the facts of the project in root. target is where
+// generated files are written and conventions are the configured ones; the
+// contents of root's ConventionsFile are added to them. A stack that can't
+// be detected, e.g. because of a broken manifest, is left out.
+func Gather(root, target string, conventions []string) (*Facts, error) {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	facts := &Facts{Root: root, Target: target, Tree: tree(root), Readme: readme(root)}
+	if profile, err := stack.Detect(root); err == nil {
+		facts.Profile = profile
+		facts.Stack = profile.Summary()
+	}
+
+	var parts []string
+	for _, convention := range conventions {
+		if convention = strings.TrimSpace(convention); convention != "" {
+			parts = append(parts, "- "+convention)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(ConventionsFile)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", ConventionsFile, err)
+	}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		parts = append(parts, text)
+	}
+	facts.Conventions = strings.Join(parts, "\n")
+
+	return facts, nil
+}
+
+// tree lists root's files and directories, indented by depth. Hidden and
+// dependency directories are left out, and the listing stops at
+// maxTreeDepth levels and maxTreeEntries lines.
+func tree(root string) string {
+	var lines []string
+	var walk func(dir string, depth int) bool
+	walk = func(dir string, depth int) bool {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return true
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") || (entry.IsDir() && skippedDirs[name]) {
+				continue
+			}
+			if len(lines) == maxTreeEntries {
+				lines = append(lines, "...")
+				return false
+			}
+			indent := strings.Repeat("  ", depth)
+			if !entry.IsDir() {
+				lines = append(lines, indent+name)
+				continue
+			}
+			lines = append(lines, indent+name+"/")
+			if depth+1 < maxTreeDepth && !walk(filepath.Join(dir, name), depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(root, 0)
+	return strings.Join(lines, "\n")
+}
+
+// readme returns the start of root's README, if it has one
+func readme(root string) string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if entry.IsDir() || (name != "readme" && !strings.HasPrefix(name, "readme.")) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return ""
+		}
+		return truncate(strings.TrimSpace(string(data)), maxReadmeBytes)
+	}
+	return ""
+}
+
+// truncate shortens text to at most max bytes, on a rune boundary
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "\n[...]"
+}
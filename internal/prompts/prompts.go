@@ -0,0 +1,129 @@
+// Package prompts renders the prompts sent to agents. System prompts are
+// text/templates with access to facts about the project being worked on: its
+// stack, directory tree, README, conventions and the directory generated
+// files go to.
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"go-code/internal/stack"
+)
+
+// Facts describes the project agents work on. Empty fields are left out of
+// prompts.
+type Facts struct {
+	// Root is the project directory
+	Root string
+	// Target is where generated files are written
+	Target string
+	// Stack names the languages and frameworks the project uses
+	Stack string
+	// Profile is the detected stack in detail, or nil if detection failed
+	Profile *stack.Profile
+	// Tree is an indented listing of the project directory
+	Tree string
+	// Readme is the start of the project's README
+	Readme string
+	// Conventions are the project's rules for generated code, from the
+	// conventions setting and .go-code/conventions.md
+	Conventions string
+}
+
+// projectTemplate renders the facts as sections of a system prompt. System
+// prompts include it with {{template "project" .}}.
+var projectTemplate = template.Must(template.New("project").Parse(`{{if or .Root .Target .Stack}}
+
+## Project{{end}}{{with .Root}}
+Project directory: {{.}}{{end}}{{with .Target}}
+Generated files are written to: {{.}}{{end}}{{with .Stack}}
+Stack: {{.}}{{end}}{{with .Profile}}{{with .Commands.Test}}
+Tests run with: {{.}}{{end}}{{end}}{{with .Conventions}}
+
+## Conventions
+Follow these project conventions; they take precedence over your usual choices.
+{{.}}{{end}}{{with .Tree}}
+
+## Directory tree
+` + "```" + `
+{{.}}
+` + "```" + `{{end}}{{with .Readme}}
+
+## README
+{{.}}{{end}}`))
+
+// sampleFacts has every fact set, to check templates against
+var sampleFacts = &Facts{
+	Root:        "/project",
+	Target:      "/project/generated-project",
+	Stack:       "Go",
+	Profile:     &stack.Profile{Commands: stack.Commands{Test: "go test ./..."}},
+	Tree:        "go.mod",
+	Readme:      "# Project",
+	Conventions: "- errors are wrapped",
+}
+
+// SystemTemplate is an agent's parsed system prompt
+type SystemTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseSystem parses an agent's system prompt template and checks that it
+// renders both without facts and with every fact set, so a template that
+// would fail at request time is rejected up front
+func ParseSystem(prompt string) (*SystemTemplate, error) {
+	tmpl, err := template.Must(projectTemplate.Clone()).New("system").Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &SystemTemplate{tmpl: tmpl}
+	for _, facts := range []*Facts{{}, sampleFacts} {
+		if _, err := t.render(facts); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// MustParseSystem is ParseSystem for built-in prompts; it panics on error
+func MustParseSystem(prompt string) *SystemTemplate {
+	t, err := ParseSystem(prompt)
+	if err != nil {
+		panic(fmt.Sprintf("prompts: invalid system prompt: %v", err))
+	}
+	return t
+}
+
+// Render renders the system prompt with facts; nil means no facts. Should
+// the facts still make it fail, the prompt is rendered without them, which
+// ParseSystem has checked works.
+func (t *SystemTemplate) Render(facts *Facts) string {
+	if facts == nil {
+		facts = &Facts{}
+	}
+	prompt, err := t.render(facts)
+	if err != nil {
+		prompt, _ = t.render(&Facts{})
+	}
+	return prompt
+}
+
+func (t *SystemTemplate) render(facts *Facts) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, facts); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// User renders the user message of a request, with the context of the task
+// when there is one. Project facts belong in the system prompt.
+func User(taskContext, request string) string {
+	if taskContext == "" {
+		return request
+	}
+	return fmt.Sprintf("Context: %s\n\nUser Request: %s", taskContext, request)
+}
@@ -0,0 +1,146 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-code/internal/stack"
+)
+
+// writeFiles creates files below root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSystem(t *testing.T) {
+	facts := &Facts{
+		Root:        "/src/app",
+		Target:      "/src/app/generated-project",
+		Stack:       "Go (chi)",
+		Profile:     &stack.Profile{Commands: stack.Commands{Test: "go test ./..."}},
+		Tree:        "go.mod\nmain.go",
+		Readme:      "# App",
+		Conventions: "- we use chi, not gin",
+	}
+
+	tmpl, err := ParseSystem(`You are the Backend Agent.{{if .Stack}} Stay within {{.Stack}}.{{end}}{{template "project" .}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tmpl.Render(facts)
+	for _, want := range []string{
+		"You are the Backend Agent. Stay within Go (chi).\n\n## Project\n",
+		"Project directory: /src/app\n",
+		"Generated files are written to: /src/app/generated-project\n",
+		"Stack: Go (chi)\nTests run with: go test ./...\n",
+		"## Conventions\n",
+		"- we use chi, not gin\n",
+		"## Directory tree\n```\ngo.mod\nmain.go\n```",
+		"## README\n# App",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("system prompt lacks %q:\n%s", want, got)
+		}
+	}
+
+	// Without facts the prompt is unchanged
+	if got := tmpl.Render(nil); got != "You are the Backend Agent." {
+		t.Errorf("Render without facts = %q", got)
+	}
+}
+
+func TestParseSystemRejectsBadTemplates(t *testing.T) {
+	for _, prompt := range []string{
+		"You are {{.Name",
+		"{{.Unknown}}",
+		"{{template \"missing\" .}}",
+		// Fails only when the stack couldn't be detected
+		"Tests: {{.Profile.Commands.Test}}",
+	} {
+		if _, err := ParseSystem(prompt); err == nil {
+			t.Errorf("ParseSystem(%q) succeeded", prompt)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParseSystem of a bad template didn't panic")
+		}
+	}()
+	MustParseSystem("{{.Unknown}}")
+}
+
+func TestUser(t *testing.T) {
+	if got := User("", "Plan a blog with {{braces}}"); got != "Plan a blog with {{braces}}" {
+		t.Errorf("User without context = %q", got)
+	}
+	if got := User("eval(input)", "Is this safe?"); got != "Context: eval(input)\n\nUser Request: Is this safe?" {
+		t.Errorf("User with context = %q", got)
+	}
+}
+
+func TestGather(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":                  "# Shop\n\n" + strings.Repeat("x", maxReadmeBytes),
+		"go.mod":                     "module shop\n\nrequire github.com/go-chi/chi/v5 v5.0.12\n",
+		"cmd/shop/main.go":           "package main\n",
+		"node_modules/left-pad/x.js": "",
+		".git/HEAD":                  "",
+		ConventionsFile:              "Handlers return errors; middleware writes them.\n",
+	})
+
+	facts, err := Gather(root, filepath.Join(root, "generated-project"), []string{"we use chi, not gin", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if facts.Root != root || facts.Target != filepath.Join(root, "generated-project") {
+		t.Errorf("root, target = %s, %s", facts.Root, facts.Target)
+	}
+	if want := "Go (chi); tests: go test; package manager: go modules"; facts.Stack != want || facts.Profile == nil {
+		t.Errorf("stack = %q, profile %v, want %q", facts.Stack, facts.Profile, want)
+	}
+	if want := "README.md\ncmd/\n  shop/\n    main.go\ngo.mod"; facts.Tree != want {
+		t.Errorf("tree = %q, want %q", facts.Tree, want)
+	}
+	if !strings.HasPrefix(facts.Readme, "# Shop") || !strings.HasSuffix(facts.Readme, "[...]") || len(facts.Readme) > maxReadmeBytes+10 {
+		t.Errorf("readme of %d bytes = %.40q...", len(facts.Readme), facts.Readme)
+	}
+	if want := "- we use chi, not gin\nHandlers return errors; middleware writes them."; facts.Conventions != want {
+		t.Errorf("conventions = %q, want %q", facts.Conventions, want)
+	}
+}
+
+func TestGatherLimitsTree(t *testing.T) {
+	root := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < maxTreeEntries+10; i++ {
+		files[filepath.Join("a", "b", "c", "d", strings.Repeat("f", 1+i%5)+string(rune('a'+i%26))+".txt")] = ""
+		files[strings.Repeat("g", 1+i/26)+string(rune('a'+i%26))+".txt"] = ""
+	}
+	writeFiles(t, root, files)
+
+	facts, err := Gather(root, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(facts.Tree, "\n")
+	if len(lines) != maxTreeEntries+1 || lines[len(lines)-1] != "..." {
+		t.Errorf("tree has %d lines ending in %q, want %d and ...", len(lines), lines[len(lines)-1], maxTreeEntries+1)
+	}
+	if strings.Contains(facts.Tree, "      ") {
+		t.Errorf("tree goes deeper than %d levels", maxTreeDepth)
+	}
+}
@@ -0,0 +1,153 @@
+// Package runner runs commands on the user's behalf, such as a generated
+// project's tests. Only the configured allowed_commands run, and with
+// require_command_permission each one needs a session permission or the
+// user's confirmation. Commands are split into words and run without a
+// shell.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// maxOutputBytes caps the output kept from a command; its end is kept,
+// where test runners report failures
+const maxOutputBytes = 16 * 1024
+
+// waitDelay is how long a cancelled command's children may hold on to its
+// output before they are abandoned
+const waitDelay = 5 * time.Second
+
+// Errors for commands that were not run
+var (
+	ErrNotAllowed = errors.New("command not allowed")
+	ErrDenied     = errors.New("permission denied")
+)
+
+// ConfirmFunc asks the user whether command may run in dir
+type ConfirmFunc func(command, dir string) bool
+
+// Runner runs commands within the configuration's command settings
+type Runner struct {
+	config  *models.Config
+	confirm ConfirmFunc
+	asking  sync.Mutex
+}
+
+// Result is a finished command
+type Result struct {
+	Command  string
+	Dir      string
+	ExitCode int
+	// Output is the end of the combined stdout and stderr
+	Output   string
+	Duration time.Duration
+}
+
+// Passed reports whether the command exited with status 0
+func (r *Result) Passed() bool {
+	return r.ExitCode == 0
+}
+
+// New creates a runner. confirm asks the user about commands that need
+// permission; when it is nil they are refused.
+func New(config *models.Config, confirm ConfirmFunc) *Runner {
+	return &Runner{config: config, confirm: confirm}
+}
+
+// Check reports whether command would be allowed to run, without asking
+// the user
+func (r *Runner) Check(command string) error {
+	_, err := r.program(command)
+	return err
+}
+
+// Run runs command in dir. A command that exits with a non-zero status is
+// not an error; its Result says how it ended. Commands that are not allowed
+// or permitted, can't be started or are stopped by ctx return an error.
+func (r *Runner) Run(ctx context.Context, dir, command string) (*Result, error) {
+	program, err := r.program(command)
+	if err != nil {
+		return nil, err
+	}
+	if !r.permitted(program, command, dir) {
+		return nil, fmt.Errorf("%w to run %q", ErrDenied, command)
+	}
+
+	args := strings.Fields(command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.WaitDelay = waitDelay
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	started := time.Now()
+	err = cmd.Run()
+	result := &Result{
+		Command:  command,
+		Dir:      dir,
+		Output:   tail(output.String()),
+		Duration: time.Since(started),
+	}
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("%s stopped: %w", command, ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	return result, nil
+}
+
+// program returns the allowed program that command runs
+func (r *Runner) program(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("%w: empty command", ErrNotAllowed)
+	}
+	for _, allowed := range r.config.AllowedCommands {
+		if args[0] == allowed {
+			return args[0], nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not in allowed_commands (add it with 'go-code config commands add %s')", ErrNotAllowed, args[0], args[0])
+}
+
+// permitted reports whether program may run, asking the user when the
+// configuration requires it
+func (r *Runner) permitted(program, command, dir string) bool {
+	if !r.config.RequireCommandPermission || r.config.SessionPermissions[models.CommandPermissionKey(program)] {
+		return true
+	}
+	if r.confirm == nil {
+		return false
+	}
+
+	r.asking.Lock()
+	defer r.asking.Unlock()
+	return r.confirm(command, dir)
+}
+
+// tail returns the end of output, at most maxOutputBytes long
+func tail(output string) string {
+	if len(output) <= maxOutputBytes {
+		return output
+	}
+	cut := len(output) - maxOutputBytes
+	if newline := strings.IndexByte(output[cut:], '\n'); newline >= 0 {
+		cut += newline + 1
+	}
+	return "[...]\n" + output[cut:]
+}
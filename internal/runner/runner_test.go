@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// testConfig allows a few POSIX utilities
+func testConfig(requirePermission bool) *models.Config {
+	config := models.DefaultConfig()
+	config.AllowedCommands = []string{"echo", "false", "sleep", "missing-program-for-test"}
+	config.RequireCommandPermission = requirePermission
+	return config
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX utilities")
+	}
+	dir := t.TempDir()
+	r := New(testConfig(false), nil)
+
+	result, err := r.Run(context.Background(), dir, "echo  tests  passed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Passed() || result.Output != "tests passed\n" || result.Dir != dir {
+		t.Errorf("echo = %+v", result)
+	}
+
+	result, err = r.Run(context.Background(), dir, "false")
+	if err != nil || result.Passed() || result.ExitCode != 1 {
+		t.Errorf("false = %+v, %v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.Run(ctx, dir, "sleep 5"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("sleep past the deadline = %v", err)
+	}
+
+	if _, err := r.Run(context.Background(), dir, "missing-program-for-test"); err == nil {
+		t.Error("a missing program ran")
+	}
+}
+
+func TestRunAllowedAndPermitted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX utilities")
+	}
+	dir := t.TempDir()
+
+	r := New(testConfig(false), nil)
+	if _, err := r.Run(context.Background(), dir, "rm -rf "+dir); !errors.Is(err, ErrNotAllowed) || !strings.Contains(err.Error(), "config commands add rm") {
+		t.Errorf("rm = %v, want ErrNotAllowed", err)
+	}
+	if err := r.Check(""); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("empty command = %v", err)
+	}
+
+	// Without a way to ask, commands need a session permission
+	config := testConfig(true)
+	r = New(config, nil)
+	if _, err := r.Run(context.Background(), dir, "echo hi"); !errors.Is(err, ErrDenied) {
+		t.Errorf("echo without permission = %v, want ErrDenied", err)
+	}
+	config.SessionPermissions[models.CommandPermissionKey("echo")] = true
+	if _, err := r.Run(context.Background(), dir, "echo hi"); err != nil {
+		t.Errorf("echo with session permission = %v", err)
+	}
+
+	var asked []string
+	r = New(testConfig(true), func(command, in string) bool {
+		asked = append(asked, command+" in "+in)
+		return command == "echo yes"
+	})
+	if _, err := r.Run(context.Background(), dir, "echo yes"); err != nil {
+		t.Errorf("confirmed echo = %v", err)
+	}
+	if _, err := r.Run(context.Background(), dir, "echo no"); !errors.Is(err, ErrDenied) {
+		t.Errorf("refused echo = %v", err)
+	}
+	if len(asked) != 2 || asked[0] != "echo yes in "+dir {
+		t.Errorf("asked %q", asked)
+	}
+}
+
+func TestTail(t *testing.T) {
+	output := strings.Repeat("ok\n", maxOutputBytes) + "FAIL\n"
+	got := tail(output)
+	if !strings.HasPrefix(got, "[...]\nok\n") || !strings.HasSuffix(got, "FAIL\n") || len(got) > maxOutputBytes+10 {
+		t.Errorf("tail of %d bytes = %d bytes", len(output), len(got))
+	}
+}
@@ -0,0 +1,163 @@
+package scanner
+
+import (
+	"regexp"
+
+	"go-code/pkg/models"
+)
+
+// Rule is a single static check run against generated files
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+	Severity    models.Severity
+	Fix         string
+
+	// Extensions limits the rule to files with these extensions. Empty means all files.
+	Extensions []string
+
+	// Patterns are matched line by line; any match produces a finding
+	Patterns []*regexp.Regexp
+
+	// Ignore suppresses a line match when it also matches, e.g. placeholders
+	Ignore *regexp.Regexp
+
+	// Unless turns the rule into a file-level check: the first pattern match is
+	// only reported when nothing in the file matches Unless
+	Unless *regexp.Regexp
+
+	// Sensitive rules match secrets, so their findings mask the line's
+	// literals and tokens instead of quoting them
+	Sensitive bool
+}
+
+// codeExtensions are the source files checked by language-specific rules
+var codeExtensions = []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".py", ".go", ".rb", ".php", ".java", ".cs"}
+
+// Token-shaped credentials that are secrets wherever they appear
+var (
+	awsAccessKey = regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)
+	apiToken     = regexp.MustCompile(`\b(gsk|sk|ghp|xox[baprs])[-_][A-Za-z0-9]{20,}\b`)
+)
+
+// Rules is the built-in rule set
+var Rules = []Rule{
+	{
+		ID:          "GC001",
+		Name:        "hardcoded-secret",
+		Description: "Hard-coded secret or credential",
+		Severity:    models.SeverityHigh,
+		Fix:         "Load the value from an environment variable or a secret manager",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\b[\w.-]*(api[_-]?key|secret[_-]?key|client[_-]?secret|password|passwd|auth[_-]?token|access[_-]?token|private[_-]?key)["']?\s*[:=]\s*["'][^"'\s]{8,}["']`),
+			awsAccessKey,
+			regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`),
+			apiToken,
+		},
+		Ignore:    regexp.MustCompile(`(?i)(your[_-]|example|placeholder|changeme|<[^>]+>|\$\{|process\.env|os\.getenv|os\.environ|xxxx|\*\*\*\*)`),
+		Sensitive: true,
+	},
+	{
+		ID:          "GC002",
+		Name:        "dynamic-code-execution",
+		Description: "Use of eval or dynamic code execution",
+		Severity:    models.SeverityHigh,
+		Fix:         "Avoid evaluating strings as code; parse the input explicitly instead",
+		Extensions:  []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".py", ".rb", ".php"},
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(^|[^\w.])eval\s*\(`),
+			regexp.MustCompile(`\bnew\s+Function\s*\(`),
+			regexp.MustCompile(`(^|[^\w.])exec\s*\(\s*[^)]`),
+			regexp.MustCompile(`\bset(Timeout|Interval)\s*\(\s*["'\x60]`),
+		},
+	},
+	{
+		ID:          "GC003",
+		Name:        "sql-string-concatenation",
+		Description: "SQL query built with string concatenation or interpolation",
+		Severity:    models.SeverityHigh,
+		Fix:         "Use parameterized queries or prepared statements",
+		Extensions:  codeExtensions,
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)["'\x60]\s*(select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\b[^"'\x60]*["'\x60]\s*\+`),
+			regexp.MustCompile(`(?i)\x60\s*(select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\b[^\x60]*\$\{`),
+			regexp.MustCompile(`(?i)\bf["'](select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\b[^"']*\{`),
+			regexp.MustCompile(`(?i)Sprintf\(\s*["\x60](select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\b[^"\x60]*%[sv]`),
+			regexp.MustCompile(`(?i)["'](select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\b[^"']*%s[^"']*["']\s*%`),
+		},
+	},
+	{
+		ID:          "GC004",
+		Name:        "tls-verification-disabled",
+		Description: "TLS certificate verification is disabled",
+		Severity:    models.SeverityHigh,
+		Fix:         "Keep certificate verification enabled and trust the required CA instead",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`InsecureSkipVerify\s*:\s*true`),
+			regexp.MustCompile(`rejectUnauthorized["']?\s*:\s*false`),
+			regexp.MustCompile(`NODE_TLS_REJECT_UNAUTHORIZED["']?\s*[=:]\s*["']?0`),
+			regexp.MustCompile(`\bverify\s*=\s*False\b`),
+			regexp.MustCompile(`ssl\._create_unverified_context`),
+		},
+	},
+	{
+		ID:          "GC005",
+		Name:        "cors-wildcard",
+		Description: "CORS allows any origin",
+		Severity:    models.SeverityMedium,
+		Fix:         "Restrict allowed origins to the domains that need access",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)Access-Control-Allow-Origin["']?\s*[,:]\s*["']\*["']`),
+			regexp.MustCompile(`\borigin\s*:\s*["']\*["']`),
+			regexp.MustCompile(`\bcors\(\s*\)`),
+			regexp.MustCompile(`AllowOrigins\s*:\s*\[\]string\{\s*"\*"`),
+			regexp.MustCompile(`AllowAllOrigins\s*:\s*true`),
+			regexp.MustCompile(`allow_origins\s*=\s*\[\s*["']\*["']`),
+			regexp.MustCompile(`CORS_ORIGIN_ALLOW_ALL\s*=\s*True`),
+		},
+	},
+	{
+		ID:          "GC006",
+		Name:        "weak-jwt-secret",
+		Description: "JWT signed with a weak or hard-coded secret",
+		Severity:    models.SeverityHigh,
+		Fix:         "Use a random secret of at least 32 bytes loaded from the environment, with no fallback",
+		Extensions:  codeExtensions,
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`jwt\.sign\(.*,\s*["'\x60][^"'\x60]*["'\x60]\s*[,)]`),
+			regexp.MustCompile(`(?i)jwt[_-]?secret["']?\s*[:=]\s*["'](secret|changeme|password|[^"']{0,15})["']`),
+			regexp.MustCompile(`(?i)JWT_SECRET\s*(\|\||\?\?|\bor\b)\s*["']`),
+			regexp.MustCompile(`(?i)getenv\(\s*["']JWT_SECRET["']\s*,\s*["']`),
+			regexp.MustCompile(`jwt\.encode\([^,]+,\s*["'][^"']*["']`),
+		},
+		Sensitive: true,
+	},
+	{
+		ID:          "GC007",
+		Name:        "missing-input-validation",
+		Description: "Request input is used without any validation",
+		Severity:    models.SeverityMedium,
+		Fix:         "Validate request bodies with a schema (e.g. joi, zod, express-validator, pydantic)",
+		Extensions:  codeExtensions,
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\breq\.(body|query|params)\b`),
+			regexp.MustCompile(`\brequest\.(json|form|args|get_json)\b`),
+			regexp.MustCompile(`json\.NewDecoder\(\s*r\.Body\s*\)`),
+		},
+		Unless: regexp.MustCompile(`(?i)(joi|zod|yup|express-validator|validationResult|celebrate|ajv|pydantic|marshmallow|wtforms|validate|validator|schema\.parse|safeParse)`),
+	},
+}
+
+// appliesTo reports whether the rule should run against a file extension
+func (r Rule) appliesTo(ext string) bool {
+	if len(r.Extensions) == 0 {
+		return true
+	}
+	for _, e := range r.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
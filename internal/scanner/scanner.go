@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go-code/pkg/models"
+)
+
+// maxFileSize skips files too large to be hand-written source
+const maxFileSize = 1 << 20
+
+// skipDirs are never walked by ScanDir
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"__pycache__":  true,
+	".venv":        true,
+}
+
+// Scanner runs a rule set over source files without any network access
+type Scanner struct {
+	rules []Rule
+}
+
+// New creates a scanner with the built-in rules
+func New() *Scanner {
+	return &Scanner{rules: Rules}
+}
+
+// ScanContent checks a single file's content. The path is used for reporting
+// and to pick the rules that apply to the file's extension.
+func (s *Scanner) ScanContent(path, content string) []models.Finding {
+	ext := strings.ToLower(filepath.Ext(path))
+	lines := strings.Split(content, "\n")
+
+	var findings []models.Finding
+	for _, rule := range s.rules {
+		if !rule.appliesTo(ext) {
+			continue
+		}
+
+		// File-level rules are satisfied by any mitigation anywhere in the file
+		if rule.Unless != nil && rule.Unless.MatchString(content) {
+			continue
+		}
+
+		for i, line := range lines {
+			if !matchesAny(rule.Patterns, line) {
+				continue
+			}
+			if rule.Ignore != nil && rule.Ignore.MatchString(line) {
+				continue
+			}
+
+			findings = append(findings, models.Finding{
+				Severity: rule.Severity,
+				File:     filepath.ToSlash(path),
+				Line:     i + 1,
+				Message:  fmt.Sprintf("%s: %s", rule.Description, snippet(rule, line)),
+				Fix:      rule.Fix,
+				Rule:     rule.ID,
+			})
+
+			if rule.Unless != nil {
+				break // One finding per file is enough for file-level rules
+			}
+		}
+	}
+
+	return findings
+}
+
+// ScanFiles checks the given files, reporting paths relative to root
+func (s *Scanner) ScanFiles(root string, paths []string) ([]models.Finding, error) {
+	var findings []models.Finding
+
+	for _, path := range paths {
+		fullPath := path
+		if !filepath.IsAbs(path) {
+			fullPath = filepath.Join(root, path)
+		}
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return findings, fmt.Errorf("failed to stat %s: %w", fullPath, err)
+		}
+		if info.IsDir() || info.Size() > maxFileSize {
+			continue
+		}
+
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return findings, fmt.Errorf("failed to read %s: %w", fullPath, err)
+		}
+		if isBinary(data) {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, fullPath)
+		if err != nil {
+			rel = fullPath
+		}
+		findings = append(findings, s.ScanContent(rel, string(data))...)
+	}
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// ScanSink checks the given files as stored in sink
+func (s *Scanner) ScanSink(sink models.FileSink, paths []string) ([]models.Finding, error) {
+	var findings []models.Finding
+
+	for _, path := range paths {
+		data, err := sink.ReadFile(path)
+		if err != nil {
+			return findings, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if len(data) > maxFileSize || isBinary(data) {
+			continue
+		}
+		findings = append(findings, s.ScanContent(path, string(data))...)
+	}
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// ScanDir walks a directory tree and checks every file in it
+func (s *Scanner) ScanDir(root string) ([]models.Finding, error) {
+	var paths []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	return s.ScanFiles(root, paths)
+}
+
+// matchesAny reports whether any pattern matches the line
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, p := range patterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinary guesses whether data is a binary file
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) != -1
+}
+
+// sortFindings orders findings by file, then line, then rule
+func sortFindings(findings []models.Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+}
+
+// truncate shortens s to at most n bytes
+// quotedLiteral matches a single-line string literal
+var quotedLiteral = regexp.MustCompile(`"[^"]*"|'[^']*'|\x60[^\x60]*\x60`)
+
+// snippet quotes the matched line in a finding. Lines matched by sensitive
+// rules have their literals and credential tokens masked so the secret never
+// reaches a report or a prompt.
+func snippet(rule Rule, line string) string {
+	if rule.Sensitive {
+		line = quotedLiteral.ReplaceAllString(line, `"****"`)
+		line = awsAccessKey.ReplaceAllString(line, "****")
+		line = apiToken.ReplaceAllString(line, "****")
+	}
+	return strings.TrimSpace(truncate(line, 120))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go-code/pkg/gocode"
+)
+
+// Build statuses
+const (
+	statusRunning   = "running"
+	statusCompleted = "completed"
+	statusFailed    = "failed"
+	statusCancelled = "cancelled"
+)
+
+// build is a build started through the API. It keeps the build's events for
+// streaming and its files in memory for download.
+type build struct {
+	description string
+	created     time.Time
+	sink        *gocode.MemorySink
+	ctx         context.Context
+	cancel      context.CancelFunc
+	started     chan struct{} // closed when the build's run ID is known
+	finished    chan struct{} // closed when the build has ended
+
+	mu      sync.Mutex
+	id      string
+	state   string
+	err     error
+	result  *gocode.BuildResult
+	events  []gocode.Event
+	changed chan struct{} // closed and replaced whenever an event arrives
+}
+
+// buildStatus describes a build in API responses
+type buildStatus struct {
+	ID          string              `json:"id"`
+	Description string              `json:"description"`
+	Status      string              `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	Created     time.Time           `json:"created"`
+	Events      int                 `json:"events"`
+	Files       []string            `json:"files"`
+	Result      *gocode.BuildResult `json:"result,omitempty"`
+}
+
+// newBuild creates a running build of description
+func newBuild(description string) *build {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &build{
+		description: description,
+		created:     time.Now(),
+		sink:        gocode.NewMemorySink(),
+		ctx:         ctx,
+		cancel:      cancel,
+		started:     make(chan struct{}),
+		finished:    make(chan struct{}),
+		state:       statusRunning,
+		changed:     make(chan struct{}),
+	}
+}
+
+// Handle keeps an event of the build and wakes the streams waiting for it
+func (b *build) Handle(event gocode.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.id == "" && event.RunID != "" {
+		b.id = event.RunID
+		close(b.started)
+	}
+	b.events = append(b.events, event)
+	b.notify()
+}
+
+// finish records the outcome of the build
+func (b *build) finish(result *gocode.BuildResult, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.result, b.err = result, err
+	switch {
+	case err == nil:
+		b.state = statusCompleted
+	case errors.Is(err, context.Canceled):
+		b.state = statusCancelled
+	default:
+		b.state = statusFailed
+	}
+	b.cancel()
+	close(b.finished)
+	b.notify()
+}
+
+// notify wakes everything waiting for a change. The caller holds b.mu.
+func (b *build) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// eventsFrom returns the events from index next on, whether the build has
+// ended, and a channel that is closed on the next change
+func (b *build) eventsFrom(next int) (events []gocode.Event, done bool, changed <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if next < len(b.events) {
+		events = append(events, b.events[next:]...)
+	}
+	return events, b.state != statusRunning, b.changed
+}
+
+// status describes the build as it is now
+func (b *build) status() buildStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := buildStatus{
+		ID:          b.id,
+		Description: b.description,
+		Status:      b.state,
+		Created:     b.created,
+		Events:      len(b.events),
+		Files:       b.sink.Paths(),
+		Result:      b.result,
+	}
+	if b.err != nil {
+		status.Error = b.err.Error()
+	}
+	return status
+}
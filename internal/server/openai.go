@@ -0,0 +1,225 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-code/internal/api"
+	"go-code/internal/catalog"
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
+)
+
+// modelPrefix names the agents as models of the OpenAI-compatible API, e.g.
+// go-code/backend
+const modelPrefix = "go-code/"
+
+// completionRequest is the body of POST /v1/chat/completions. Sampling
+// settings are ignored: the agent's own configuration applies.
+type completionRequest struct {
+	Model         string              `json:"model"`
+	Messages      []completionMessage `json:"messages"`
+	Stream        bool                `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+// completionMessage is a message whose content is either a string or a list
+// of content parts, of which only the text parts are kept
+type completionMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message's text content
+func (m completionMessage) text() (string, error) {
+	var content string
+	if err := json.Unmarshal(m.Content, &content); err == nil {
+		return content, nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or a list of content parts")
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// completionChunk is one server-sent event of a streamed completion
+type completionChunk struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []chunkChoice `json:"choices"`
+	Usage   *api.Usage    `json:"usage,omitempty"`
+}
+
+// chunkChoice is the choice of a streamed completion chunk
+type chunkChoice struct {
+	Index        int               `json:"index"`
+	Delta        map[string]string `json:"delta"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+// listModels handles GET /v1/models: one model per agent
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	known := catalog.Load(catalog.DefaultCachePath())
+
+	data := []api.ModelInfo{}
+	for _, agent := range s.team.Agents() {
+		config := s.team.AgentConfig(agent.Type())
+		info := api.ModelInfo{
+			ID:                  modelPrefix + string(agent.Type()),
+			Object:              "model",
+			OwnedBy:             "go-code",
+			Active:              true,
+			MaxCompletionTokens: config.MaxTokens,
+		}
+		if model, ok := known.Lookup(config.Model); ok {
+			info.ContextWindow = model.ContextWindow
+		}
+		data = append(data, info)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": data})
+}
+
+// chatCompletions handles POST /v1/chat/completions. The conversation goes
+// to the agent named by the model, with the agent's system prompt and model
+// settings, and the reply comes back as a chat completion.
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	agentType, ok := s.agentForModel(req.Model)
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, "model_not_found",
+			fmt.Sprintf("model %q does not exist; use one of GET /v1/models", req.Model))
+		return
+	}
+
+	messages := make([]gocode.Message, 0, len(req.Messages))
+	for i, message := range req.Messages {
+		content, err := message.text()
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("messages[%d]: %v", i, err))
+			return
+		}
+		messages = append(messages, gocode.Message{Role: message.Role, Content: content})
+	}
+	if len(messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+		return
+	}
+
+	id, created := newCompletionID(), time.Now().Unix()
+	if !req.Stream {
+		response, err := s.team.Chat(r.Context(), agentType, messages)
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadGateway, "api_error", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, api.ChatResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   req.Model,
+			Choices: []api.Choice{{
+				Message:      api.Message{Role: models.RoleAssistant, Content: response.Content},
+				FinishReason: "stop",
+			}},
+			Usage: completionUsage(response),
+		})
+		return
+	}
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "streaming is not supported")
+		return
+	}
+	chunk := func(delta map[string]string, finishReason *string) completionChunk {
+		return completionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: []chunkChoice{{Delta: delta, FinishReason: finishReason}},
+		}
+	}
+
+	stream.data(chunk(map[string]string{"role": models.RoleAssistant}, nil))
+	response, err := s.team.ChatStream(r.Context(), agentType, messages, func(content string) {
+		stream.data(chunk(map[string]string{"content": content}, nil))
+	})
+	if err != nil {
+		stream.data(openAIError("api_error", err.Error()))
+		return
+	}
+
+	stop := "stop"
+	stream.data(chunk(map[string]string{}, &stop))
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		usage := completionUsage(response)
+		stream.data(completionChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: req.Model, Choices: []chunkChoice{}, Usage: &usage})
+	}
+	stream.done()
+}
+
+// agentForModel returns the agent a model name stands for
+func (s *Server) agentForModel(model string) (models.AgentType, bool) {
+	name, ok := strings.CutPrefix(model, modelPrefix)
+	if !ok {
+		return "", false
+	}
+	for _, agent := range s.team.Agents() {
+		if string(agent.Type()) == name {
+			return agent.Type(), true
+		}
+	}
+	return "", false
+}
+
+// completionUsage reports a response's tokens the way the API does
+func completionUsage(response *models.Response) api.Usage {
+	return api.Usage{
+		PromptTokens:     response.PromptTokens,
+		CompletionTokens: response.CompletionTokens,
+		TotalTokens:      response.PromptTokens + response.CompletionTokens,
+	}
+}
+
+// newCompletionID returns a random chat completion ID
+func newCompletionID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return "chatcmpl-" + hex.EncodeToString(id)
+}
+
+// openAIError is an error body in the OpenAI format
+func openAIError(errorType, message string) map[string]interface{} {
+	return map[string]interface{}{"error": map[string]string{"message": message, "type": errorType}}
+}
+
+// writeOpenAIError writes an error response in the OpenAI format
+func writeOpenAIError(w http.ResponseWriter, status int, errorType, message string) {
+	writeJSON(w, status, openAIError(errorType, message))
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-code/internal/api"
+	"go-code/internal/llmtest"
+	"go-code/pkg/models"
+)
+
+// echoReply answers with the last message, so tests see what was forwarded
+func echoReply(req api.ChatRequest) llmtest.Reply {
+	return llmtest.Reply{Content: "You said: " + req.Messages[len(req.Messages)-1].Content}
+}
+
+func TestOpenAIModels(t *testing.T) {
+	ts, _ := newTestServer(t, echoReply, Options{})
+
+	// go-code's own client is a stand-in for any OpenAI-compatible tool
+	client := api.NewClient(testToken, ts.URL+"/v1")
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	var ids []string
+	for _, model := range models {
+		ids = append(ids, model.ID)
+	}
+	for _, want := range []string{"go-code/planner", "go-code/backend", "go-code/security"} {
+		if !strings.Contains(strings.Join(ids, ","), want) {
+			t.Errorf("models %v lack %s", ids, want)
+		}
+	}
+}
+
+func TestChatCompletions(t *testing.T) {
+	ts, fake := newTestServer(t, echoReply, Options{})
+
+	client := api.NewClient(testToken, ts.URL+"/v1")
+	resp, err := client.SendChatRequest(api.ChatRequest{
+		Model:       "go-code/backend",
+		Messages:    []api.Message{{Role: "user", Content: "Design a users table"}},
+		Temperature: 1.5,
+	})
+	if err != nil {
+		t.Fatalf("SendChatRequest: %v", err)
+	}
+	if resp.Model != "go-code/backend" || resp.Choices[0].Message.Content != "You said: Design a users table" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Usage.TotalTokens == 0 || resp.Usage.TotalTokens != resp.Usage.PromptTokens+resp.Usage.CompletionTokens {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+
+	// The provider gets the agent's system prompt and model settings
+	forwarded := fake.Requests()[0]
+	if !strings.HasPrefix(llmtest.SystemPrompt(forwarded), "You are the Backend Agent") {
+		t.Errorf("system prompt = %q", llmtest.SystemPrompt(forwarded))
+	}
+	config := models.DefaultConfig().AgentPreferences[models.BackendAgent]
+	if forwarded.Model != config.Model || forwarded.Temperature != config.Temperature || forwarded.MaxTokens != config.MaxTokens {
+		t.Errorf("forwarded %s at %v with %d tokens, want the backend agent's settings", forwarded.Model, forwarded.Temperature, forwarded.MaxTokens)
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	ts, _ := newTestServer(t, echoReply, Options{})
+
+	client := api.NewClient(testToken, ts.URL+"/v1")
+	var streamed strings.Builder
+	resp, err := client.StreamChatRequest(context.Background(), api.ChatRequest{
+		Model: "go-code/frontend",
+		Messages: []api.Message{
+			{Role: "user", Content: "Build a navbar"},
+			{Role: "assistant", Content: "Which framework?"},
+			{Role: "user", Content: "Plain HTML and CSS please"},
+		},
+	}, func(content string) { streamed.WriteString(content) })
+	if err != nil {
+		t.Fatalf("StreamChatRequest: %v", err)
+	}
+	if streamed.String() != "You said: Plain HTML and CSS please" || resp.Choices[0].Message.Content != streamed.String() {
+		t.Errorf("streamed %q, response %+v", streamed.String(), resp)
+	}
+}
+
+func TestChatCompletionsContentParts(t *testing.T) {
+	ts, _ := newTestServer(t, echoReply, Options{})
+
+	body := map[string]interface{}{
+		"model": "go-code/backend",
+		"messages": []map[string]interface{}{{
+			"role":    "user",
+			"content": []map[string]string{{"type": "text", "text": "First part"}, {"type": "image_url"}, {"type": "text", "text": "second part"}},
+		}},
+	}
+	var resp api.ChatResponse
+	decode(t, do(t, "POST", ts.URL+"/v1/chat/completions", body), http.StatusOK, &resp)
+	if resp.Choices[0].Message.Content != "You said: First part\nsecond part" {
+		t.Errorf("content = %q", resp.Choices[0].Message.Content)
+	}
+}
+
+func TestChatCompletionsErrors(t *testing.T) {
+	ts, _ := newTestServer(t, echoReply, Options{})
+
+	tests := []struct {
+		name   string
+		body   interface{}
+		status int
+		kind   string
+	}{
+		{"unknown model", map[string]interface{}{"model": "llama-3.3-70b-versatile", "messages": []api.Message{{Role: "user", Content: "hi"}}}, http.StatusNotFound, "model_not_found"},
+		{"no messages", map[string]interface{}{"model": "go-code/backend"}, http.StatusBadRequest, "invalid_request_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Error struct{ Message, Type string }
+			}
+			decode(t, do(t, "POST", ts.URL+"/v1/chat/completions", tt.body), tt.status, &body)
+			if body.Error.Type != tt.kind || body.Error.Message == "" {
+				t.Errorf("error = %+v", body.Error)
+			}
+		})
+	}
+
+	client := api.NewClient("wrong-token", ts.URL+"/v1")
+	_, err := client.SendChatRequest(api.ChatRequest{Model: "go-code/backend", Messages: []api.Message{{Role: "user", Content: "hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "missing or invalid token") {
+		t.Errorf("wrong token: %v", err)
+	}
+}
+
+func TestCompletionMessageText(t *testing.T) {
+	var message completionMessage
+	if err := json.Unmarshal([]byte(`{"role": "user", "content": 42}`), &message); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := message.text(); err == nil {
+		t.Error("numeric content was accepted")
+	}
+}
@@ -0,0 +1,419 @@
+// Package server exposes an agent team over a local HTTP/JSON API: the agent
+// list, chats, and builds whose events stream as server-sent events and whose
+// files can be downloaded as a zip. Under /v1 the agents also speak the
+// OpenAI chat completions API, each agent appearing as a model.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
+)
+
+// DefaultMaxBuilds is how many builds may run at once unless configured
+const DefaultMaxBuilds = 2
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 20
+
+// Options configure a Server
+type Options struct {
+	// Token must be sent by every request, as "Authorization: Bearer <token>"
+	// or, for clients like EventSource that can't set headers, "?token=<token>".
+	Token string
+	// MaxBuilds limits how many builds run at once; further builds are
+	// refused with 429 Too Many Requests
+	MaxBuilds int
+}
+
+// Server serves a team's agents and builds over HTTP
+type Server struct {
+	team    *gocode.Team
+	options Options
+	mux     *http.ServeMux
+
+	mu      sync.Mutex
+	builds  map[string]*build
+	order   []string
+	running int
+}
+
+// New creates a server for team
+func New(team *gocode.Team, options Options) *Server {
+	if options.MaxBuilds <= 0 {
+		options.MaxBuilds = DefaultMaxBuilds
+	}
+
+	s := &Server{team: team, options: options, mux: http.NewServeMux(), builds: make(map[string]*build)}
+	s.mux.HandleFunc("GET /agents", s.listAgents)
+	s.mux.HandleFunc("POST /chat", s.chat)
+	s.mux.HandleFunc("GET /builds", s.listBuilds)
+	s.mux.HandleFunc("POST /builds", s.startBuild)
+	s.mux.HandleFunc("GET /builds/{id}", s.getBuild)
+	s.mux.HandleFunc("DELETE /builds/{id}", s.cancelBuild)
+	s.mux.HandleFunc("GET /builds/{id}/events", s.buildEvents)
+	s.mux.HandleFunc("GET /builds/{id}/download", s.downloadBuild)
+	s.mux.HandleFunc("GET /v1/models", s.listModels)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	return s
+}
+
+// ServeHTTP checks the request's token and routes it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		if strings.HasPrefix(r.URL.Path, "/v1/") {
+			writeOpenAIError(w, http.StatusUnauthorized, "invalid_api_key", "missing or invalid token")
+		} else {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		}
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether r carries the server's token
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Token)) == 1
+}
+
+// Close cancels every running build
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.builds {
+		b.cancel()
+	}
+}
+
+// agentInfo describes an agent in GET /agents
+type agentInfo struct {
+	Type        models.AgentType `json:"type"`
+	Name        string           `json:"name"`
+	Role        string           `json:"role"`
+	Model       string           `json:"model"`
+	Temperature float32          `json:"temperature"`
+	MaxTokens   int              `json:"max_tokens"`
+}
+
+// listAgents handles GET /agents
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	infos := []agentInfo{}
+	for _, agent := range s.team.Agents() {
+		config := s.team.AgentConfig(agent.Type())
+		infos = append(infos, agentInfo{
+			Type:        agent.Type(),
+			Name:        agent.Name(),
+			Role:        agent.Role(),
+			Model:       config.Model,
+			Temperature: config.Temperature,
+			MaxTokens:   config.MaxTokens,
+		})
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// chatRequest is the body of POST /chat. Message is shorthand for a
+// conversation of one user message.
+type chatRequest struct {
+	Agent    string           `json:"agent"`
+	Message  string           `json:"message,omitempty"`
+	Messages []gocode.Message `json:"messages,omitempty"`
+	Stream   bool             `json:"stream,omitempty"`
+}
+
+// chat handles POST /chat. With "stream": true or an Accept header of
+// text/event-stream the reply streams as delta events followed by a response
+// event.
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Message != "" {
+		req.Messages = append(req.Messages, gocode.Message{Role: models.RoleUser, Content: req.Message})
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "message or messages is required")
+		return
+	}
+
+	agent, err := s.team.FindAgent(strings.TrimPrefix(req.Agent, "@"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if !req.Stream && !wantsEventStream(r) {
+		response, err := s.team.Chat(r.Context(), agent.Type(), req.Messages)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	response, err := s.team.ChatStream(r.Context(), agent.Type(), req.Messages, func(content string) {
+		stream.send("", "delta", map[string]string{"content": content})
+	})
+	if err != nil {
+		stream.send("", "error", map[string]string{"error": err.Error()})
+		return
+	}
+	stream.send("", "response", response)
+}
+
+// buildRequest is the body of POST /builds
+type buildRequest struct {
+	Description string `json:"description"`
+}
+
+// startBuild handles POST /builds. It answers once the build has started,
+// with its ID, which is the run ID of its journal and state.
+func (s *Server) startBuild(w http.ResponseWriter, r *http.Request) {
+	var req buildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		writeError(w, http.StatusBadRequest, "description is required")
+		return
+	}
+
+	s.mu.Lock()
+	if s.running >= s.options.MaxBuilds {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "30")
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%d builds are already running", s.running))
+		return
+	}
+	s.running++
+	s.mu.Unlock()
+
+	b := newBuild(req.Description)
+	go func() {
+		result, err := s.team.Build(b.ctx, req.Description, gocode.BuildSink(b.sink), gocode.BuildEventHandler(b))
+		b.finish(result, err)
+
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-b.started:
+	case <-b.finished:
+	}
+	status := b.status()
+	if status.ID == "" {
+		writeError(w, http.StatusInternalServerError, status.Error)
+		return
+	}
+
+	s.mu.Lock()
+	s.builds[status.ID] = b
+	s.order = append(s.order, status.ID)
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/builds/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// listBuilds handles GET /builds, oldest build first
+func (s *Server) listBuilds(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	statuses := make([]buildStatus, 0, len(s.order))
+	for _, id := range s.order {
+		statuses = append(statuses, s.builds[id].status())
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// getBuild handles GET /builds/{id}: the build's status and, once it has
+// ended, its result. With an Accept header of text/event-stream it streams
+// the build's events instead.
+func (s *Server) getBuild(w http.ResponseWriter, r *http.Request) {
+	if wantsEventStream(r) {
+		s.buildEvents(w, r)
+		return
+	}
+
+	b, ok := s.build(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, b.status())
+}
+
+// cancelBuild handles DELETE /builds/{id}
+func (s *Server) cancelBuild(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.build(w, r)
+	if !ok {
+		return
+	}
+	b.cancel()
+	writeJSON(w, http.StatusAccepted, b.status())
+}
+
+// buildEvents handles GET /builds/{id}/events. Every event of the build so
+// far is sent, then new ones as they happen, each with its index as the
+// event ID; a Last-Event-ID header resumes after that event. The stream ends
+// with a done event carrying the build's status.
+func (s *Server) buildEvents(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.build(w, r)
+	if !ok {
+		return
+	}
+
+	next := 0
+	if last, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		next = last + 1
+	}
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	for {
+		pending, done, changed := b.eventsFrom(next)
+		for _, event := range pending {
+			stream.send(strconv.Itoa(next), string(event.Type), event)
+			next++
+		}
+		if done {
+			stream.send("", "done", b.status())
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// downloadBuild handles GET /builds/{id}/download: the generated files as a
+// zip, once the build has ended
+func (s *Server) downloadBuild(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.build(w, r)
+	if !ok {
+		return
+	}
+	if b.status().Status == statusRunning {
+		writeError(w, http.StatusConflict, "the build is still running")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.PathValue("id")+".zip"))
+	if err := writeZip(w, b.sink); err != nil {
+		// The headers are gone; all that's left is to cut the response short
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// build looks up the build named in the request's path, answering 404 when
+// there is none
+func (s *Server) build(w http.ResponseWriter, r *http.Request) (*build, bool) {
+	s.mu.Lock()
+	b, ok := s.builds[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no build %q", r.PathValue("id")))
+	}
+	return b, ok
+}
+
+// wantsEventStream reports whether the client asked for server-sent events
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// eventStream writes server-sent events
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream starts an event stream response, if w can flush
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+// send writes one event with data encoded as JSON
+func (e *eventStream) send(id, name string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+
+	if id != "" {
+		fmt.Fprintf(e.w, "id: %s\n", id)
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, encoded)
+	e.flusher.Flush()
+}
+
+// data writes an unnamed event with data encoded as JSON, the way the chat
+// completions API streams
+func (e *eventStream) data(data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+
+	fmt.Fprintf(e.w, "data: %s\n\n", encoded)
+	e.flusher.Flush()
+}
+
+// done ends a chat completions stream
+func (e *eventStream) done() {
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	e.flusher.Flush()
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response as {"error": message}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/internal/api"
+	"go-code/internal/llmtest"
+	"go-code/pkg/gocode"
+	"go-code/pkg/models"
+)
+
+const testToken = "secret-token"
+
+// scriptedTeam answers as a planner with a two task plan and as the agents
+// doing the tasks
+func scriptedTeam(req api.ChatRequest) llmtest.Reply {
+	system := llmtest.SystemPrompt(req)
+	switch {
+	case strings.HasPrefix(system, "You are the Planner Agent"):
+		return llmtest.Reply{Content: "1. [BACKEND] Create the todo API\n2. [FRONTEND] Build the todo page"}
+	case strings.HasPrefix(system, "You are the Backend Agent"):
+		return llmtest.Reply{Content: "```js\n// filename: routes/todos.js\nmodule.exports = {};\n```"}
+	case strings.HasPrefix(system, "You are the Frontend Agent"):
+		return llmtest.Reply{Content: "```html\n<!-- public/index.html -->\n<ul id=\"todos\"></ul>\n```"}
+	}
+	return llmtest.Reply{Status: 500, Error: "unexpected agent"}
+}
+
+// newTestServer serves a team talking to a fake API answering with handler
+func newTestServer(t *testing.T, handler llmtest.Handler, options Options) (*httptest.Server, *llmtest.Server) {
+	t.Helper()
+	fake := llmtest.NewServer(t)
+	fake.Handle(handler)
+
+	team, err := gocode.New(gocode.WithClient(fake.Client()), gocode.WithDataDir(t.TempDir()), gocode.WithSecurityScan(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options.Token = testToken
+	srv := New(team, options)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+	return ts, fake
+}
+
+// do sends an authorized request with body encoded as JSON, if not nil
+func do(t *testing.T, method, url string, body interface{}, header ...string) *http.Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decode reads a JSON response body into v, checking the status first
+func decode(t *testing.T, resp *http.Response, status int, v interface{}) {
+	t.Helper()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: status %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	ID   string
+	Name string
+	Data string
+}
+
+// readEvents reads server-sent events until the stream ends
+func readEvents(t *testing.T, body io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			current.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+func TestTokenRequired(t *testing.T) {
+	ts, _ := newTestServer(t, scriptedTeam, Options{})
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testToken} {
+		req, _ := http.NewRequest("GET", ts.URL+"/agents", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/agents?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("token in query: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestListAgents(t *testing.T) {
+	ts, _ := newTestServer(t, scriptedTeam, Options{})
+
+	var agents []agentInfo
+	decode(t, do(t, "GET", ts.URL+"/agents", nil), http.StatusOK, &agents)
+	found := false
+	for _, agent := range agents {
+		if agent.Type == models.BackendAgent {
+			found = agent.Model != "" && agent.MaxTokens > 0
+		}
+	}
+	if !found {
+		t.Errorf("agents = %+v, want the backend agent with its model", agents)
+	}
+}
+
+func TestChat(t *testing.T) {
+	ts, _ := newTestServer(t, func(api.ChatRequest) llmtest.Reply {
+		return llmtest.Reply{Content: "Use PostgreSQL for relational data."}
+	}, Options{})
+
+	var response models.Response
+	decode(t, do(t, "POST", ts.URL+"/chat", chatRequest{Agent: "@backend", Message: "Which database?"}), http.StatusOK, &response)
+	if response.Content != "Use PostgreSQL for relational data." || response.Agent != models.BackendAgent {
+		t.Errorf("response = %+v", response)
+	}
+
+	resp := do(t, "POST", ts.URL+"/chat", chatRequest{Agent: "backend"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("chat without a message: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	ts, _ := newTestServer(t, func(api.ChatRequest) llmtest.Reply {
+		return llmtest.Reply{Content: "Use PostgreSQL for relational data."}
+	}, Options{})
+
+	resp := do(t, "POST", ts.URL+"/chat", chatRequest{
+		Agent:  "backend",
+		Stream: true,
+		Messages: []gocode.Message{
+			{Role: models.RoleUser, Content: "Which database?"},
+			{Role: models.RoleAssistant, Content: "For what data?"},
+			{Role: models.RoleUser, Content: "Relational."},
+		},
+	})
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	events := readEvents(t, resp.Body)
+	var streamed strings.Builder
+	for _, event := range events[:len(events)-1] {
+		if event.Name != "delta" {
+			t.Fatalf("got %s event before the response", event.Name)
+		}
+		var delta struct{ Content string }
+		json.Unmarshal([]byte(event.Data), &delta)
+		streamed.WriteString(delta.Content)
+	}
+	if len(events) < 3 || streamed.String() != "Use PostgreSQL for relational data." {
+		t.Errorf("streamed %q in %d events", streamed.String(), len(events))
+	}
+
+	last := events[len(events)-1]
+	var response models.Response
+	if err := json.Unmarshal([]byte(last.Data), &response); last.Name != "response" || err != nil || response.Content != streamed.String() {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	ts, _ := newTestServer(t, scriptedTeam, Options{})
+
+	var started buildStatus
+	decode(t, do(t, "POST", ts.URL+"/builds", buildRequest{Description: "a todo app"}), http.StatusAccepted, &started)
+	if started.ID == "" || started.Status != statusRunning {
+		t.Fatalf("started build = %+v", started)
+	}
+
+	events := readEvents(t, do(t, "GET", ts.URL+"/builds/"+started.ID+"/events", nil).Body)
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Name)
+	}
+	got := strings.Join(names, ",")
+	for _, want := range []string{"build_started", "plan", "file_written", "task_completed", "summary"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("events %s lack %s", got, want)
+		}
+	}
+	if names[0] != "build_started" || events[0].ID != "0" || names[len(names)-1] != "done" {
+		t.Errorf("events = %s", got)
+	}
+
+	var status buildStatus
+	decode(t, do(t, "GET", ts.URL+"/builds/"+started.ID, nil), http.StatusOK, &status)
+	if status.Status != statusCompleted || status.Result == nil || len(status.Result.Tasks) != 2 || len(status.Files) != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Resuming after the last event only gets the end of the stream
+	resumed := readEvents(t, do(t, "GET", ts.URL+"/builds/"+started.ID, nil,
+		"Accept", "text/event-stream", "Last-Event-ID", events[len(events)-2].ID).Body)
+	if len(resumed) != 1 || resumed[0].Name != "done" {
+		t.Errorf("resumed stream = %+v", resumed)
+	}
+
+	resp := do(t, "GET", ts.URL+"/builds/"+started.ID+"/download", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("download: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	data, _ := io.ReadAll(resp.Body)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, file := range archive.File {
+		files = append(files, file.Name)
+	}
+	if strings.Join(files, ",") != "routes/todos.js,public/index.html" {
+		t.Errorf("zip holds %v", files)
+	}
+
+	var builds []buildStatus
+	decode(t, do(t, "GET", ts.URL+"/builds", nil), http.StatusOK, &builds)
+	if len(builds) != 1 || builds[0].ID != started.ID {
+		t.Errorf("builds = %+v", builds)
+	}
+
+	if resp := do(t, "GET", ts.URL+"/builds/unknown", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown build: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestBuildLimitAndCancel(t *testing.T) {
+	// The planner doesn't answer until the test ends, so builds keep running
+	release := make(chan struct{})
+	ts, _ := newTestServer(t, func(api.ChatRequest) llmtest.Reply {
+		<-release
+		return llmtest.Reply{Status: 500, Error: "released"}
+	}, Options{MaxBuilds: 1})
+	t.Cleanup(func() { close(release) })
+
+	var first buildStatus
+	decode(t, do(t, "POST", ts.URL+"/builds", buildRequest{Description: "a todo app"}), http.StatusAccepted, &first)
+
+	resp := do(t, "POST", ts.URL+"/builds", buildRequest{Description: "a blog"})
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second build: status %d, want 429", resp.StatusCode)
+	}
+	if resp := do(t, "GET", ts.URL+"/builds/"+first.ID+"/download", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("download of a running build: status %d, want 409", resp.StatusCode)
+	}
+
+	decode(t, do(t, "DELETE", ts.URL+"/builds/"+first.ID, nil), http.StatusAccepted, &first)
+	events := readEvents(t, do(t, "GET", ts.URL+"/builds/"+first.ID+"/events", nil).Body)
+	var status buildStatus
+	if err := json.Unmarshal([]byte(events[len(events)-1].Data), &status); err != nil || status.Status != statusCancelled {
+		t.Errorf("cancelled build ended as %+v", status)
+	}
+
+	// The slot is free again once the cancelled build has ended
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := do(t, "POST", ts.URL+"/builds", buildRequest{Description: "a blog"})
+		if resp.StatusCode == http.StatusAccepted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("build after cancel: status %d, want 202", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
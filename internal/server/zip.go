@@ -0,0 +1,26 @@
+package server
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+
+	"go-code/pkg/gocode"
+)
+
+// writeZip writes the files of sink to w as a zip archive
+func writeZip(w io.Writer, sink *gocode.MemorySink) error {
+	archive := zip.NewWriter(w)
+
+	files := sink.Files()
+	for _, name := range sink.Paths() {
+		file, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+		if _, err := file.Write(files[name]); err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,387 @@
+package stack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// goFrameworks maps Go module paths to framework names
+var goFrameworks = map[string]string{
+	"github.com/go-chi/chi":       "chi",
+	"github.com/go-chi/chi/v5":    "chi",
+	"github.com/gin-gonic/gin":    "gin",
+	"github.com/labstack/echo/v4": "echo",
+	"github.com/gofiber/fiber/v2": "fiber",
+	"github.com/gorilla/mux":      "gorilla/mux",
+	"github.com/spf13/cobra":      "cobra",
+	"gorm.io/gorm":                "gorm",
+	"github.com/jackc/pgx/v5":     "pgx",
+	"google.golang.org/grpc":      "grpc",
+}
+
+// nodeFrameworks maps npm packages to framework names
+var nodeFrameworks = map[string]string{
+	"react":         "react",
+	"next":          "next.js",
+	"vue":           "vue",
+	"nuxt":          "nuxt",
+	"@angular/core": "angular",
+	"svelte":        "svelte",
+	"express":       "express",
+	"fastify":       "fastify",
+	"koa":           "koa",
+	"@nestjs/core":  "nestjs",
+	"prisma":        "prisma",
+	"mongoose":      "mongoose",
+}
+
+// pythonFrameworks maps Python distributions to framework names
+var pythonFrameworks = map[string]string{
+	"django":     "django",
+	"flask":      "flask",
+	"fastapi":    "fastapi",
+	"starlette":  "starlette",
+	"tornado":    "tornado",
+	"aiohttp":    "aiohttp",
+	"sqlalchemy": "sqlalchemy",
+	"streamlit":  "streamlit",
+}
+
+// rustFrameworks maps crates to framework names
+var rustFrameworks = map[string]string{
+	"actix-web": "actix-web",
+	"axum":      "axum",
+	"rocket":    "rocket",
+	"warp":      "warp",
+	"tokio":     "tokio",
+	"diesel":    "diesel",
+	"tauri":     "tauri",
+}
+
+// nodeTestRunners are npm packages that run tests, in order of preference,
+// with the command that runs them once
+var nodeTestRunners = []struct{ name, command string }{
+	{"vitest", "npx vitest run"},
+	{"jest", "npx jest"},
+	{"mocha", "npx mocha"},
+	{"ava", "npx ava"},
+}
+
+// golang reads go.mod
+func (d *detector) golang() error {
+	data, err := d.read("go.mod")
+	if data == nil {
+		return err
+	}
+
+	var requires []string
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "require (":
+			inBlock = true
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			requires = append(requires, strings.Fields(line)[0])
+		case strings.HasPrefix(line, "require "):
+			requires = append(requires, strings.Fields(line)[1])
+		}
+	}
+	d.matchFrameworks(goFrameworks, requires)
+
+	d.addLanguage(Go, "go test", "go modules", Commands{
+		Install: "go mod download",
+		Build:   "go build ./...",
+		Test:    "go test ./...",
+	})
+	d.addEntrypoints("main.go")
+	if mains, err := filepath.Glob(filepath.Join(d.root, "cmd", "*", "main.go")); err == nil {
+		for _, main := range mains {
+			rel, _ := filepath.Rel(d.root, main)
+			d.addEntrypoints(filepath.ToSlash(rel))
+		}
+	}
+	return nil
+}
+
+// rust reads Cargo.toml
+func (d *detector) rust() error {
+	data, err := d.read("Cargo.toml")
+	if data == nil {
+		return err
+	}
+	manifest, err := readTOML(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse Cargo.toml: %w", err)
+	}
+
+	var crates []string
+	for _, section := range []string{"dependencies", "dev-dependencies"} {
+		crates = append(crates, keys(manifest[section])...)
+	}
+	d.matchFrameworks(rustFrameworks, crates)
+
+	d.addLanguage(Rust, "cargo test", "cargo", Commands{
+		Install: "cargo fetch",
+		Build:   "cargo build",
+		Test:    "cargo test",
+	})
+	d.addEntrypoints("src/main.rs")
+	if bins, err := filepath.Glob(filepath.Join(d.root, "src", "bin", "*.rs")); err == nil {
+		for _, bin := range bins {
+			d.addEntrypoints("src/bin/" + filepath.Base(bin))
+		}
+	}
+	return nil
+}
+
+// requirementName matches the distribution name at the start of a
+// requirement such as "fastapi[all]>=0.110"
+var requirementName = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// python reads pyproject.toml and requirements.txt
+func (d *detector) python() error {
+	var requirements []string
+	packageManager := "pip"
+	install := "pip install -r requirements.txt"
+	pytest := d.exists("pytest.ini") || d.exists("conftest.py") || d.exists("tests/conftest.py")
+
+	data, err := d.read("pyproject.toml")
+	if err != nil {
+		return err
+	}
+	found := data != nil
+	if data != nil {
+		manifest, err := readTOML(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse pyproject.toml: %w", err)
+		}
+		project, _ := manifest["project"].(map[string]interface{})
+		requirements = append(requirements, stringList(project["dependencies"])...)
+		if optional, ok := project["optional-dependencies"].(map[string]interface{}); ok {
+			for _, group := range optional {
+				requirements = append(requirements, stringList(group)...)
+			}
+		}
+		if groups, ok := manifest["dependency-groups"].(map[string]interface{}); ok {
+			for _, group := range groups {
+				requirements = append(requirements, stringList(group)...)
+			}
+		}
+
+		tool, _ := manifest["tool"].(map[string]interface{})
+		if poetry, ok := tool["poetry"].(map[string]interface{}); ok {
+			packageManager = "poetry"
+			requirements = append(requirements, keys(poetry["dependencies"])...)
+			requirements = append(requirements, keys(poetry["dev-dependencies"])...)
+			if groups, ok := poetry["group"].(map[string]interface{}); ok {
+				for _, group := range groups {
+					if group, ok := group.(map[string]interface{}); ok {
+						requirements = append(requirements, keys(group["dependencies"])...)
+					}
+				}
+			}
+		}
+		if _, ok := tool["pytest"]; ok {
+			pytest = true
+		}
+		install = "pip install -e ."
+
+		if scripts, ok := project["scripts"].(map[string]interface{}); ok {
+			for _, name := range keys(scripts) {
+				d.profile.Entrypoints = append(d.profile.Entrypoints, fmt.Sprintf("%s (%v)", name, scripts[name]))
+			}
+		}
+	}
+
+	data, err = d.read("requirements.txt")
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		found = true
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-") {
+				requirements = append(requirements, line)
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	var names []string
+	for _, requirement := range requirements {
+		if match := requirementName.FindStringSubmatch(requirement); match != nil {
+			name := strings.ToLower(strings.ReplaceAll(match[1], "_", "-"))
+			names = append(names, name)
+			if name == "pytest" {
+				pytest = true
+			}
+		}
+	}
+	d.matchFrameworks(pythonFrameworks, names)
+
+	for _, lock := range []struct{ file, manager, install string }{
+		{"poetry.lock", "poetry", "poetry install"},
+		{"uv.lock", "uv", "uv sync"},
+		{"pdm.lock", "pdm", "pdm install"},
+		{"Pipfile.lock", "pipenv", "pipenv install --dev"},
+	} {
+		if d.exists(lock.file) {
+			packageManager, install = lock.manager, lock.install
+			d.profile.Manifests = append(d.profile.Manifests, lock.file)
+			break
+		}
+	}
+	if packageManager == "poetry" {
+		install = "poetry install"
+	}
+
+	testRunner, test := "unittest", "python -m unittest"
+	if pytest {
+		testRunner, test = "pytest", "pytest"
+	}
+	if packageManager != "pip" {
+		test = packageManager + " run " + test
+	}
+	d.addLanguage(Python, testRunner, packageManager, Commands{Install: install, Test: test})
+	d.addEntrypoints("manage.py", "main.py", "app.py", "wsgi.py", "asgi.py")
+	return nil
+}
+
+// packageJSON is the part of package.json the detector reads
+type packageJSON struct {
+	Main            string            `json:"main"`
+	Bin             json.RawMessage   `json:"bin"`
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+	PackageManager  string            `json:"packageManager"`
+}
+
+// node reads package.json and the lockfiles next to it
+func (d *detector) node() error {
+	data, err := d.read("package.json")
+	if data == nil {
+		return err
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	dependencies := keys(pkg.Dependencies)
+	dependencies = append(dependencies, keys(pkg.DevDependencies)...)
+	d.matchFrameworks(nodeFrameworks, dependencies)
+	has := func(name string) bool {
+		_, dep := pkg.Dependencies[name]
+		_, dev := pkg.DevDependencies[name]
+		return dep || dev
+	}
+
+	language := JavaScript
+	if d.exists("tsconfig.json") || has("typescript") {
+		language = TypeScript
+	}
+
+	packageManager := "npm"
+	for _, lock := range []struct{ file, manager string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun"},
+		{"bun.lock", "bun"},
+		{"package-lock.json", "npm"},
+	} {
+		if d.exists(lock.file) {
+			packageManager = lock.manager
+			d.profile.Manifests = append(d.profile.Manifests, lock.file)
+			break
+		}
+	}
+	if name, _, ok := strings.Cut(pkg.PackageManager, "@"); ok && name != "" {
+		packageManager = name
+	}
+
+	commands := Commands{Install: packageManager + " install"}
+	if _, ok := pkg.Scripts["build"]; ok {
+		commands.Build = packageManager + " run build"
+	}
+	var testRunner string
+	for _, runner := range nodeTestRunners {
+		if has(runner.name) {
+			testRunner, commands.Test = runner.name, runner.command
+			break
+		}
+	}
+	if script, ok := pkg.Scripts["test"]; ok && !strings.Contains(script, "no test specified") {
+		commands.Test = packageManager + " test"
+		if testRunner == "" {
+			testRunner = packageManager + " test"
+		}
+	}
+	d.addLanguage(language, testRunner, packageManager, commands)
+
+	d.addEntrypoints(pkg.Main)
+	var bin map[string]string
+	var binPath string
+	if json.Unmarshal(pkg.Bin, &bin) == nil {
+		for _, name := range keys(bin) {
+			d.addEntrypoints(strings.TrimPrefix(bin[name], "./"))
+		}
+	} else if json.Unmarshal(pkg.Bin, &binPath) == nil {
+		d.addEntrypoints(strings.TrimPrefix(binPath, "./"))
+	}
+	d.addEntrypoints("index.js", "server.js", "app.js", "src/index.js", "src/index.ts", "src/main.ts", "src/main.tsx")
+	return nil
+}
+
+// readTOML parses a TOML document the way the config loader does
+func readTOML(data []byte) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// keys returns the sorted keys of a map, or nil if v isn't one
+func keys(v interface{}) []string {
+	var names []string
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for name := range m {
+			names = append(names, name)
+		}
+	case map[string]string:
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stringList returns the strings in a decoded list
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var values []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -0,0 +1,169 @@
+package stack
+
+import (
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Limits that keep the source scan quick on large projects
+const (
+	maxSourceDepth = 4
+	maxSourceFiles = 500
+	maxSourceBytes = 64 * 1024
+)
+
+// skippedDirs hold dependencies, build output or generated files
+var skippedDirs = map[string]bool{
+	"node_modules":      true,
+	"vendor":            true,
+	"dist":              true,
+	"build":             true,
+	"target":            true,
+	"__pycache__":       true,
+	"venv":              true,
+	"generated-project": true,
+}
+
+// sourceLanguages maps source file extensions to languages
+var sourceLanguages = map[string]string{
+	".go":  Go,
+	".js":  JavaScript,
+	".jsx": JavaScript,
+	".mjs": JavaScript,
+	".cjs": JavaScript,
+	".ts":  TypeScript,
+	".tsx": TypeScript,
+	".py":  Python,
+	".rs":  Rust,
+}
+
+// Import statements, by language. The first group is the imported path.
+var (
+	jsImport     = regexp.MustCompile(`(?:\bfrom\s+|\bimport\s+|\brequire\(\s*)['"]([^'"./][^'"]*)['"]`)
+	pythonImport = regexp.MustCompile(`(?m)^\s*(?:from|import)\s+([A-Za-z_]\w*)`)
+	rustImport   = regexp.MustCompile(`(?m)^\s*(?:pub\s+)?use\s+([a-z_]\w*)`)
+)
+
+// sources scans source files for framework imports. When no manifest named
+// a language, the languages of the source files are recorded instead, most
+// files first.
+func (d *detector) sources() {
+	counts := make(map[string]int)
+	files := 0
+
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if files == maxSourceFiles || strings.HasPrefix(name, ".") {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if entry.IsDir() {
+				if !skippedDirs[name] && depth+1 < maxSourceDepth {
+					walk(path, depth+1)
+				}
+				continue
+			}
+			language, ok := sourceLanguages[filepath.Ext(name)]
+			if !ok {
+				continue
+			}
+			files++
+			counts[language]++
+			d.matchFrameworks(frameworksOf(language), imports(language, head(path)))
+		}
+	}
+	walk(d.root, 0)
+
+	if len(d.profile.Languages) > 0 {
+		return
+	}
+	var languages []string
+	for language := range counts {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if counts[languages[i]] != counts[languages[j]] {
+			return counts[languages[i]] > counts[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+	for _, language := range languages {
+		d.addLanguage(language, "", "", Commands{})
+	}
+}
+
+// frameworksOf returns the known frameworks of a language
+func frameworksOf(language string) map[string]string {
+	switch language {
+	case Go:
+		return goFrameworks
+	case JavaScript, TypeScript:
+		return nodeFrameworks
+	case Python:
+		return pythonFrameworks
+	case Rust:
+		return rustFrameworks
+	}
+	return nil
+}
+
+// imports returns the dependencies a source file imports, named the way
+// the language's manifest names them
+func imports(language, source string) []string {
+	var names []string
+	switch language {
+	case Go:
+		file, err := parser.ParseFile(token.NewFileSet(), "", source, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, spec := range file.Imports {
+			// Packages below a module import the module
+			path := strings.Trim(spec.Path.Value, "`\"")
+			for ; strings.Contains(path, "/"); path = path[:strings.LastIndex(path, "/")] {
+				names = append(names, path)
+			}
+		}
+	case JavaScript, TypeScript:
+		for _, match := range jsImport.FindAllStringSubmatch(source, -1) {
+			parts := strings.SplitN(match[1], "/", 3)
+			name := parts[0]
+			if strings.HasPrefix(name, "@") && len(parts) > 1 {
+				name += "/" + parts[1]
+			}
+			names = append(names, name)
+		}
+	case Python:
+		for _, match := range pythonImport.FindAllStringSubmatch(source, -1) {
+			names = append(names, strings.ToLower(match[1]))
+		}
+	case Rust:
+		for _, match := range rustImport.FindAllStringSubmatch(source, -1) {
+			names = append(names, strings.ReplaceAll(match[1], "_", "-"))
+		}
+	}
+	return names
+}
+
+// head returns the start of a file, where its imports are
+func head(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(io.LimitReader(f, maxSourceBytes))
+	return string(data)
+}
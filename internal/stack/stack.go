@@ -0,0 +1,219 @@
+// Package stack works out which languages, frameworks and tools a project
+// uses from its manifests, lockfiles and source imports.
+package stack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Language names
+const (
+	Go         = "Go"
+	JavaScript = "JavaScript"
+	TypeScript = "TypeScript"
+	Python     = "Python"
+	Rust       = "Rust"
+)
+
+// Profile describes a project's stack. The first language is the primary
+// one; the test runner, package manager and commands are its.
+type Profile struct {
+	Root           string   `json:"root"`
+	Languages      []string `json:"languages"`
+	Frameworks     []string `json:"frameworks"`
+	TestRunner     string   `json:"test_runner,omitempty"`
+	PackageManager string   `json:"package_manager,omitempty"`
+	Entrypoints    []string `json:"entrypoints,omitempty"`
+	Tools          []string `json:"tools,omitempty"`
+	// Manifests are the files the profile was read from
+	Manifests []string `json:"manifests,omitempty"`
+	Commands  Commands `json:"commands"`
+}
+
+// Commands install a project's dependencies, build it and run its tests
+type Commands struct {
+	Install string `json:"install,omitempty"`
+	Build   string `json:"build,omitempty"`
+	Test    string `json:"test,omitempty"`
+}
+
+// Empty reports whether nothing was detected
+func (p *Profile) Empty() bool {
+	return len(p.Languages) == 0 && len(p.Tools) == 0
+}
+
+// Primary returns the project's main language, or "" if none was detected
+func (p *Profile) Primary() string {
+	if len(p.Languages) == 0 {
+		return ""
+	}
+	return p.Languages[0]
+}
+
+// Summary describes the stack in a line, e.g. "Go (chi, gorm); tests: go
+// test; package manager: go modules", or "" if nothing was detected
+func (p *Profile) Summary() string {
+	if p.Empty() {
+		return ""
+	}
+
+	var parts []string
+	stack := strings.Join(p.Languages, ", ")
+	if len(p.Frameworks) > 0 {
+		stack += " (" + strings.Join(p.Frameworks, ", ") + ")"
+	}
+	if stack != "" {
+		parts = append(parts, stack)
+	}
+	if p.TestRunner != "" {
+		parts = append(parts, "tests: "+p.TestRunner)
+	}
+	if p.PackageManager != "" {
+		parts = append(parts, "package manager: "+p.PackageManager)
+	}
+	if len(p.Tools) > 0 {
+		parts = append(parts, "tools: "+strings.Join(p.Tools, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Layout returns the directories a new project in the primary language
+// starts with, or nil if no language was detected
+func (p *Profile) Layout() []string {
+	switch p.Primary() {
+	case Go:
+		return []string{"cmd", "internal"}
+	case Python, Rust:
+		return []string{"src", "tests"}
+	case JavaScript, TypeScript:
+		return []string{"src", "models", "routes", "controllers", "middleware", "config", "utils", "tests"}
+	}
+	return nil
+}
+
+// Detect inspects the project in root
+func Detect(root string) (*Profile, error) {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	d := &detector{root: root, profile: &Profile{Root: root}, frameworks: make(map[string]bool)}
+	for _, detect := range []func() error{d.golang, d.rust, d.python, d.node} {
+		if err := detect(); err != nil {
+			return nil, err
+		}
+	}
+	d.tools()
+	d.sources()
+
+	for name := range d.frameworks {
+		d.profile.Frameworks = append(d.profile.Frameworks, name)
+	}
+	sort.Strings(d.profile.Frameworks)
+	if d.profile.Languages == nil {
+		d.profile.Languages = []string{}
+	}
+	if d.profile.Frameworks == nil {
+		d.profile.Frameworks = []string{}
+	}
+	return d.profile, nil
+}
+
+// detector gathers a profile
+type detector struct {
+	root       string
+	profile    *Profile
+	frameworks map[string]bool
+}
+
+// exists reports whether a file or directory below the root exists
+func (d *detector) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(d.root, filepath.FromSlash(name)))
+	return err == nil
+}
+
+// read returns a manifest's content and records it, or nil if it doesn't
+// exist
+func (d *detector) read(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(d.root, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	d.profile.Manifests = append(d.profile.Manifests, name)
+	return data, nil
+}
+
+// addLanguage records a language once. The first language found sets the
+// test runner, package manager and commands.
+func (d *detector) addLanguage(language, testRunner, packageManager string, commands Commands) {
+	for _, known := range d.profile.Languages {
+		if known == language {
+			return
+		}
+	}
+	primary := len(d.profile.Languages) == 0
+	d.profile.Languages = append(d.profile.Languages, language)
+	if primary {
+		d.profile.TestRunner = testRunner
+		d.profile.PackageManager = packageManager
+		d.profile.Commands = commands
+	}
+}
+
+// addEntrypoints records the entrypoints that exist below the root
+func (d *detector) addEntrypoints(names ...string) {
+	for _, name := range names {
+		if name == "" || !d.exists(name) {
+			continue
+		}
+		for _, known := range d.profile.Entrypoints {
+			if known == name {
+				name = ""
+				break
+			}
+		}
+		if name != "" {
+			d.profile.Entrypoints = append(d.profile.Entrypoints, name)
+		}
+	}
+}
+
+// matchFrameworks records the frameworks among a language's dependencies
+func (d *detector) matchFrameworks(known map[string]string, dependencies []string) {
+	for _, dependency := range dependencies {
+		if name, ok := known[dependency]; ok {
+			d.frameworks[name] = true
+		}
+	}
+}
+
+// tools records container and build tooling
+func (d *detector) tools() {
+	if d.exists("Dockerfile") {
+		d.profile.Tools = append(d.profile.Tools, "Docker")
+		d.profile.Manifests = append(d.profile.Manifests, "Dockerfile")
+	}
+	for _, name := range []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"} {
+		if d.exists(name) {
+			d.profile.Tools = append(d.profile.Tools, "Docker Compose")
+			break
+		}
+	}
+	if d.exists("Makefile") {
+		d.profile.Tools = append(d.profile.Tools, "Make")
+	}
+}
@@ -0,0 +1,170 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// project creates files in a temporary directory and returns it
+func project(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Profile
+	}{
+		{
+			name: "go",
+			files: map[string]string{
+				"go.mod":               "module shop\n\ngo 1.22\n\nrequire (\n\tgithub.com/go-chi/chi/v5 v5.0.12\n\tgorm.io/gorm v1.25.0 // indirect\n)\n\nrequire github.com/spf13/cobra v1.8.0\n",
+				"cmd/shop/main.go":     "package main\n",
+				"internal/db/db.go":    "package db\n\nimport (\n\t\"fmt\"\n\n\tpgx \"github.com/jackc/pgx/v5/pgxpool\"\n)\n",
+				"internal/db/names.go": "package db\n\nvar routers = []string{\n\t\"github.com/gin-gonic/gin\",\n}\n",
+				"Dockerfile":           "FROM golang\n",
+				"Makefile":             "all:\n",
+			},
+			want: Profile{
+				Languages:      []string{Go},
+				Frameworks:     []string{"chi", "cobra", "gorm", "pgx"},
+				TestRunner:     "go test",
+				PackageManager: "go modules",
+				Entrypoints:    []string{"cmd/shop/main.go"},
+				Tools:          []string{"Docker", "Make"},
+				Manifests:      []string{"go.mod", "Dockerfile"},
+				Commands:       Commands{Install: "go mod download", Build: "go build ./...", Test: "go test ./..."},
+			},
+		},
+		{
+			name: "typescript",
+			files: map[string]string{
+				"package.json":   `{"main": "dist/index.js", "scripts": {"build": "tsc", "test": "jest"}, "dependencies": {"express": "^4", "@nestjs/core": "^10"}, "devDependencies": {"jest": "^29", "typescript": "^5"}}`,
+				"pnpm-lock.yaml": "",
+				"src/index.ts":   "import React from 'react';\nimport { x } from './x';\n",
+			},
+			want: Profile{
+				Languages:      []string{TypeScript},
+				Frameworks:     []string{"express", "nestjs", "react"},
+				TestRunner:     "jest",
+				PackageManager: "pnpm",
+				Entrypoints:    []string{"src/index.ts"},
+				Manifests:      []string{"package.json", "pnpm-lock.yaml"},
+				Commands:       Commands{Install: "pnpm install", Build: "pnpm run build", Test: "pnpm test"},
+			},
+		},
+		{
+			name: "python",
+			files: map[string]string{
+				"pyproject.toml":   "[project]\nname = \"api\"\ndependencies = [\"FastAPI[all]>=0.110\", \"SQLAlchemy\"]\n\n[project.optional-dependencies]\ndev = [\"pytest\"]\n",
+				"uv.lock":          "",
+				"main.py":          "import fastapi\nfrom flask import Flask\n",
+				"requirements.txt": "# pinned\n-r base.txt\nuvicorn==0.29\n",
+			},
+			want: Profile{
+				Languages:      []string{Python},
+				Frameworks:     []string{"fastapi", "flask", "sqlalchemy"},
+				TestRunner:     "pytest",
+				PackageManager: "uv",
+				Entrypoints:    []string{"main.py"},
+				Manifests:      []string{"pyproject.toml", "requirements.txt", "uv.lock"},
+				Commands:       Commands{Install: "uv sync", Test: "uv run pytest"},
+			},
+		},
+		{
+			name: "rust",
+			files: map[string]string{
+				"Cargo.toml":  "[package]\nname = \"api\"\n\n[dependencies]\naxum = \"0.7\"\ntokio = { version = \"1\", features = [\"full\"] }\n",
+				"src/main.rs": "use actix_web::App;\n",
+			},
+			want: Profile{
+				Languages:      []string{Rust},
+				Frameworks:     []string{"actix-web", "axum", "tokio"},
+				TestRunner:     "cargo test",
+				PackageManager: "cargo",
+				Entrypoints:    []string{"src/main.rs"},
+				Manifests:      []string{"Cargo.toml"},
+				Commands:       Commands{Install: "cargo fetch", Build: "cargo build", Test: "cargo test"},
+			},
+		},
+		{
+			name: "sources only",
+			files: map[string]string{
+				"app.py":                      "from django.http import HttpResponse\n",
+				"views.py":                    "",
+				"static/site.js":              "",
+				"node_modules/vue/index.js":   "",
+				".venv/lib/site.py":           "",
+				"generated-project/server.js": "",
+			},
+			want: Profile{
+				Languages:  []string{Python, JavaScript},
+				Frameworks: []string{"django"},
+			},
+		},
+		{
+			name:  "empty",
+			files: map[string]string{"notes.txt": ""},
+			want:  Profile{Languages: []string{}, Frameworks: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := project(t, tt.files)
+			got, err := Detect(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Root = got.Root
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Detect =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectErrors(t *testing.T) {
+	if _, err := Detect(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Detect of a missing directory succeeded")
+	}
+	root := project(t, map[string]string{"package.json": "{"})
+	if _, err := Detect(root); err == nil {
+		t.Error("Detect with a broken package.json succeeded")
+	}
+}
+
+func TestProfileSummaryAndLayout(t *testing.T) {
+	p := &Profile{
+		Languages:      []string{Go, TypeScript},
+		Frameworks:     []string{"chi"},
+		TestRunner:     "go test",
+		PackageManager: "go modules",
+		Tools:          []string{"Docker"},
+	}
+	if want := "Go, TypeScript (chi); tests: go test; package manager: go modules; tools: Docker"; p.Summary() != want {
+		t.Errorf("Summary = %q, want %q", p.Summary(), want)
+	}
+	if want := []string{"cmd", "internal"}; !reflect.DeepEqual(p.Layout(), want) {
+		t.Errorf("Layout = %v, want %v", p.Layout(), want)
+	}
+
+	empty := &Profile{}
+	if empty.Summary() != "" || empty.Layout() != nil || empty.Primary() != "" {
+		t.Errorf("empty profile = %q, %v, %q", empty.Summary(), empty.Layout(), empty.Primary())
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,7 +12,7 @@ import (
 
 // DisplayAgentHeader shows the agent information before processing
 func DisplayAgentHeader(agent models.Agent) {
-	fmt.Println()
+	fmt.Fprintln(Stdout)
 	
 	// Agent info with color and icon
 	agentColor := agent.Color()
@@ -21,26 +22,26 @@ func DisplayAgentHeader(agent models.Agent) {
 	gray := color.New(color.FgHiBlack)
 	gray.Printf("   %s\n", agent.Role())
 	
-	fmt.Println(strings.Repeat("─", 50))
-	fmt.Println()
+	fmt.Fprintln(Stdout, strings.Repeat("─", 50))
+	fmt.Fprintln(Stdout)
 }
 
 // DisplayAgentResponse shows the agent's response with formatting
 func DisplayAgentResponse(agent models.Agent, response *models.Response) {
-	fmt.Println()
+	fmt.Fprintln(Stdout)
 	
 	// Response header
 	agentColor := agent.Color()
 	agentColor.Printf("%s %s:\n", agent.Icon(), agent.Name())
-	fmt.Println()
+	fmt.Fprintln(Stdout)
 	
 	// Response content
-	fmt.Println(response.Content)
-	fmt.Println()
+	fmt.Fprintln(Stdout, response.Content)
+	fmt.Fprintln(Stdout)
 	
 	// Metadata
 	displayResponseMetadata(response)
-	fmt.Println()
+	fmt.Fprintln(Stdout)
 }
 
 // displayResponseMetadata shows token usage and model info
@@ -54,7 +55,16 @@ func displayResponseMetadata(response *models.Response) {
 	}
 	
 	if response.TokensUsed > 0 {
-		metadata = append(metadata, fmt.Sprintf("Tokens: %d", response.TokensUsed))
+		metadata = append(metadata, fmt.Sprintf("Tokens: %d (%d prompt, %d completion)",
+			response.TokensUsed, response.PromptTokens, response.CompletionTokens))
+	}
+	
+	if response.Cost > 0 {
+		metadata = append(metadata, fmt.Sprintf("Cost: %s", FormatCost(response.Cost)))
+	}
+	
+	if response.Cached {
+		metadata = append(metadata, "Cached")
 	}
 	
 	if len(metadata) > 0 {
@@ -72,7 +82,7 @@ func DisplayThinking(agent models.Agent) {
 		agentColor.Printf("\r%s %s is thinking... %s", agent.Icon(), agent.Name(), char)
 		time.Sleep(200 * time.Millisecond)
 	}
-	fmt.Print("\r" + strings.Repeat(" ", 50) + "\r") // Clear line
+	fmt.Fprint(Stdout, "\r" + strings.Repeat(" ", 50) + "\r") // Clear line
 }
 
 // DisplayError shows an error message
@@ -101,8 +111,8 @@ func DisplayInfo(message string) {
 
 // DisplayAgentList shows a formatted list of agents
 func DisplayAgentList(agents []models.Agent) {
-	fmt.Println("🤖 Available Agents:")
-	fmt.Println()
+	fmt.Fprintln(Stdout, "🤖 Available Agents:")
+	fmt.Fprintln(Stdout)
 	
 	for _, agent := range agents {
 		agentColor := agent.Color()
@@ -110,38 +120,46 @@ func DisplayAgentList(agents []models.Agent) {
 		
 		gray := color.New(color.FgHiBlack)
 		gray.Printf("     %s\n", agent.Role())
-		fmt.Println()
+		fmt.Fprintln(Stdout)
 	}
 }
 
 // DisplayHelp shows help information
 func DisplayHelp() {
-	fmt.Println("🚀 go-code - AI Development Team CLI")
-	fmt.Println()
-	fmt.Println("Commands:")
-	fmt.Println("  init                    Initialize configuration")
-	fmt.Println("  agents                  List available agents")
-	fmt.Println("  chat @agent message     Chat with an agent")
-	fmt.Println("  config                  Manage configuration")
-	fmt.Println()
-	fmt.Println("Agent Examples:")
-	fmt.Println("  go-code chat @planner \"Plan a web app\"")
-	fmt.Println("  go-code chat @frontend \"Create React component\"")
-	fmt.Println("  go-code chat @backend \"Design REST API\"")
-	fmt.Println("  go-code chat @security \"Review this code\"")
-	fmt.Println()
-	fmt.Println("For more help: go-code --help")
+	fmt.Fprintln(Stdout, "🚀 go-code - AI Development Team CLI")
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(Stdout, "Commands:")
+	fmt.Fprintln(Stdout, "  init                    Initialize configuration")
+	fmt.Fprintln(Stdout, "  agents                  List available agents")
+	fmt.Fprintln(Stdout, "  chat @agent message     Chat with an agent")
+	fmt.Fprintln(Stdout, "  config                  Manage configuration")
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(Stdout, "Agent Examples:")
+	fmt.Fprintln(Stdout, "  go-code chat @planner \"Plan a web app\"")
+	fmt.Fprintln(Stdout, "  go-code chat @frontend \"Create React component\"")
+	fmt.Fprintln(Stdout, "  go-code chat @backend \"Design REST API\"")
+	fmt.Fprintln(Stdout, "  go-code chat @security \"Review this code\"")
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(Stdout, "For more help: go-code --help")
 }
 
 // ClearScreen clears the terminal screen
 func ClearScreen() {
-	fmt.Print("\033[2J\033[H")
+	if format != OutputText {
+		return
+	}
+	fmt.Fprint(Stdout, "\033[2J\033[H")
 }
 
 // DisplayProgress shows progress with stage replacement
 func DisplayProgress(stage string, current, total int, startTime time.Time) {
+	// Rewriting the line only works on a terminal
+	if format != OutputText {
+		return
+	}
+	
 	// Clear the line and move cursor to beginning
-	fmt.Print("\r\033[K")
+	fmt.Fprint(Stdout, "\r\033[K")
 	
 	elapsed := time.Since(startTime)
 	cyan := color.New(color.FgCyan, color.Bold)
@@ -152,7 +170,7 @@ func DisplayProgress(stage string, current, total int, startTime time.Time) {
 	progressBar := strings.Repeat("█", int(progress/5)) + strings.Repeat("░", 20-int(progress/5))
 	
 	cyan.Printf("🔄 Stage %d/%d: %s ", current, total, stage)
-	fmt.Printf("[%s] %.0f%% ", progressBar, progress)
+	fmt.Fprintf(Stdout, "[%s] %.0f%% ", progressBar, progress)
 	yellow.Printf("(⏱️ %s)", elapsed.Round(time.Second))
 }
 
@@ -161,29 +179,196 @@ func DisplayStageComplete(stage string, current, total int, startTime time.Time)
 	elapsed := time.Since(startTime)
 	green := color.New(color.FgGreen, color.Bold)
 	
-	fmt.Print("\r\033[K")
+	if format == OutputText {
+		fmt.Fprint(Stdout, "\r\033[K")
+	}
 	green.Printf("✅ Stage %d/%d: %s completed (⏱️ %s)\n", current, total, stage, elapsed.Round(time.Second))
 }
 
-// DisplayFinalResults shows final completion with total time
-func DisplayFinalResults(totalStages int, startTime time.Time, projectPath string) {
+// DisplayFinalResults shows final completion with total time and usage
+func DisplayFinalResults(totalStages int, startTime time.Time, projectPath string, usage models.UsageSummary) {
 	totalTime := time.Since(startTime)
 	green := color.New(color.FgGreen, color.Bold)
 	cyan := color.New(color.FgCyan)
 	
-	fmt.Println()
-	fmt.Println(strings.Repeat("═", 60))
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(Stdout, strings.Repeat("═", 60))
 	green.Printf("🎉 BUILD COMPLETED SUCCESSFULLY! \n")
-	fmt.Println(strings.Repeat("═", 60))
-	fmt.Printf("📊 Total stages: %d\n", totalStages)
-	fmt.Printf("⏱️  Total time: %s\n", totalTime.Round(time.Second))
-	fmt.Printf("📁 Project location: %s\n", projectPath)
-	fmt.Println()
+	fmt.Fprintln(Stdout, strings.Repeat("═", 60))
+	fmt.Fprintf(Stdout, "📊 Total stages: %d\n", totalStages)
+	fmt.Fprintf(Stdout, "⏱️  Total time: %s\n", totalTime.Round(time.Second))
+	fmt.Fprintf(Stdout, "📁 Project location: %s\n", projectPath)
+	DisplayUsage(usage)
+	fmt.Fprintln(Stdout)
 	cyan.Println("Manual setup required:")
-	fmt.Println("  cd generated-project")
-	fmt.Println("  npm install           # Install dependencies") 
-	fmt.Println("  # Set up database (if needed)")
-	fmt.Println("  npm start            # Start application")
-	fmt.Println()
-	fmt.Println("💡 go-code only generates files - you must run setup commands manually")
+	fmt.Fprintln(Stdout, "  cd generated-project")
+	fmt.Fprintln(Stdout, "  npm install           # Install dependencies") 
+	fmt.Fprintln(Stdout, "  # Set up database (if needed)")
+	fmt.Fprintln(Stdout, "  npm start            # Start application")
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(Stdout, "💡 go-code only generates files - you must run setup commands manually")
+}
+
+// DisplayUsage shows the tokens and cost of a run, per task and per agent
+func DisplayUsage(usage models.UsageSummary) {
+	gray := color.New(color.FgHiBlack)
+	
+	total := usage.Total
+	fmt.Fprintf(Stdout, "🪙 Tokens: %d (%d prompt, %d completion) in %d requests • Cost: %s\n",
+		total.TotalTokens(), total.PromptTokens, total.CompletionTokens, total.Requests, FormatCost(total.Cost))
+	
+	if budget := usage.Budget; budget.MaxTokens > 0 || budget.MaxCost > 0 {
+		limits := []string{}
+		if budget.MaxTokens > 0 {
+			limits = append(limits, fmt.Sprintf("%d tokens", budget.MaxTokens))
+		}
+		if budget.MaxCost > 0 {
+			limits = append(limits, FormatCost(budget.MaxCost))
+		}
+		gray.Printf("   Budget: %s\n", strings.Join(limits, ", "))
+	}
+	
+	for _, task := range usage.ByTask {
+		gray.Printf("   %-10s %-40s %7d tokens  %s\n", task.Agent, truncate(task.Task, 40), task.Usage.TotalTokens(), FormatCost(task.Usage.Cost))
+	}
+	
+	agents := make([]string, 0, len(usage.ByAgent))
+	for agent := range usage.ByAgent {
+		agents = append(agents, string(agent))
+	}
+	sort.Strings(agents)
+	if len(agents) > 1 {
+		parts := make([]string, 0, len(agents))
+		for _, agent := range agents {
+			agentUsage := usage.ByAgent[models.AgentType(agent)]
+			parts = append(parts, fmt.Sprintf("%s %d (%s)", agent, agentUsage.TotalTokens(), FormatCost(agentUsage.Cost)))
+		}
+		gray.Printf("   By agent: %s\n", strings.Join(parts, " • "))
+	}
+}
+
+// FormatCost formats a US dollar amount, keeping sub-cent precision
+func FormatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// DisplayReviewFindings shows the review loop outcome for a task
+func DisplayReviewFindings(task string, findings []models.Finding, passed bool) {
+	green := color.New(color.FgGreen)
+	yellow := color.New(color.FgYellow)
+	gray := color.New(color.FgHiBlack)
+	
+	fmt.Fprintln(Stdout)
+	if passed {
+		green.Printf("🔍 %s: review passed\n", task)
+	} else {
+		yellow.Printf("🔍 %s: unresolved findings remain\n", task)
+	}
+	
+	for _, f := range findings {
+		displayFinding(f)
+		if f.Fix != "" {
+			gray.Printf("      round %d fix: %s\n", f.Round, f.Fix)
+		}
+	}
+}
+
+// maxTestOutputLines is how much of failing tests' output is shown
+const maxTestOutputLines = 10
+
+// DisplayTestResult shows a task's acceptance test outcome: the criteria,
+// and the end of the test output if they failed
+func DisplayTestResult(task string, result *models.TestResult) {
+	gray := color.New(color.FgHiBlack)
+	
+	fmt.Fprintln(Stdout)
+	switch result.Status {
+	case models.TestsPassed:
+		attempts := ""
+		if result.Attempts > 1 {
+			attempts = fmt.Sprintf(" after %d runs", result.Attempts)
+		}
+		color.New(color.FgGreen).Printf("🧪 %s: acceptance tests passed%s\n", task, attempts)
+	case models.TestsFailed:
+		color.New(color.FgRed).Printf("🧪 %s: acceptance tests failed (%s)\n", task, result.Reason)
+	default:
+		color.New(color.FgYellow).Printf("🧪 %s: acceptance tests not run (%s)\n", task, result.Reason)
+	}
+	
+	for _, criterion := range result.Criteria {
+		fmt.Fprintf(Stdout, "   - %s\n", criterion)
+	}
+	if len(result.Files) > 0 {
+		gray.Printf("   tests: %s\n", strings.Join(result.Files, ", "))
+	}
+	if result.Status == models.TestsFailed {
+		lines := strings.Split(strings.TrimRight(result.Output, "\n"), "\n")
+		if len(lines) > maxTestOutputLines {
+			lines = lines[len(lines)-maxTestOutputLines:]
+		}
+		for _, line := range lines {
+			gray.Printf("   | %s\n", line)
+		}
+	}
+}
+
+// DisplayScanFindings shows the static security scan results
+func DisplayScanFindings(findings []models.Finding, reportPath string) {
+	gray := color.New(color.FgHiBlack)
+	
+	fmt.Fprintln(Stdout)
+	if len(findings) == 0 {
+		color.New(color.FgGreen).Println("🛡️  Security scan: no issues found")
+	} else {
+		color.New(color.FgYellow).Printf("🛡️  Security scan: %d issue(s) found\n", len(findings))
+	}
+	
+	for _, f := range findings {
+		displayFinding(f)
+		if f.Fix != "" {
+			gray.Printf("      fix: %s\n", f.Fix)
+		}
+	}
+	
+	if reportPath != "" {
+		gray.Printf("   SARIF report: %s\n", reportPath)
+	}
+}
+
+// displayFinding prints a finding's severity, location and message
+func displayFinding(f models.Finding) {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	label := string(f.Severity)
+	if f.Rule != "" {
+		label = f.Rule + " " + label
+	}
+	severityColor(f.Severity).Printf("   [%s] ", label)
+	fmt.Fprintf(Stdout, "%s %s\n", location, f.Message)
+}
+
+// severityColor returns the color used to display a finding's severity
+func severityColor(severity models.Severity) *color.Color {
+	switch severity {
+	case models.SeverityCritical, models.SeverityHigh:
+		return color.New(color.FgRed, color.Bold)
+	case models.SeverityMedium:
+		return color.New(color.FgYellow)
+	default:
+		return color.New(color.FgHiBlack)
+	}
 }
\ No newline at end of file
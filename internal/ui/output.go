@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// OutputFormat selects how commands write their results
+type OutputFormat string
+
+const (
+	// OutputText is colored, human-readable output for a terminal
+	OutputText OutputFormat = "text"
+	// OutputPlain is human-readable output without color or cursor movement;
+	// emoji are dropped from chat and build output
+	OutputPlain OutputFormat = "plain"
+	// OutputJSON writes one JSON document per command
+	OutputJSON OutputFormat = "json"
+	// OutputNDJSON writes one JSON object per line as things happen
+	OutputNDJSON OutputFormat = "ndjson"
+)
+
+// OutputFormats lists the accepted --output values
+var OutputFormats = []OutputFormat{OutputText, OutputPlain, OutputJSON, OutputNDJSON}
+
+// Stdout receives all human-readable output. It is discarded in the JSON
+// formats so that stdout carries nothing but JSON.
+var Stdout io.Writer = os.Stdout
+
+var (
+	format      = OutputText
+	colorOutput = color.Output
+)
+
+// SetOutput selects the output format. An empty format picks text on a
+// terminal and plain when stdout is redirected.
+func SetOutput(f OutputFormat) error {
+	if f == "" {
+		f = OutputText
+		if !IsTerminal() {
+			f = OutputPlain
+		}
+	}
+
+	switch f {
+	case OutputText:
+		Stdout = colorOutput
+	case OutputPlain:
+		color.NoColor = true
+		Stdout = &plainWriter{w: os.Stdout}
+	case OutputJSON, OutputNDJSON:
+		color.NoColor = true
+		Stdout = io.Discard
+	default:
+		return fmt.Errorf("unknown output format %q (use text, plain, json or ndjson)", f)
+	}
+
+	format = f
+	color.Output = Stdout
+	return nil
+}
+
+// Output returns the selected output format
+func Output() OutputFormat {
+	return format
+}
+
+// IsMachineReadable reports whether a JSON format was selected
+func IsMachineReadable() bool {
+	return format == OutputJSON || format == OutputNDJSON
+}
+
+// Silence discards human-readable output until the returned function is
+// called, e.g. while a full-screen view owns the terminal
+func Silence() (restore func()) {
+	stdout, output := Stdout, color.Output
+	Stdout, color.Output = io.Discard, io.Discard
+	return func() {
+		Stdout, color.Output = stdout, output
+	}
+}
+
+// IsTerminal reports whether stdout is an interactive terminal
+func IsTerminal() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// PrintJSON writes v to stdout, indented for json and on one line for ndjson
+func PrintJSON(v interface{}) error {
+	var data []byte
+	var err error
+	if format == OutputNDJSON {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
+
+// plainWriter drops emoji, so plain output reads cleanly in CI logs
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p *plainWriter) Write(data []byte) (int, error) {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		i += size
+		if isEmoji(r) {
+			// Drop the spaces that separated the emoji from the text
+			for i < len(data) && data[i] == ' ' {
+				i++
+			}
+			continue
+		}
+		out = append(out, data[i-size:i]...)
+	}
+	if _, err := p.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// isEmoji reports whether r is a pictograph or an emoji modifier
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF, r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r == 0x2139, r == 0xFE0F, r == 0x200D:
+		return true
+	}
+	return false
+}
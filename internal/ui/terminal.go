@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go-code/internal/events"
+	"go-code/pkg/models"
+)
+
+// AgentLookup finds the agent behind an agent type, for its name and colors
+type AgentLookup func(agentType models.AgentType) (models.Agent, error)
+
+// Terminal renders build events as the human-readable progress output
+type Terminal struct {
+	mu      sync.Mutex
+	agents  AgentLookup
+	started time.Time
+	reviews []reviewedTask
+	tests   []testedTask
+}
+
+// reviewedTask is a task's review outcome, shown once the build is done
+type reviewedTask struct {
+	task   string
+	review events.Review
+}
+
+// testedTask is a task's acceptance test outcome, shown once the build is
+// done
+type testedTask struct {
+	task   string
+	result *models.TestResult
+}
+
+// NewTerminal creates a terminal renderer. agents is used to show agent
+// responses with the agent's name and colors.
+func NewTerminal(agents AgentLookup) *Terminal {
+	return &Terminal{agents: agents, started: time.Now()}
+}
+
+// Handle renders the event
+func (t *Terminal) Handle(event events.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Type {
+	case events.BuildStarted:
+		t.started = event.Time
+		ClearScreen()
+		fmt.Fprintf(Stdout, "🚀 Building: %s\n\n", event.Message)
+	case events.StageStarted:
+		DisplayProgress(event.Stage, event.Step, event.Total, t.started)
+	case events.StageCompleted:
+		DisplayStageComplete(event.Stage, event.Step, event.Total, t.started)
+	case events.TaskCompleted:
+		if event.Review != nil {
+			t.reviews = append(t.reviews, reviewedTask{task: event.Task, review: *event.Review})
+		}
+		t.addTests(event)
+	case events.TaskFailed:
+		DisplayError(fmt.Errorf("task failed: %s", event.Error))
+		t.addTests(event)
+	case events.TaskSkipped:
+		DisplayWarning(fmt.Sprintf("Skipped task: %s", event.Task))
+	case events.Error:
+		if event.Path != "" {
+			fmt.Fprintf(Stdout, "⚠️  Failed to write %s: %s\n", event.Path, event.Error)
+		}
+	case events.Warning:
+		DisplayWarning(event.Message)
+	case events.BudgetExceeded:
+		if event.Summary != nil {
+			DisplayUsage(*event.Summary)
+		}
+	case events.AgentResponse:
+		t.displayResponse(event)
+	case events.Summary:
+		t.displaySummary(event)
+	}
+}
+
+// addTests keeps a task's acceptance test outcome for the summary
+func (t *Terminal) addTests(event events.Event) {
+	if event.Tests != nil {
+		t.tests = append(t.tests, testedTask{task: event.Task, result: event.Tests})
+	}
+}
+
+// displayResponse shows an agent's response with the agent's header
+func (t *Terminal) displayResponse(event events.Event) {
+	if event.Response == nil || t.agents == nil {
+		return
+	}
+	agent, err := t.agents(event.Agent)
+	if err != nil {
+		return
+	}
+	DisplayAgentResponse(agent, event.Response)
+}
+
+// displaySummary shows the final results, the review and acceptance test
+// outcomes and the security scan findings
+func (t *Terminal) displaySummary(event events.Event) {
+	var usage models.UsageSummary
+	if event.Summary != nil {
+		usage = *event.Summary
+	}
+	DisplayFinalResults(event.Total, t.started, event.Path, usage)
+
+	for _, reviewed := range t.reviews {
+		DisplayReviewFindings(reviewed.task, reviewed.review.Findings, reviewed.review.Passed)
+	}
+	for _, tested := range t.tests {
+		DisplayTestResult(tested.task, tested.result)
+	}
+	if event.Report != "" {
+		DisplayScanFindings(event.Findings, event.Report)
+	}
+}
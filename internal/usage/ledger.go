@@ -0,0 +1,182 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// Entry is one request recorded in the usage ledger
+type Entry struct {
+	Time             time.Time        `json:"time"`
+	Command          string           `json:"command"`
+	RunID            string           `json:"run_id,omitempty"`
+	Task             string           `json:"task,omitempty"`
+	Agent            models.AgentType `json:"agent"`
+	Model            string           `json:"model"`
+	PromptTokens     int              `json:"prompt_tokens"`
+	CompletionTokens int              `json:"completion_tokens"`
+	Cost             float64          `json:"cost"`
+}
+
+// Usage returns the entry as a single-request usage
+func (e Entry) Usage() models.Usage {
+	return models.Usage{
+		Requests:         1,
+		PromptTokens:     e.PromptTokens,
+		CompletionTokens: e.CompletionTokens,
+		Cost:             e.Cost,
+	}
+}
+
+// Total is the usage of every entry sharing a key
+type Total struct {
+	Key string
+	models.Usage
+}
+
+// Groupings accepted by Summarize
+var Groupings = []string{"agent", "model", "day", "run", "command"}
+
+// DefaultLedgerPath returns where usage is recorded
+func DefaultLedgerPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-code", "usage.jsonl")
+}
+
+// Append adds an entry to the ledger at path, one JSON object per line
+func Append(path string, entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage entry: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return nil
+}
+
+// Read returns the ledger entries recorded at or after since. A missing
+// ledger has no entries; malformed lines are skipped.
+func Read(path string, since time.Time) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// ParseSince turns a --since value, a duration such as 7d, 12h or 2w or a
+// date, into the time it refers to relative to now. Empty means no limit.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since %q, expected e.g. 7d, 12h, 2w or 2024-06-01", value)
+}
+
+// Summarize totals entries by agent, model, day, run or command, most
+// expensive first
+func Summarize(entries []Entry, by string) ([]Total, error) {
+	key := func(e Entry) string { return "" }
+	switch by {
+	case "agent":
+		key = func(e Entry) string { return string(e.Agent) }
+	case "model":
+		key = func(e Entry) string { return e.Model }
+	case "day":
+		key = func(e Entry) string { return e.Time.Local().Format("2006-01-02") }
+	case "run":
+		key = func(e Entry) string {
+			if e.RunID == "" {
+				return "(" + e.Command + ")"
+			}
+			return e.RunID
+		}
+	case "command":
+		key = func(e Entry) string { return e.Command }
+	default:
+		return nil, fmt.Errorf("unknown grouping '%s' (use one of %v)", by, Groupings)
+	}
+
+	totals := make(map[string]*Total)
+	var order []string
+	for _, entry := range entries {
+		k := key(entry)
+		if totals[k] == nil {
+			totals[k] = &Total{Key: k}
+			order = append(order, k)
+		}
+		totals[k].Add(entry.Usage())
+	}
+
+	result := make([]Total, 0, len(order))
+	for _, k := range order {
+		result = append(result, *totals[k])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if by == "day" {
+			return result[i].Key < result[j].Key
+		}
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].TotalTokens() > result[j].TotalTokens()
+	})
+	return result, nil
+}
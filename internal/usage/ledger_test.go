@@ -0,0 +1,149 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// day returns noon on the given day of June 2024
+func day(d int) time.Time {
+	return time.Date(2024, 6, d, 12, 0, 0, 0, time.Local)
+}
+
+func TestLedgerAcrossDays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.jsonl")
+
+	entries := []Entry{
+		{Time: day(1), Command: "chat", Agent: models.PlannerAgent, Model: "a", PromptTokens: 10, CompletionTokens: 5, Cost: 0.01},
+		{Time: day(2), Command: "build", RunID: "run-1", Agent: models.BackendAgent, Model: "b", PromptTokens: 100, CompletionTokens: 50, Cost: 0.10},
+		{Time: day(3), Command: "build", RunID: "run-1", Agent: models.BackendAgent, Model: "b", PromptTokens: 200, CompletionTokens: 20, Cost: 0.20},
+	}
+	for _, entry := range entries {
+		if err := Append(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A malformed line, e.g. from a crash mid-write, is skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time": "2024-06-0` + "\n")
+	file.Close()
+
+	tests := []struct {
+		since time.Time
+		want  int
+	}{
+		{time.Time{}, 3},
+		{day(2).Add(-time.Hour), 2},
+		{day(3), 1},
+		{day(4), 0},
+	}
+	for _, tt := range tests {
+		got, err := Read(path, tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("Read since %v: %d entries, want %d", tt.since, len(got), tt.want)
+		}
+	}
+
+	got, _ := Read(path, time.Time{})
+	if got[1].RunID != "run-1" || got[1].Agent != models.BackendAgent || got[1].PromptTokens != 100 || !got[1].Time.Equal(day(2)) {
+		t.Errorf("entry did not round-trip: %+v", got[1])
+	}
+
+	if got, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"), time.Time{}); err != nil || got != nil {
+		t.Errorf("Read of a missing ledger = %v, %v", got, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	entries := []Entry{
+		{Time: day(2), Command: "chat", Agent: models.PlannerAgent, Model: "cheap", PromptTokens: 10, CompletionTokens: 5, Cost: 0.01},
+		{Time: day(1), Command: "build", RunID: "run-1", Agent: models.BackendAgent, Model: "dear", PromptTokens: 100, CompletionTokens: 50, Cost: 0.10},
+		{Time: day(1), Command: "build", RunID: "run-1", Agent: models.BackendAgent, Model: "free", PromptTokens: 300, CompletionTokens: 0},
+		{Time: day(3), Command: "build", RunID: "run-2", Agent: models.PlannerAgent, Model: "cheap", PromptTokens: 20, CompletionTokens: 10, Cost: 0.02},
+	}
+
+	tests := []struct {
+		by   string
+		keys []string
+	}{
+		// Most expensive first, then most tokens
+		{"agent", []string{"backend", "planner"}},
+		{"model", []string{"dear", "cheap", "free"}},
+		{"run", []string{"run-1", "run-2", "(chat)"}},
+		{"command", []string{"build", "chat"}},
+		// Days are in date order
+		{"day", []string{"2024-06-01", "2024-06-02", "2024-06-03"}},
+	}
+	for _, tt := range tests {
+		totals, err := Summarize(entries, tt.by)
+		if err != nil {
+			t.Fatalf("by %s: %v", tt.by, err)
+		}
+
+		var keys []string
+		for _, total := range totals {
+			keys = append(keys, total.Key)
+		}
+		if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+			t.Errorf("by %s: keys = %v, want %v", tt.by, keys, tt.keys)
+		}
+	}
+
+	totals, _ := Summarize(entries, "agent")
+	backend := totals[0]
+	if backend.Requests != 2 || backend.PromptTokens != 400 || backend.CompletionTokens != 50 || backend.Cost != 0.10 {
+		t.Errorf("backend total = %+v", backend)
+	}
+
+	if _, err := Summarize(entries, "weekday"); err == nil || !strings.Contains(err.Error(), "unknown grouping") {
+		t.Errorf("error = %v, want unknown grouping", err)
+	}
+	if totals, err := Summarize(nil, "model"); err != nil || len(totals) != 0 {
+		t.Errorf("Summarize(nil) = %v, %v", totals, err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"7d", now.Add(-7 * 24 * time.Hour)},
+		{"0d", now},
+		{"2w", now.Add(-14 * 24 * time.Hour)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.value, now)
+		if err != nil {
+			t.Errorf("ParseSince(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"d", "-3d", "1.5d", "week", "-2h", "2024-13-01", "06/01/2024", "7 days"} {
+		if _, err := ParseSince(value, now); err == nil || !strings.Contains(err.Error(), "invalid --since") {
+			t.Errorf("ParseSince(%q) error = %v, want invalid --since", value, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go-code/internal/catalog"
+	"go-code/pkg/models"
+)
+
+// Tracker prices every response of a command, records it in the ledger and
+// keeps running totals for the budget
+type Tracker struct {
+	mu         sync.Mutex
+	ledgerPath string
+	catalog    *catalog.Catalog
+	command    string
+	runID      string
+	budget     models.Budget
+	summary    models.UsageSummary
+}
+
+// NewTracker creates a tracker that records to ledgerPath. An empty path
+// keeps totals without writing a ledger.
+func NewTracker(ledgerPath, command, runID string, budget models.Budget) *Tracker {
+	return &Tracker{
+		ledgerPath: ledgerPath,
+		catalog:    catalog.Load(catalog.DefaultCachePath()),
+		command:    command,
+		runID:      runID,
+		budget:     budget,
+		summary: models.UsageSummary{
+			ByAgent: make(map[models.AgentType]models.Usage),
+			Budget:  budget,
+		},
+	}
+}
+
+// Record prices a response, sets its Cost, appends it to the ledger and adds
+// it to the totals. It returns the response's usage. Responses served from
+// the cache cost nothing and aren't recorded.
+func (t *Tracker) Record(task string, response *models.Response) models.Usage {
+	if response.Cached {
+		return models.Usage{}
+	}
+
+	entry := Entry{
+		Time:             time.Now().UTC(),
+		Command:          t.command,
+		RunID:            t.runID,
+		Task:             task,
+		Agent:            response.Agent,
+		Model:            response.Model,
+		PromptTokens:     response.PromptTokens,
+		CompletionTokens: response.CompletionTokens,
+	}
+	if model, ok := t.catalog.Lookup(response.Model); ok {
+		entry.Cost = model.Cost(entry.PromptTokens, entry.CompletionTokens)
+	}
+	response.Cost = entry.Cost
+
+	usage := entry.Usage()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.summary.Total.Add(usage)
+	agentUsage := t.summary.ByAgent[response.Agent]
+	agentUsage.Add(usage)
+	t.summary.ByAgent[response.Agent] = agentUsage
+
+	if task != "" {
+		t.addTaskUsage(task, response.Agent, usage)
+	}
+
+	if t.ledgerPath != "" {
+		if err := Append(t.ledgerPath, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %v\n", err)
+		}
+	}
+
+	return usage
+}
+
+// addTaskUsage adds usage to the task's running total, keeping tasks in the
+// order they first used tokens
+func (t *Tracker) addTaskUsage(task string, agent models.AgentType, usage models.Usage) {
+	for i := range t.summary.ByTask {
+		if t.summary.ByTask[i].Task == task {
+			t.summary.ByTask[i].Usage.Add(usage)
+			return
+		}
+	}
+	t.summary.ByTask = append(t.summary.ByTask, models.TaskUsage{Task: task, Agent: agent, Usage: usage})
+}
+
+// CheckBudget returns an error describing the overrun once the budget is
+// exceeded
+func (t *Tracker) CheckBudget() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.summary.Total
+	if !t.budget.Exceeded(total) {
+		return nil
+	}
+	if t.budget.MaxCost > 0 && total.Cost > t.budget.MaxCost {
+		return fmt.Errorf("spend budget of $%.4f exceeded ($%.4f used)", t.budget.MaxCost, total.Cost)
+	}
+	return fmt.Errorf("token budget of %d exceeded (%d used)", t.budget.MaxTokens, total.TotalTokens())
+}
+
+// Summary returns a copy of the totals so far
+func (t *Tracker) Summary() models.UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := t.summary
+	summary.ByAgent = make(map[models.AgentType]models.Usage, len(t.summary.ByAgent))
+	for agent, usage := range t.summary.ByAgent {
+		summary.ByAgent[agent] = usage
+	}
+	summary.ByTask = append([]models.TaskUsage(nil), t.summary.ByTask...)
+	return summary
+}
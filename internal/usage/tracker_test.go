@@ -0,0 +1,111 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-code/pkg/models"
+)
+
+// newTestTracker creates a tracker priced from the embedded catalog
+func newTestTracker(t *testing.T, ledgerPath string, budget models.Budget) *Tracker {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return NewTracker(ledgerPath, "build", "run-1", budget)
+}
+
+func response(agent models.AgentType, model string, prompt, completion int) *models.Response {
+	return &models.Response{Agent: agent, Model: model, PromptTokens: prompt, CompletionTokens: completion}
+}
+
+func TestTrackerSums(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "usage.jsonl")
+	tracker := newTestTracker(t, ledger, models.Budget{})
+
+	// llama-3.3-70b-versatile costs $0.59 in and $0.79 out per million tokens
+	first := response(models.BackendAgent, "llama-3.3-70b-versatile", 1000000, 1000000)
+	usage := tracker.Record("Create API", first)
+	if math.Abs(first.Cost-1.38) > 1e-9 || usage.Cost != first.Cost || usage.Requests != 1 {
+		t.Errorf("cost = %v, usage = %+v", first.Cost, usage)
+	}
+
+	tracker.Record("Review API", response(models.ReviewerAgent, "llama-3.3-70b-versatile", 100, 10))
+	tracker.Record("Create API", response(models.BackendAgent, "unknown-model", 50, 5))
+
+	// Cached responses are free and not recorded
+	cached := response(models.BackendAgent, "llama-3.3-70b-versatile", 500, 500)
+	cached.Cached = true
+	if usage := tracker.Record("Create API", cached); usage != (models.Usage{}) {
+		t.Errorf("cached usage = %+v", usage)
+	}
+
+	summary := tracker.Summary()
+	if summary.Total.Requests != 3 || summary.Total.PromptTokens != 1000150 || summary.Total.CompletionTokens != 1000015 {
+		t.Errorf("total = %+v", summary.Total)
+	}
+	backend := summary.ByAgent[models.BackendAgent]
+	if backend.Requests != 2 || backend.PromptTokens != 1000050 || backend.Cost != first.Cost {
+		t.Errorf("backend = %+v", backend)
+	}
+	if len(summary.ByTask) != 2 || summary.ByTask[0].Task != "Create API" || summary.ByTask[0].Usage.Requests != 2 || summary.ByTask[1].Agent != models.ReviewerAgent {
+		t.Errorf("by task = %+v", summary.ByTask)
+	}
+
+	// The summary is a copy
+	summary.ByAgent[models.BackendAgent] = models.Usage{}
+	if tracker.Summary().ByAgent[models.BackendAgent].Requests != 2 {
+		t.Error("changing a summary changed the tracker")
+	}
+
+	entries, err := Read(ledger, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Command != "build" || entries[0].RunID != "run-1" || entries[0].Task != "Create API" || entries[2].Cost != 0 {
+		t.Errorf("ledger = %+v", entries)
+	}
+}
+
+func TestTrackerWithoutLedger(t *testing.T) {
+	tracker := newTestTracker(t, "", models.Budget{})
+	tracker.Record("", response(models.PlannerAgent, "llama-3.1-8b-instant", 10, 10))
+
+	summary := tracker.Summary()
+	if summary.Total.Requests != 1 || len(summary.ByTask) != 0 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestTrackerBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget models.Budget
+		err    string
+	}{
+		{"no budget", models.Budget{}, ""},
+		{"within tokens", models.Budget{MaxTokens: 2000}, ""},
+		{"over tokens", models.Budget{MaxTokens: 1000}, "token budget of 1000 exceeded (1500 used)"},
+		{"over cost", models.Budget{MaxCost: 0.0001}, "spend budget of $0.0001 exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker(t, "", tt.budget)
+			tracker.Record("", response(models.PlannerAgent, "llama-3.3-70b-versatile", 1000, 500))
+
+			err := tracker.CheckBudget()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("CheckBudget: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+// Package gocode embeds the go-code agent team in other Go programs. A Team
+// is built from options, chats with a single agent or runs a whole build,
+// and reports what happened as events and structured results.
+//
+//	team, err := gocode.New(gocode.WithAPIKey(key), gocode.WithFileSink(gocode.NewMemorySink()))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := team.Build(ctx, "a todo app with a REST API")
+package gocode
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-code/internal/agents"
+	"go-code/internal/api"
+	"go-code/internal/events"
+	"go-code/internal/orchestrator"
+	"go-code/internal/prompts"
+	"go-code/internal/runner"
+	"go-code/internal/stack"
+	"go-code/internal/usage"
+	"go-code/pkg/models"
+)
+
+// Client talks to an OpenAI-compatible chat completions API
+type Client = api.GroqClient
+
+// NewClient creates a client for the API rooted at baseURL, e.g.
+// https://api.groq.com/openai/v1 or http://localhost:11434/v1
+func NewClient(apiKey, baseURL string) *Client {
+	return api.NewClient(apiKey, baseURL)
+}
+
+// Message is one turn of a conversation with an agent
+type Message = models.Message
+
+// Toolset is the set of tools an agent may call during its requests
+type Toolset = api.Toolset
+
+// ToolProvider hands out the tools each agent may use
+type ToolProvider = agents.ToolProvider
+
+// ConfirmCommandFunc asks the user whether a command, such as a task's
+// acceptance tests, may run in a directory
+type ConfirmCommandFunc = runner.ConfirmFunc
+
+// ProjectFacts describes the project agents work on; their prompts are
+// rendered with it
+type ProjectFacts = prompts.Facts
+
+// StackProfile describes the languages, frameworks and tooling detected in
+// the project directory
+type StackProfile = stack.Profile
+
+// Team is a set of agents sharing a client, a configuration and a file sink.
+// It is safe to use from several goroutines, but builds running at the same
+// time write into the same sink.
+type Team struct {
+	config   *models.Config
+	client   *Client
+	apiKey   string
+	baseURL  string
+	custom   []models.Agent
+	registry *agents.Registry
+	sink     models.FileSink
+	dataDir  string
+	bus      *events.Bus
+
+	repo      *GitRepo
+	noScan    bool
+	sarifPath string
+	controls  *Controls
+	stream    bool
+	tools     ToolProvider
+	confirm   ConfirmCommandFunc
+
+	projectDir string
+	facts      *ProjectFacts
+}
+
+// Option configures a Team
+type Option func(*Team)
+
+// WithConfig sets the configuration: agent models, budget, review settings
+// and provider. The default is models.DefaultConfig().
+func WithConfig(config *models.Config) Option {
+	return func(t *Team) { t.config = config }
+}
+
+// WithClient sets the API client. Without it a client is created from
+// WithAPIKey, WithBaseURL and the configured provider.
+func WithClient(client *Client) Option {
+	return func(t *Team) { t.client = client }
+}
+
+// WithAPIKey sets the API key of the default client
+func WithAPIKey(key string) Option {
+	return func(t *Team) { t.apiKey = key }
+}
+
+// WithBaseURL points the default client at another OpenAI-compatible API
+func WithBaseURL(url string) Option {
+	return func(t *Team) { t.baseURL = url }
+}
+
+// WithAgent adds an agent to the team, replacing the built-in agent of the
+// same type
+func WithAgent(agent models.Agent) Option {
+	return func(t *Team) { t.custom = append(t.custom, agent) }
+}
+
+// WithFileSink sets where builds write their files. The default is the
+// generated-project directory below the working directory.
+func WithFileSink(sink models.FileSink) Option {
+	return func(t *Team) { t.sink = sink }
+}
+
+// WithEventHandler subscribes handler to the events of every build
+func WithEventHandler(handler EventHandler) Option {
+	return func(t *Team) { t.bus.Subscribe(handler) }
+}
+
+// WithDataDir keeps run journals, run state and the usage ledger below dir
+// instead of ~/.go-code
+func WithDataDir(dir string) Option {
+	return func(t *Team) { t.dataDir = dir }
+}
+
+// WithGitRepo makes builds run on a new go-code/<run-id> branch of repo and
+// commit every completed task. It needs a directory file sink.
+func WithGitRepo(repo *GitRepo) Option {
+	return func(t *Team) { t.repo = repo }
+}
+
+// WithSecurityScan turns the offline scan of the generated files on or off.
+// It is on by default.
+func WithSecurityScan(enabled bool) Option {
+	return func(t *Team) { t.noScan = !enabled }
+}
+
+// WithCommandConfirm lets builds ask before running commands, such as
+// acceptance tests, that require_command_permission guards. Without it
+// only commands with a session permission run.
+func WithCommandConfirm(confirm ConfirmCommandFunc) Option {
+	return func(t *Team) { t.confirm = confirm }
+}
+
+// WithSARIFPath sets where the security scan's SARIF report is written
+func WithSARIFPath(path string) Option {
+	return func(t *Team) { t.sarifPath = path }
+}
+
+// WithControls lets controls pause, skip, retry and stop tasks of a build
+func WithControls(controls *Controls) Option {
+	return func(t *Team) { t.controls = controls }
+}
+
+// WithStreaming makes builds publish task_output events as agents write
+func WithStreaming() Option {
+	return func(t *Team) { t.stream = true }
+}
+
+// WithTools offers agents the tools provider allows them, e.g. those of the
+// configured MCP servers. Agents without a SetTools(Toolset) method get none.
+func WithTools(provider ToolProvider) Option {
+	return func(t *Team) { t.tools = provider }
+}
+
+// WithProjectDir sets the project agents work on. Its directory tree,
+// README and .go-code/conventions.md are put into every agent's prompt. The
+// default is the working directory.
+func WithProjectDir(dir string) Option {
+	return func(t *Team) { t.projectDir = dir }
+}
+
+// New builds a team from options
+func New(opts ...Option) (*Team, error) {
+	t := &Team{bus: events.NewBus()}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.config == nil {
+		t.config = models.DefaultConfig()
+	}
+	if t.client == nil {
+		client, err := t.defaultClient()
+		if err != nil {
+			return nil, err
+		}
+		t.client = client
+	}
+
+	t.registry = agents.NewRegistry(t.client, t.config)
+	for _, agent := range t.custom {
+		t.registry.Register(agent)
+	}
+	if t.tools != nil {
+		t.registry.SetToolProvider(t.tools)
+	}
+
+	facts, err := t.gatherFacts()
+	if err != nil {
+		return nil, err
+	}
+	t.facts = facts
+	t.registry.SetFacts(facts)
+	return t, nil
+}
+
+// gatherFacts collects the facts of the project directory. Generated files
+// go to the directory sink's root, or where the orchestrator writes by
+// default; other sinks have no target on disk.
+func (t *Team) gatherFacts() (*ProjectFacts, error) {
+	dir := t.projectDir
+	if dir == "" {
+		dir = "."
+	}
+
+	var target string
+	switch sink := t.sink.(type) {
+	case nil:
+		target = orchestrator.DefaultOutputDir()
+	case *DirectorySink:
+		target = sink.Root()
+	}
+	if target != "" {
+		if abs, err := filepath.Abs(target); err == nil {
+			target = abs
+		}
+	}
+
+	return prompts.Gather(dir, target, t.config.Conventions)
+}
+
+// Facts returns the project facts agents' prompts are rendered with
+func (t *Team) Facts() *ProjectFacts {
+	return t.facts
+}
+
+// Stack returns the stack detected in the project directory, or nil if it
+// couldn't be detected
+func (t *Team) Stack() *StackProfile {
+	return t.facts.Profile
+}
+
+// defaultClient creates a client for the configured provider. Only Groq
+// needs an API key.
+func (t *Team) defaultClient() (*Client, error) {
+	if t.baseURL != "" {
+		return api.NewClient(t.apiKey, t.baseURL), nil
+	}
+
+	provider := t.config.Provider
+	apiKey := t.apiKey
+	if apiKey == "" && provider.IsGroq() {
+		apiKey = t.config.GroqAPIKey
+	}
+	if apiKey == "" && provider.IsGroq() {
+		return nil, fmt.Errorf("a Groq API key is required; use WithAPIKey, WithBaseURL or WithClient")
+	}
+	if provider.BaseURL == "" {
+		return api.NewGroqClient(apiKey), nil
+	}
+	return api.NewClient(apiKey, provider.BaseURL), nil
+}
+
+// Client returns the team's API client
+func (t *Team) Client() *Client {
+	return t.client
+}
+
+// Agent returns the team's agent of the given type
+func (t *Team) Agent(agentType models.AgentType) (models.Agent, error) {
+	return t.registry.GetAgent(agentType)
+}
+
+// FindAgent returns the agent whose name best matches name
+func (t *Team) FindAgent(name string) (models.Agent, error) {
+	return t.registry.GetAgentByName(name)
+}
+
+// Agents returns every agent of the team
+func (t *Team) Agents() []models.Agent {
+	return t.registry.ListAgents()
+}
+
+// AgentConfig returns the model settings of the team's agent of agentType
+func (t *Team) AgentConfig(agentType models.AgentType) models.AgentConfig {
+	return t.registry.AgentConfig(agentType)
+}
+
+// AgentNames returns the lowercase names of the team's agents
+func (t *Team) AgentNames() []string {
+	return t.registry.GetAgentNames()
+}
+
+// Subscribe delivers the events of every build from now on to handler,
+// until the returned function is called
+func (t *Team) Subscribe(handler EventHandler) (unsubscribe func()) {
+	return t.bus.Subscribe(handler)
+}
+
+// Chat sends a conversation to one agent and returns its reply. The last
+// message is normally the user's. Usage is recorded in the usage ledger.
+func (t *Team) Chat(ctx context.Context, agentType models.AgentType, messages []Message) (*models.Response, error) {
+	return t.ChatStream(ctx, agentType, messages, nil)
+}
+
+// ChatStream is Chat with the reply passed to onDelta as it is generated.
+// Agents that can't stream pass their whole reply to onDelta at once.
+func (t *Team) ChatStream(ctx context.Context, agentType models.AgentType, messages []Message, onDelta func(content string)) (*models.Response, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages to send")
+	}
+	agent, err := t.Agent(agentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *models.Response
+	if conversational, ok := agent.(models.ConversationalAgent); ok {
+		response, err = conversational.Converse(ctx, messages, onDelta)
+	} else {
+		response, err = agent.Process(transcript(messages[:len(messages)-1]), messages[len(messages)-1].Content)
+		if err == nil && onDelta != nil {
+			onDelta(response.Content)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage.NewTracker(t.ledgerPath(), "chat", "", t.config.Budget).Record("", response)
+	return response, nil
+}
+
+// transcript renders earlier turns as context for agents that take a single
+// message
+func transcript(messages []Message) string {
+	var b strings.Builder
+	for _, message := range messages {
+		fmt.Fprintf(&b, "%s: %s\n", message.Role, message.Content)
+	}
+	return b.String()
+}
+
+// ledgerPath returns where usage is recorded
+func (t *Team) ledgerPath() string {
+	if t.dataDir != "" {
+		return filepath.Join(t.dataDir, "usage.jsonl")
+	}
+	return usage.DefaultLedgerPath()
+}
+
+// BuildOption configures a single build
+type BuildOption func(*buildSettings)
+
+// buildSettings are the per-build overrides of the team's settings
+type buildSettings struct {
+	sink     models.FileSink
+	handlers []EventHandler
+}
+
+// BuildSink makes one build write into sink instead of the team's sink
+func BuildSink(sink models.FileSink) BuildOption {
+	return func(s *buildSettings) { s.sink = sink }
+}
+
+// BuildEventHandler delivers the events of one build to handler, on top of
+// the team's handlers
+func BuildEventHandler(handler EventHandler) BuildOption {
+	return func(s *buildSettings) { s.handlers = append(s.handlers, handler) }
+}
+
+// Build plans and builds description with the whole team. The result is
+// returned even when the build fails, with whatever was done by then.
+// Cancelling ctx stops the build and the request in flight.
+func (t *Team) Build(ctx context.Context, description string, opts ...BuildOption) (*BuildResult, error) {
+	started := time.Now()
+	settings := buildSettings{sink: t.sink}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	orch := t.newOrchestrator(settings.sink)
+	defer orch.Events().Subscribe(events.HandlerFunc(t.bus.Publish))()
+	for _, handler := range settings.handlers {
+		orch.Events().Subscribe(handler)
+	}
+	err := orch.ExecuteBuildContext(ctx, description)
+
+	result := newBuildResult(orch, time.Since(started))
+	if t.repo != nil {
+		result.Branch = orch.BranchName()
+	}
+	return result, err
+}
+
+// Plan asks the planner to break description into tasks, without building
+// anything. The plan event goes to the team's handlers.
+func (t *Team) Plan(ctx context.Context, description string) ([]PlannedTask, error) {
+	orch := t.newOrchestrator(nil)
+	defer orch.Events().Subscribe(events.HandlerFunc(t.bus.Publish))()
+
+	tasks, err := orch.Plan(ctx, description)
+	if err != nil {
+		return nil, err
+	}
+	planned := make([]PlannedTask, len(tasks))
+	for i, task := range tasks {
+		planned[i] = PlannedTask{ID: task.ID, Agent: task.AgentType, Description: task.Description, Acceptance: task.Acceptance}
+	}
+	return planned, nil
+}
+
+// newOrchestrator creates an orchestrator with the team's settings, writing
+// into sink if it is set
+func (t *Team) newOrchestrator(sink models.FileSink) *orchestrator.Orchestrator {
+	orch := orchestrator.New(t.registry, t.config)
+	orch.SetRunner(runner.New(t.config, t.confirm))
+	if sink != nil {
+		orch.SetFileSink(sink)
+	}
+	if t.dataDir != "" {
+		orch.SetDataDir(t.dataDir)
+	}
+	if t.repo != nil {
+		orch.EnableGit(t.repo)
+	}
+	if t.noScan {
+		orch.DisableSecurityScan()
+	}
+	if t.sarifPath != "" {
+		orch.SetSARIFPath(t.sarifPath)
+	}
+	if t.controls != nil {
+		orch.EnableControls(t.controls)
+	}
+	if t.stream {
+		orch.EnableStreaming()
+	}
+	if t.facts.Profile != nil {
+		orch.SetStack(t.facts.Profile)
+	}
+	return orch
+}
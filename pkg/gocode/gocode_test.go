@@ -0,0 +1,341 @@
+package gocode
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"go-code/internal/api"
+	"go-code/internal/llmtest"
+	"go-code/pkg/models"
+)
+
+// scriptedTeam answers as a planner with a two task plan and as the agents
+// doing the tasks
+func scriptedTeam(req api.ChatRequest) llmtest.Reply {
+	system := llmtest.SystemPrompt(req)
+	switch {
+	case strings.HasPrefix(system, "You are the Planner Agent"):
+		return llmtest.Reply{Content: "1. [BACKEND] Create the todo API\n2. [FRONTEND] Build the todo page"}
+	case strings.HasPrefix(system, "You are the Backend Agent"):
+		return llmtest.Reply{Content: "```js\n// filename: routes/todos.js\nmodule.exports = {};\n```"}
+	case strings.HasPrefix(system, "You are the Frontend Agent"):
+		return llmtest.Reply{Content: "```html\n<!-- public/index.html -->\n<ul id=\"todos\"></ul>\n```"}
+	}
+	return llmtest.Reply{Status: 500, Error: "unexpected agent"}
+}
+
+// newTestTeam creates a team talking to a scripted fake API, keeping its
+// data in a temporary directory
+func newTestTeam(t *testing.T, opts ...Option) (*Team, *llmtest.Server) {
+	t.Helper()
+	server := llmtest.NewServer(t)
+	server.Handle(scriptedTeam)
+
+	opts = append([]Option{WithClient(server.Client()), WithDataDir(t.TempDir()), WithSecurityScan(false)}, opts...)
+	team, err := New(opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return team, server
+}
+
+func TestNewNeedsAPIKey(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("New without a key or client succeeded")
+	}
+	if _, err := New(WithAPIKey("gsk_test")); err != nil {
+		t.Errorf("New with a key: %v", err)
+	}
+	if _, err := New(WithBaseURL("http://localhost:11434/v1")); err != nil {
+		t.Errorf("New with a local base URL: %v", err)
+	}
+}
+
+func TestBuildIntoMemorySink(t *testing.T) {
+	sink := NewMemorySink()
+	probe := &Recorder{}
+	team, _ := newTestTeam(t, WithFileSink(sink), WithEventHandler(probe))
+
+	result, err := team.Build(context.Background(), "a todo app")
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"routes/todos.js":   "module.exports = {};",
+		"public/index.html": `<ul id="todos"></ul>`,
+	} {
+		data, err := sink.ReadFile(path)
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("%s = %q, %v", path, data, err)
+		}
+	}
+	if _, err := os.Stat("generated-project"); !os.IsNotExist(err) {
+		t.Error("a memory sink build created generated-project on disk")
+	}
+
+	if result.RunID == "" || len(result.Files) != 2 || result.Usage.Total.Requests != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Tasks) != 2 {
+		t.Fatalf("result has %d tasks, want 2", len(result.Tasks))
+	}
+	for _, task := range result.Tasks {
+		if task.Status != "completed" || task.Response == nil || task.Usage.TotalTokens() == 0 {
+			t.Errorf("task %s = %+v", task.ID, task)
+		}
+	}
+	if result.Tasks[0].Agent != models.BackendAgent || result.Tasks[1].Agent != models.FrontendAgent {
+		t.Errorf("task agents = %s, %s", result.Tasks[0].Agent, result.Tasks[1].Agent)
+	}
+
+	if got := probe.Events(EventTaskCompleted); len(got) != 2 {
+		t.Errorf("got %d task_completed events, want 2", len(got))
+	}
+	if got := probe.Events(EventSummary); len(got) != 1 || got[0].RunID != result.RunID {
+		t.Errorf("summary events = %+v", got)
+	}
+}
+
+func TestBuildIntoTarball(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewTarballSink(&buf)
+	team, _ := newTestTeam(t, WithFileSink(sink))
+
+	if _, err := team.Build(context.Background(), "a todo app"); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	if strings.Join(names, ",") != "routes/todos.js,public/index.html" {
+		t.Errorf("tarball holds %v", names)
+	}
+}
+
+func TestBuildIntoDirectoryIsJournaled(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project")
+	dataDir := t.TempDir()
+	team, _ := newTestTeam(t, WithFileSink(NewDirectorySink(root)), WithDataDir(dataDir))
+
+	result, err := team.Build(context.Background(), "a todo app")
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "routes", "todos.js")); err != nil {
+		t.Errorf("file not written: %v", err)
+	}
+	for _, name := range []string{"manifest.json", "state.json"} {
+		if _, err := os.Stat(filepath.Join(dataDir, "runs", result.RunID, name)); err != nil {
+			t.Errorf("run %s missing: %v", name, err)
+		}
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	team, server := newTestTeam(t, WithFileSink(NewMemorySink()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := team.Build(ctx, "a todo app")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Build error = %v, want context.Canceled", err)
+	}
+	if result == nil || len(result.Tasks) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if got := len(server.Requests()); got != 0 {
+		t.Errorf("cancelled build sent %d requests", got)
+	}
+}
+
+func TestChatSendsConversation(t *testing.T) {
+	team, server := newTestTeam(t)
+	server.Enqueue(llmtest.Reply{Content: "Use PostgreSQL."})
+
+	response, err := team.Chat(context.Background(), models.BackendAgent, []Message{
+		{Role: models.RoleUser, Content: "Which database should I use?"},
+		{Role: models.RoleAssistant, Content: "What kind of data is it?"},
+		{Role: models.RoleUser, Content: "Relational, with reporting."},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if response.Content != "Use PostgreSQL." || response.Agent != models.BackendAgent {
+		t.Errorf("response = %+v", response)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	messages := requests[0].Messages
+	if len(messages) != 4 || messages[0].Role != "system" || messages[2].Content != "What kind of data is it?" || messages[3].Role != models.RoleUser {
+		t.Errorf("messages sent = %+v", messages)
+	}
+
+	if _, err := team.Chat(context.Background(), models.BackendAgent, nil); err == nil {
+		t.Error("Chat without messages succeeded")
+	}
+}
+
+func TestProjectFactsInPrompts(t *testing.T) {
+	project := t.TempDir()
+	os.MkdirAll(filepath.Join(project, ".go-code"), 0755)
+	os.WriteFile(filepath.Join(project, ".go-code", "conventions.md"), []byte("Log with slog."), 0644)
+	os.WriteFile(filepath.Join(project, "go.mod"), []byte("module shop\n"), 0644)
+
+	config := models.DefaultConfig()
+	config.Conventions = []string{"we use chi, not gin"}
+	team, server := newTestTeam(t, WithConfig(config), WithProjectDir(project), WithFileSink(NewDirectorySink(filepath.Join(project, "out"))))
+	server.Enqueue(llmtest.Reply{Content: "Use chi."})
+
+	if _, err := team.Chat(context.Background(), models.BackendAgent, []Message{{Role: models.RoleUser, Content: "Which router?"}}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	system := llmtest.SystemPrompt(server.Requests()[0])
+	for _, want := range []string{
+		"You are the Backend Agent",
+		"This project is built with " + team.Facts().Stack + ". Write server code for that stack",
+		"Project directory: " + project,
+		"Generated files are written to: " + filepath.Join(project, "out"),
+		"- we use chi, not gin\nLog with slog.",
+		"go.mod",
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt lacks %q", want)
+		}
+	}
+	if team.Facts().Root != project {
+		t.Errorf("facts = %+v", team.Facts())
+	}
+}
+
+func TestFactsTargetIsBuildDirectory(t *testing.T) {
+	project, work := t.TempDir(), t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	// Builds without a sink write below the working directory, wherever the
+	// project's config file was found
+	team, _ := newTestTeam(t, WithProjectDir(project))
+	if got, want := team.Facts().Target, filepath.Join(work, "generated-project"); got != want {
+		t.Errorf("target = %s, want %s", got, want)
+	}
+}
+
+func TestBuildFollowsDetectedStack(t *testing.T) {
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, "go.mod"), []byte("module shop\n\nrequire github.com/go-chi/chi/v5 v5.0.12\n"), 0644)
+	root := filepath.Join(project, "out")
+	team, server := newTestTeam(t, WithProjectDir(project), WithFileSink(NewDirectorySink(root)), WithDataDir(t.TempDir()))
+
+	if got := team.Stack(); got == nil || got.Primary() != "Go" {
+		t.Fatalf("Stack = %+v", got)
+	}
+	if _, err := team.Build(context.Background(), "a todo app"); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	plan := llmtest.UserMessage(server.Requests()[0])
+	if !strings.Contains(plan, "detected stack is Go (chi); tests: go test") || !strings.Contains(plan, "Tests run with `go test ./...`") {
+		t.Errorf("plan prompt lacks the stack:\n%s", plan)
+	}
+	for _, dir := range []string{"cmd", "internal"} {
+		if _, err := os.Stat(filepath.Join(root, dir)); err != nil {
+			t.Errorf("Go layout not created: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "controllers")); err == nil {
+		t.Error("Node layout created for a Go project")
+	}
+}
+
+// echoAgent is a custom agent that answers without an API
+type echoAgent struct{}
+
+func (echoAgent) Name() string            { return "Echo" }
+func (echoAgent) Type() models.AgentType  { return models.BackendAgent }
+func (echoAgent) Color() *color.Color     { return color.New(color.FgWhite) }
+func (echoAgent) Icon() string            { return "🔁" }
+func (echoAgent) Role() string            { return "Repeats the message" }
+func (echoAgent) GetSystemPrompt() string { return "" }
+func (echoAgent) Process(context string, message string) (*models.Response, error) {
+	return &models.Response{Agent: models.BackendAgent, Content: context + message}, nil
+}
+
+func TestWithAgentReplacesBuiltIn(t *testing.T) {
+	team, server := newTestTeam(t, WithAgent(echoAgent{}))
+
+	agent, err := team.Agent(models.BackendAgent)
+	if err != nil || agent.Name() != "Echo" {
+		t.Fatalf("Agent(backend) = %v, %v", agent, err)
+	}
+
+	response, err := team.Chat(context.Background(), models.BackendAgent, []Message{
+		{Role: models.RoleUser, Content: "hi"},
+		{Role: models.RoleAssistant, Content: "hello"},
+		{Role: models.RoleUser, Content: "again"},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if response.Content != "user: hi\nassistant: hello\nagain" {
+		t.Errorf("custom agent got %q", response.Content)
+	}
+	if len(server.Requests()) != 0 {
+		t.Error("custom agent reached the API")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	probe := &Recorder{}
+	team, server := newTestTeam(t, WithEventHandler(probe))
+
+	tasks, err := team.Plan(context.Background(), "a todo app")
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Agent != models.BackendAgent || tasks[1].Description != "Build the todo page" {
+		t.Errorf("tasks = %+v", tasks)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("Plan sent %d requests, want only the planner's", len(server.Requests()))
+	}
+	if got := probe.Types(); len(got) != 2 || got[0] != EventTokensUsed || got[1] != EventPlanCreated {
+		t.Errorf("events = %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package gocode
+
+import (
+	"io"
+	"time"
+
+	"go-code/internal/events"
+	"go-code/internal/filewriter"
+	"go-code/internal/git"
+	"go-code/internal/orchestrator"
+	"go-code/pkg/models"
+)
+
+// Event is something that happened during a build
+type Event = events.Event
+
+// EventType identifies what an event reports
+type EventType = events.Type
+
+// PlannedTask is a task as listed in a plan event
+type PlannedTask = events.PlannedTask
+
+// Review is the outcome of a task's review loop
+type Review = events.Review
+
+// TestResult is the outcome of a task's acceptance tests
+type TestResult = models.TestResult
+
+// EventHandler receives build events. Handlers run on the build's goroutine
+// and must not block.
+type EventHandler = events.Subscriber
+
+// HandlerFunc lets an ordinary function handle events
+type HandlerFunc = events.HandlerFunc
+
+// Recorder is an EventHandler that keeps every event, e.g. for tests
+type Recorder = events.Recorder
+
+// Build event types
+const (
+	EventBuildStarted   = events.BuildStarted
+	EventStageStarted   = events.StageStarted
+	EventStageCompleted = events.StageCompleted
+	EventPlanCreated    = events.PlanCreated
+	EventTaskStarted    = events.TaskStarted
+	EventTaskOutput     = events.TaskOutput
+	EventTaskCompleted  = events.TaskCompleted
+	EventTaskFailed     = events.TaskFailed
+	EventTaskSkipped    = events.TaskSkipped
+	EventFileWritten    = events.FileWritten
+	EventTokensUsed     = events.TokensUsed
+	EventBudgetExceeded = events.BudgetExceeded
+	EventAgentResponse  = events.AgentResponse
+	EventWarning        = events.Warning
+	EventError          = events.Error
+	EventSummary        = events.Summary
+)
+
+// Controls steers a running build: pause, skip, retry and stop
+type Controls = orchestrator.Controls
+
+// NewControls creates controls for an unpaused build
+func NewControls() *Controls {
+	return orchestrator.NewControls()
+}
+
+// GitRepo is a git repository builds can commit to
+type GitRepo = git.Repo
+
+// OpenGitRepo opens the repository containing dir
+func OpenGitRepo(dir string) (*GitRepo, error) {
+	return git.Open(dir)
+}
+
+// DirectorySink writes files into a directory on disk
+type DirectorySink = filewriter.DirectorySink
+
+// MemorySink keeps files in memory
+type MemorySink = filewriter.MemorySink
+
+// TarballSink writes files as a gzipped tarball when closed
+type TarballSink = filewriter.TarballSink
+
+// NewDirectorySink creates a sink writing below root. Builds into a
+// directory are journaled and can be undone with go-code undo.
+func NewDirectorySink(root string) *DirectorySink {
+	return filewriter.NewDirectorySink(root)
+}
+
+// NewMemorySink creates an empty in-memory sink
+func NewMemorySink() *MemorySink {
+	return filewriter.NewMemorySink()
+}
+
+// NewTarballSink creates a sink that writes a .tar.gz to w when closed
+func NewTarballSink(w io.Writer) *TarballSink {
+	return filewriter.NewTarballSink(w)
+}
+
+// BuildResult is what a build did
+type BuildResult struct {
+	RunID    string              `json:"run_id"`
+	Branch   string              `json:"branch,omitempty"`
+	Tasks    []TaskResult        `json:"tasks"`
+	Files    []string            `json:"files"`
+	Findings []models.Finding    `json:"findings,omitempty"`
+	Usage    models.UsageSummary `json:"usage"`
+	Duration time.Duration       `json:"duration"`
+}
+
+// TaskResult is the outcome of one planned task
+type TaskResult struct {
+	ID          string           `json:"id"`
+	Agent       models.AgentType `json:"agent"`
+	Description string           `json:"description"`
+	// Status is pending, completed, failed or skipped. A task with
+	// acceptance criteria whose tests fail is failed.
+	Status     string           `json:"status"`
+	Response   *models.Response `json:"response,omitempty"`
+	Usage      models.Usage     `json:"usage"`
+	Review     *Review          `json:"review,omitempty"`
+	Acceptance []string         `json:"acceptance,omitempty"`
+	Tests      *TestResult      `json:"tests,omitempty"`
+}
+
+// newBuildResult collects the result of orch's build
+func newBuildResult(orch *orchestrator.Orchestrator, duration time.Duration) *BuildResult {
+	result := &BuildResult{
+		RunID:    orch.RunID(),
+		Files:    orch.WrittenFiles(),
+		Findings: orch.ScanFindings(),
+		Usage:    orch.Usage(),
+		Duration: duration,
+	}
+	for _, task := range orch.Tasks() {
+		taskResult := TaskResult{
+			ID:          task.ID,
+			Agent:       task.AgentType,
+			Description: task.Description,
+			Status:      task.Status,
+			Response:    task.Result,
+			Usage:       task.Usage,
+			Acceptance:  task.Acceptance,
+			Tests:       task.Tests,
+		}
+		if task.ReviewRounds > 0 {
+			taskResult.Review = &Review{Rounds: task.ReviewRounds, Passed: task.ReviewPassed, Findings: task.Findings}
+		}
+		result.Tasks = append(result.Tasks, taskResult)
+	}
+	return result
+}
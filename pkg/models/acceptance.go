@@ -0,0 +1,39 @@
+package models
+
+// AcceptanceConfig controls the acceptance tests generated and run for each
+// planned task that has acceptance criteria
+type AcceptanceConfig struct {
+	Enabled bool `json:"enabled"`
+	// Rounds is how many times the task's agent may fix its code after the
+	// tests fail
+	Rounds int `json:"rounds"`
+	// Agent writes the tests
+	Agent AgentType `json:"agent"`
+	// Timeout bounds one run of the test command, e.g. "5m"
+	Timeout string `json:"timeout"`
+}
+
+// TestStatus is the outcome of a task's acceptance tests
+type TestStatus string
+
+// Acceptance test outcomes
+const (
+	TestsPassed TestStatus = "passed"
+	TestsFailed TestStatus = "failed"
+	// TestsNotRun means the tests could not be generated or run, e.g. because
+	// the test command isn't allowed; Reason says why
+	TestsNotRun TestStatus = "not_run"
+)
+
+// TestResult is what became of a task's acceptance criteria
+type TestResult struct {
+	Status   TestStatus `json:"status"`
+	Criteria []string   `json:"criteria"`
+	Files    []string   `json:"files,omitempty"`
+	Command  string     `json:"command,omitempty"`
+	ExitCode int        `json:"exit_code,omitempty"`
+	// Attempts counts the test runs, the first one included
+	Attempts int    `json:"attempts,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"context"
+
 	"github.com/fatih/color"
 )
 
@@ -19,6 +21,29 @@ const (
 	SecurityAgent  AgentType = "security"
 )
 
+// AllAgentTypes lists every agent type
+var AllAgentTypes = []AgentType{
+	PlannerAgent,
+	FrontendAgent,
+	BackendAgent,
+	DevOpsAgent,
+	ReviewerAgent,
+	ManagerAgent,
+	ToolsAgent,
+	ResearchAgent,
+	SecurityAgent,
+}
+
+// IsValidAgentType reports whether t names a known agent type
+func IsValidAgentType(t AgentType) bool {
+	for _, known := range AllAgentTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
 // Agent represents a specialized AI agent
 type Agent interface {
 	Name() string
@@ -30,6 +55,31 @@ type Agent interface {
 	GetSystemPrompt() string
 }
 
+// StreamingAgent is an Agent that can stream its response as it is generated
+type StreamingAgent interface {
+	Agent
+	ProcessStream(ctx context.Context, taskContext, message string, onDelta func(content string)) (*Response, error)
+}
+
+// ConversationalAgent is an Agent that can continue a conversation. onDelta,
+// when set, gets the reply as it is generated.
+type ConversationalAgent interface {
+	Agent
+	Converse(ctx context.Context, messages []Message, onDelta func(content string)) (*Response, error)
+}
+
+// Message is one turn of a conversation with an agent
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Message roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
 // AgentConfig holds configuration for an agent
 type AgentConfig struct {
 	Model       string `json:"model"`
@@ -41,6 +91,10 @@ type AgentConfig struct {
 type Response struct {
 	Content     string            `json:"content"`
 	TokensUsed  int              `json:"tokens_used"`
+	PromptTokens     int         `json:"prompt_tokens"`
+	CompletionTokens int         `json:"completion_tokens"`
+	Cost        float64           `json:"cost,omitempty"`
+	Cached      bool              `json:"cached,omitempty"`
 	Model       string            `json:"model"`
 	Agent       AgentType         `json:"agent"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
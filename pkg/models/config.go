@@ -1,8 +1,14 @@
 package models
 
+// ConfigVersion is the config file schema version written by this release.
+// Older files are upgraded by the migrations in internal/config.
+const ConfigVersion = 1
+
 // Config represents the application configuration
 type Config struct {
+	Version                 int                      `json:"version"`
 	GroqAPIKey              string                   `json:"groq_api_key"`
+	KeyCommand              string                   `json:"key_command,omitempty"`
 	DefaultModel            string                   `json:"default_model"`
 	AllowedCommands         []string                 `json:"allowed_commands"`
 	RequireCommandPermission bool                    `json:"require_command_permission"`
@@ -10,62 +16,113 @@ type Config struct {
 	AgentPreferences        map[AgentType]AgentConfig `json:"agent_preferences"`
 	WorkingDirectory        string                   `json:"working_directory"`
 	SessionPermissions      map[string]bool          `json:"session_permissions"`
+	Review                  ReviewConfig             `json:"review"`
+	Acceptance              AcceptanceConfig         `json:"acceptance"`
+	Provider                ProviderConfig           `json:"provider"`
+	Profile                 string                   `json:"profile"`
+	Profiles                map[string]Profile       `json:"profiles,omitempty"`
+	Budget                  Budget                   `json:"budget"`
+	RateLimits              map[string]RateLimit     `json:"rate_limits,omitempty"`
+	Cache                   CacheConfig              `json:"cache"`
+	MCPServers              map[string]MCPServerConfig `json:"mcp_servers,omitempty"`
+	// Conventions are rules every agent follows, e.g. "we use chi, not gin"
+	Conventions             []string                 `json:"conventions,omitempty"`
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		DefaultModel:            "llama-3.1-70b-versatile",
+		Version:                 ConfigVersion,
+		DefaultModel:            "llama-3.3-70b-versatile",
 		AllowedCommands:         []string{"npm", "go", "docker", "git"},
 		RequireCommandPermission: true,
 		RestrictToCurrentDir:    true,
 		AgentPreferences: map[AgentType]AgentConfig{
 			PlannerAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.7,
 				MaxTokens:   4096,
 			},
 			FrontendAgent: {
-				Model:       "qwen2.5-coder-32b-instruct",
+				Model:       "moonshotai/kimi-k2-instruct",
 				Temperature: 0.3,
 				MaxTokens:   4096,
 			},
 			BackendAgent: {
-				Model:       "qwen2.5-coder-32b-instruct",
+				Model:       "moonshotai/kimi-k2-instruct",
 				Temperature: 0.3,
 				MaxTokens:   4096,
 			},
 			DevOpsAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.5,
 				MaxTokens:   4096,
 			},
 			ReviewerAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.3,
 				MaxTokens:   4096,
 			},
 			ManagerAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.6,
 				MaxTokens:   4096,
 			},
 			ToolsAgent: {
-				Model:       "qwen2.5-coder-32b-instruct",
+				Model:       "moonshotai/kimi-k2-instruct",
 				Temperature: 0.2,
 				MaxTokens:   4096,
 			},
 			ResearchAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.4,
 				MaxTokens:   4096,
 			},
 			SecurityAgent: {
-				Model:       "llama-3.1-70b-versatile",
+				Model:       "llama-3.3-70b-versatile",
 				Temperature: 0.2,
 				MaxTokens:   4096,
 			},
 		},
 		SessionPermissions: make(map[string]bool),
+		Review: ReviewConfig{
+			Enabled: false,
+			Rounds:  2,
+			Agent:   ReviewerAgent,
+		},
+		Acceptance: AcceptanceConfig{
+			Enabled: true,
+			Rounds:  1,
+			Agent:   BackendAgent,
+			Timeout: "5m",
+		},
+		Provider: ProviderConfig{
+			Name:    GroqProvider,
+			BaseURL: GroqBaseURL,
+		},
+		Cache: CacheConfig{
+			Enabled:   false,
+			TTL:       "168h",
+			MaxSizeMB: 100,
+		},
 	}
-}
\ No newline at end of file
+}
+// CommandPermissionKey is the SessionPermissions key that, when true, lets
+// go-code run an allowed command without asking
+func CommandPermissionKey(command string) string {
+	return "command:" + command
+}
+
+// RateLimit is a model's requests-per-minute and tokens-per-minute
+// allowance. Zero means no limit.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+}
+
+// CacheConfig controls the on-disk cache of API responses
+type CacheConfig struct {
+	Enabled   bool   `json:"enabled"`
+	TTL       string `json:"ttl"`
+	MaxSizeMB int    `json:"max_size_mb"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// AllTools is the tool name in MCPServerConfig.Tools that stands for every
+// tool of the server
+const AllTools = "*"
+
+// MCPServerConfig is an MCP server launched as a local subprocess that
+// speaks the protocol over its stdin and stdout. Its tools are offered to
+// agents during requests.
+type MCPServerConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Tools maps a tool name, or AllTools, to the agents allowed to use it.
+	// Tools not listed are offered to no agent.
+	Tools map[string][]AgentType `json:"tools"`
+}
+
+// Allows reports whether agentType may use the server's tool
+func (c MCPServerConfig) Allows(tool string, agentType AgentType) bool {
+	for _, name := range []string{tool, AllTools} {
+		for _, allowed := range c.Tools[name] {
+			if allowed == agentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MCPPermissionKey is the SessionPermissions key that, when true, lets
+// agents call a server's tool without asking
+func MCPPermissionKey(server, tool string) string {
+	return "mcp:" + server + "/" + tool
+}
@@ -0,0 +1,55 @@
+package models
+
+// GroqProvider is the name of the default provider
+const GroqProvider = "groq"
+
+// GroqBaseURL is the root of Groq's OpenAI-compatible API
+const GroqBaseURL = "https://api.groq.com/openai/v1"
+
+// ProviderConfig selects the OpenAI-compatible API that requests are sent to.
+// The Groq API key is only ever sent to Groq; other providers read their key,
+// if they need one, from the environment variable named by APIKeyEnv.
+type ProviderConfig struct {
+	Name      string `json:"name"`
+	BaseURL   string `json:"base_url"`
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+}
+
+// IsGroq reports whether the provider is Groq, whose models are listed in
+// the model catalog and which requires an API key
+func (p ProviderConfig) IsGroq() bool {
+	return p.Name == "" || p.Name == GroqProvider
+}
+
+// Profile is a named set of overrides applied on top of the configuration.
+// Empty fields leave the underlying setting unchanged.
+type Profile struct {
+	Description      string                    `json:"description,omitempty"`
+	Model            string                    `json:"model,omitempty"`
+	DefaultModel     string                    `json:"default_model,omitempty"`
+	Provider         ProviderConfig            `json:"provider,omitempty"`
+	AgentPreferences map[AgentType]AgentConfig `json:"agent_preferences,omitempty"`
+}
+
+// BuiltinProfiles returns the profiles that ship with go-code. A user profile
+// with the same name replaces the built-in one.
+func BuiltinProfiles() map[string]Profile {
+	return map[string]Profile{
+		"fast": {
+			Description: "Fast and cheap: llama-3.1-8b-instant for every agent",
+			Model:       "llama-3.1-8b-instant",
+		},
+		"quality": {
+			Description: "Highest quality: openai/gpt-oss-120b for every agent",
+			Model:       "openai/gpt-oss-120b",
+		},
+		"local": {
+			Description: "Local OpenAI-compatible server (e.g. Ollama) on localhost:11434",
+			Model:       "llama3.1:8b",
+			Provider: ProviderConfig{
+				Name:    "local",
+				BaseURL: "http://localhost:11434/v1",
+			},
+		},
+	}
+}
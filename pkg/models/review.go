@@ -0,0 +1,30 @@
+package models
+
+// Severity ranks how serious a finding is
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityInfo     Severity = "info"
+)
+
+// Finding is a single structured issue reported against generated code
+type Finding struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"`
+	Rule     string   `json:"rule,omitempty"`
+	Round    int      `json:"round,omitempty"`
+}
+
+// ReviewConfig controls the review loop that runs after each coding task
+type ReviewConfig struct {
+	Enabled bool      `json:"enabled"`
+	Rounds  int       `json:"rounds"`
+	Agent   AgentType `json:"agent"`
+}
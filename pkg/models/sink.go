@@ -0,0 +1,8 @@
+package models
+
+// FileSink stores the files a build generates. Paths are relative to the
+// project and use forward slashes.
+type FileSink interface {
+	WriteFile(path string, data []byte) error
+	ReadFile(path string) ([]byte, error)
+}
@@ -0,0 +1,49 @@
+package models
+
+// Usage counts the tokens used by one or more requests and what they cost
+type Usage struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// TotalTokens returns prompt plus completion tokens
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add accumulates other into u
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.Cost += other.Cost
+}
+
+// TaskUsage is the usage of a single build task, including its reviews
+type TaskUsage struct {
+	Task  string    `json:"task"`
+	Agent AgentType `json:"agent"`
+	Usage Usage     `json:"usage"`
+}
+
+// UsageSummary is the token usage of a run, overall, per agent and per task
+type UsageSummary struct {
+	Total   Usage               `json:"total"`
+	ByAgent map[AgentType]Usage `json:"by_agent"`
+	ByTask  []TaskUsage         `json:"by_task,omitempty"`
+	Budget  Budget              `json:"budget"`
+}
+
+// Budget limits what a single build may spend. Zero means no limit.
+type Budget struct {
+	MaxTokens int     `json:"max_tokens"`
+	MaxCost   float64 `json:"max_cost"`
+}
+
+// Exceeded reports whether usage is over the budget
+func (b Budget) Exceeded(usage Usage) bool {
+	return (b.MaxTokens > 0 && usage.TotalTokens() > b.MaxTokens) ||
+		(b.MaxCost > 0 && usage.Cost > b.MaxCost)
+}